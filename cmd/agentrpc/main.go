@@ -6,7 +6,9 @@ import (
 	"github.com/caarlos0/env/v6"
 	"github.com/sergeysynergy/metricser/config"
 	"github.com/sergeysynergy/metricser/internal/agent"
+	"github.com/sergeysynergy/metricser/internal/agent/collector"
 	"github.com/sergeysynergy/metricser/pkg/crypter"
+	"github.com/sergeysynergy/metricser/pkg/retry"
 	"github.com/sergeysynergy/metricser/pkg/utils"
 	"log"
 )
@@ -33,6 +35,20 @@ func main() {
 	flag.DurationVar(&cfg.PollInterval, "p", cfg.PollInterval, "update metrics interval")
 	flag.StringVar(&cfg.Key, "k", cfg.Key, "sign key")
 	flag.StringVar(&cfg.CryptoKey, "crypto-key", cfg.CryptoKey, "path to file with public key")
+	flag.StringVar(&cfg.ID, "id", cfg.ID, "agent ID, generated on first start if empty")
+	flag.StringVar(&cfg.IDFile, "id-file", cfg.IDFile, "file to keep generated agent ID")
+	flag.BoolVar(&cfg.IdentityLabels, "agent-labels", cfg.IdentityLabels, "add agent ID and hostname labels to all reported metrics")
+	flag.StringVar(&cfg.OutboxDir, "outbox", cfg.OutboxDir, "directory to keep unsent reports, empty to disable")
+	flag.IntVar(&cfg.OutboxSize, "outbox-size", cfg.OutboxSize, "max number of unsent reports kept on disk")
+	flag.DurationVar(&cfg.RequestTimeout, "timeout", cfg.RequestTimeout, "timeout for a single report request")
+	flag.IntVar(&cfg.RetryMax, "retry-max", cfg.RetryMax, "max number of retries for a failed report, 0 to disable")
+	flag.DurationVar(&cfg.RetryInterval, "retry-interval", cfg.RetryInterval, "delay before the first retry")
+	flag.DurationVar(&cfg.RetryMaxInterval, "retry-max-interval", cfg.RetryMaxInterval, "max delay between retries")
+	flag.Float64Var(&cfg.RetryMultiplier, "retry-multiplier", cfg.RetryMultiplier, "retry delay multiplier")
+	flag.Float64Var(&cfg.RetryJitter, "retry-jitter", cfg.RetryJitter, "random retry delay spread, fraction from 0 to 1")
+	flag.DurationVar(&cfg.RetryMaxElapsed, "retry-max-elapsed", cfg.RetryMaxElapsed, "max total time spent on retries of a single report")
+	flag.StringVar(&cfg.PushAddr, "push", cfg.PushAddr, "local address to accept application metrics over HTTP, empty to disable")
+	flag.StringVar(&cfg.StatsDAddr, "statsd", cfg.StatsDAddr, "local address to accept application metrics over StatsD UDP, empty to disable")
 	flag.Parse()
 
 	err := env.Parse(cfg)
@@ -45,17 +61,107 @@ func main() {
 		log.Println("[WARNING] Failed to get public key -", err)
 	}
 
+	disk, err := collector.NewDisk(collector.DiskFilter{
+		IncludeFSTypes:     cfg.Collectors.Disk.IncludeFSTypes,
+		ExcludeFSTypes:     cfg.Collectors.Disk.ExcludeFSTypes,
+		IncludeMountpoints: cfg.Collectors.Disk.IncludeMountpoints,
+		ExcludeMountpoints: cfg.Collectors.Disk.ExcludeMountpoints,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	network, err := collector.NewNet(collector.NetFilter{
+		IncludeInterfaces: cfg.Collectors.Net.IncludeInterfaces,
+		ExcludeInterfaces: cfg.Collectors.Net.ExcludeInterfaces,
+		TCP:               cfg.Collectors.Net.TCP,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	proc, err := collector.NewProcess(collector.ProcessFilter{
+		Names:    cfg.Collectors.Process.Names,
+		PidFiles: cfg.Collectors.Process.PidFiles,
+		Cgroups:  cfg.Collectors.Process.Cgroups,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	opts := []agent.Option{
+		agent.WithCollector(disk, collectorSettings(cfg.Collectors.Disk.CollectorConf)),
+		agent.WithCollector(network, collectorSettings(cfg.Collectors.Net.CollectorConf)),
+		agent.WithCollector(proc, collectorSettings(cfg.Collectors.Process.CollectorConf)),
+	}
+
+	for _, e := range cfg.Collectors.Exec {
+		c, errExec := collector.NewExec(collector.ExecCommand{
+			Name:    e.Name,
+			Command: e.Command,
+			Args:    e.Args,
+			Env:     e.Env,
+			Dir:     e.Dir,
+		})
+		if errExec != nil {
+			log.Fatalln(errExec)
+		}
+		opts = append(opts, agent.WithCollector(c, collectorSettings(e.CollectorConf)))
+	}
+
+	// метрики контрольной группы доступны только при наличии cgroup v2
+	cg, err := collector.NewCgroup(cfg.Collectors.Cgroup.Root)
+	if err != nil {
+		log.Println("[INFO] Метрики контрольной группы недоступны -", err)
+	} else {
+		log.Printf("[INFO] Контрольная группа агента: %s; в контейнере: %v\n", cg.Dir(), cg.Namespaced())
+		opts = append(opts,
+			agent.WithCollector(cg, collectorSettings(cfg.Collectors.Cgroup.CollectorConf)),
+			// в контейнере объём памяти определяется ограничением контрольной группы
+			agent.WithMemoryLimit(cg),
+		)
+	}
+
 	// создадим агента по сбору и отправке метрик
 	// в качестве метрик выступают различные системные характеристики машины, на которой запущен агент
-	a := agent.New(
+	opts = append(opts,
 		agent.WithAddress(cfg.Addr),
 		agent.WithGRPCAddress(cfg.GRPCAddr),
 		agent.WithReportInterval(cfg.ReportInterval),
 		agent.WithPollInterval(cfg.PollInterval),
 		agent.WithKey(cfg.Key),
 		agent.WithPublicKey(pubKey),
+		agent.WithID(cfg.ID),
+		agent.WithIDFile(cfg.IDFile),
+		agent.WithIdentityLabels(cfg.IdentityLabels),
+		agent.WithVersion(buildVersion),
+		agent.WithOutbox(cfg.OutboxDir, cfg.OutboxSize),
+		agent.WithCollectorSettings(collector.NameRuntime, collectorSettings(cfg.Collectors.Runtime)),
+		agent.WithCollectorSettings(collector.NameGopsutil, collectorSettings(cfg.Collectors.Gopsutil)),
+		agent.WithCollectorSettings(collector.NameCPU, collectorSettings(cfg.Collectors.CPU)),
+		agent.WithPush(cfg.PushAddr),
+		agent.WithStatsD(cfg.StatsDAddr),
+		agent.WithTimeout(cfg.RequestTimeout),
+		agent.WithRetry(retry.Policy{
+			MaxRetries:      cfg.RetryMax,
+			InitialInterval: cfg.RetryInterval,
+			MaxInterval:     cfg.RetryMaxInterval,
+			Multiplier:      cfg.RetryMultiplier,
+			Jitter:          cfg.RetryJitter,
+			MaxElapsedTime:  cfg.RetryMaxElapsed,
+		}),
 		agent.WithGRPC(true),
 	)
+	a := agent.New(opts...)
 
 	a.Run()
 }
+
+// collectorSettings Преобразует настройки источника метрик из конфига.
+func collectorSettings(c config.CollectorConf) collector.Settings {
+	return collector.Settings{
+		Enabled:  c.Enabled,
+		Interval: c.Interval.Duration,
+		Timeout:  c.Timeout.Duration,
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"github.com/caarlos0/env/v6"
 	"log"
+	"strings"
 
 	"github.com/sergeysynergy/metricser/config"
 	"github.com/sergeysynergy/metricser/internal/service"
 	"github.com/sergeysynergy/metricser/internal/service/data/repository/filestore"
+	"github.com/sergeysynergy/metricser/internal/service/data/repository/logstore"
 	"github.com/sergeysynergy/metricser/internal/service/data/repository/memory"
 	"github.com/sergeysynergy/metricser/internal/service/data/repository/pgsql"
+	"github.com/sergeysynergy/metricser/internal/service/data/repository/sqlite"
 	"github.com/sergeysynergy/metricser/internal/service/storage"
 	"github.com/sergeysynergy/metricser/pkg/utils"
 )
@@ -39,10 +42,24 @@ func main() {
 	flag.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "path to file with public key")
 	flag.StringVar(&cfg.Addr, "a", cfg.Addr, "address to listen on")
 	flag.StringVar(&cfg.GRPCAddr, "ga", cfg.GRPCAddr, "gRPC server address to listen on")
-	flag.StringVar(&cfg.DatabaseDSN, "d", cfg.DatabaseDSN, "Postgres DSN")
+	flag.StringVar(&cfg.DatabaseDSN, "d", cfg.DatabaseDSN, "Postgres DSN, sqlite:///path/to/file.db for SQLite or log:///path/to/file for the embedded log store")
 	flag.StringVar(&cfg.StoreFile, "f", cfg.StoreFile, "file to store metrics")
+	flag.StringVar(&cfg.BatchFile, "batch-file", cfg.BatchFile, "file to keep IDs of accepted metric batches, empty to keep them in memory only")
 	flag.StringVar(&cfg.Key, "k", cfg.Key, "sign key")
 	flag.DurationVar(&cfg.StoreInterval, "i", cfg.StoreInterval, "interval for saving to file")
+	flag.DurationVar(&cfg.HistoryRetention.Duration, "hr", cfg.HistoryRetention.Duration, "how long to keep metrics history")
+	flag.StringVar(&cfg.LogSync, "log-sync", cfg.LogSync, "when to fsync the embedded log store: always, interval or none")
+	flag.DurationVar(&cfg.LogSyncInterval.Duration, "log-sync-interval", cfg.LogSyncInterval.Duration, "interval for syncing the embedded log store to disk")
+	flag.Int64Var(&cfg.LogCompactSize, "log-compact-size", cfg.LogCompactSize, "embedded log store size in bytes that triggers compaction, 0 for default")
+	flag.StringVar(&cfg.StatsDAddr, "statsd", cfg.StatsDAddr, "UDP address to accept StatsD metrics, empty to disable")
+	flag.StringVar(&cfg.StatsDTCPAddr, "statsd-tcp", cfg.StatsDTCPAddr, "TCP address to accept StatsD metrics, empty to disable")
+	flag.DurationVar(&cfg.StatsDFlush, "statsd-flush", cfg.StatsDFlush, "interval for flushing aggregated StatsD metrics")
+	flag.StringVar(&cfg.GraphiteAddr, "graphite", cfg.GraphiteAddr, "TCP address to accept Graphite plaintext metrics, empty to disable")
+	flag.StringVar(&cfg.GraphiteUDPAddr, "graphite-udp", cfg.GraphiteUDPAddr, "UDP address to accept Graphite plaintext metrics, empty to disable")
+	flag.DurationVar(&cfg.GraphiteFlush, "graphite-flush", cfg.GraphiteFlush, "interval for flushing received Graphite metrics")
+	flag.DurationVar(&cfg.ScrapeInterval, "scrape-interval", cfg.ScrapeInterval, "default interval for scraping Prometheus targets")
+	flag.DurationVar(&cfg.ScrapeTimeout, "scrape-timeout", cfg.ScrapeTimeout, "default timeout for scraping Prometheus targets")
+	flag.DurationVar(&cfg.SetResetInterval, "set-reset", cfg.SetResetInterval, "window for counting unique values of set metrics, 0 to never reset")
 	flag.BoolVar(&cfg.Restore, "r", cfg.Restore, "restore metrics from file")
 	flag.StringVar(&cfg.CryptoKey, "crypto-key", cfg.CryptoKey, "path to file with public key")
 	flag.StringVar(&cfg.TrustedSubnet, "t", cfg.TrustedSubnet, "CIDR - Classless Inter-Domain Routing")
@@ -60,10 +77,13 @@ func main() {
 	var _ storage.Repo = new(pgsql.Storage)
 
 	// Получим реализацию репозитория для работы с БД.
-	repo := storage.Repo(memory.New())
-	repoDB := pgsql.New(cfg.DatabaseDSN)
-	if repoDB != nil {
-		repo = storage.Repo(repoDB)
+	repo, durable := newRepo(cfg)
+
+	// Базы данных и журнал сами сохраняют метрики между запусками: восстановление из файла
+	// повторно добавило бы к ним приращения счётчиков.
+	restore := cfg.Restore && !durable
+	if cfg.Restore && durable {
+		log.Println("[INFO] Restoring metrics from file skipped: metrics are kept by the database")
 	}
 
 	// Проверка на выполнение контракта интерфейса.
@@ -76,8 +96,11 @@ func main() {
 	uc := storage.New(
 		storage.WithDBStorer(repo),
 		storage.WithFileStorer(fileStorer),
-		storage.WithRestore(cfg.Restore),
+		storage.WithRestore(restore),
+		storage.WithBatchFile(cfg.BatchFile),
 		storage.WithStoreInterval(cfg.StoreInterval),
+		storage.WithSetResetInterval(cfg.SetResetInterval),
+		storage.WithHistoryRetention(cfg.HistoryRetention.Duration),
 	)
 
 	// Подключим обработчики запросов.
@@ -85,3 +108,41 @@ func main() {
 	srv := service.New(cfg, uc)
 	srv.Run()
 }
+
+// newRepo Выбирает реализацию репозитория по DSN: `sqlite:///path/to/file.db` — файл SQLite,
+// `log:///path/to/file` — встроенный журнал на диске, иначе — Postgres;
+// при пустом DSN метрики хранятся только в памяти. Второе значение сообщает, сохраняет ли репозиторий
+// метрики между запусками.
+func newRepo(cfg *config.ServerConf) (storage.Repo, bool) {
+	switch {
+	case strings.HasPrefix(cfg.DatabaseDSN, "sqlite://"):
+		repo, err := sqlite.New(strings.TrimPrefix(cfg.DatabaseDSN, "sqlite://"))
+		if err != nil {
+			log.Fatal("[FATAL] SQLite initialization failed - ", err)
+		}
+		return repo, true
+	case strings.HasPrefix(cfg.DatabaseDSN, "log://"):
+		policy, err := logstore.ParseSyncPolicy(cfg.LogSync)
+		if err != nil {
+			log.Fatal("[FATAL] Bad log store config - ", err)
+		}
+		repo, err := logstore.New(
+			strings.TrimPrefix(cfg.DatabaseDSN, "log://"),
+			logstore.WithSyncPolicy(policy),
+			logstore.WithSyncInterval(cfg.LogSyncInterval.Duration),
+			logstore.WithCompactSize(cfg.LogCompactSize),
+			logstore.WithHistory(cfg.HistoryRetention.Duration),
+		)
+		if err != nil {
+			log.Fatal("[FATAL] Log store initialization failed - ", err)
+		}
+		return repo, true
+	}
+
+	repoDB := pgsql.New(cfg.DatabaseDSN)
+	if repoDB != nil {
+		return repoDB, true
+	}
+
+	return memory.New(memory.WithHistory(cfg.HistoryRetention.Duration)), false
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/sergeysynergy/metricser/pkg/crypter"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -33,28 +34,155 @@ func (d *Duration) UnmarshalJSON(b []byte) (err error) {
 	return nil
 }
 
+// UnmarshalText Позволяет задавать длительность в переменных окружения.
+func (d *Duration) UnmarshalText(b []byte) (err error) {
+	d.Duration, err = time.ParseDuration(string(b))
+	return err
+}
+
+// CollectorConf Настройки опроса источника метрик агента.
+type CollectorConf struct {
+	Enabled  bool     `env:"ENABLED" json:"enabled"`
+	Interval Duration `env:"INTERVAL" json:"interval"`
+	Timeout  Duration `env:"TIMEOUT" json:"timeout"`
+}
+
+// DiskCollectorConf Настройки источника метрик файловых систем агента.
+// Фильтры задаются регулярными выражениями, совпадающими со значением целиком.
+type DiskCollectorConf struct {
+	CollectorConf
+	IncludeFSTypes     []string `env:"INCLUDE_FSTYPES" json:"include_fstypes"`
+	ExcludeFSTypes     []string `env:"EXCLUDE_FSTYPES" json:"exclude_fstypes"`
+	IncludeMountpoints []string `env:"INCLUDE_MOUNTPOINTS" json:"include_mountpoints"`
+	ExcludeMountpoints []string `env:"EXCLUDE_MOUNTPOINTS" json:"exclude_mountpoints"`
+}
+
+// NetCollectorConf Настройки источника сетевых метрик агента.
+// Фильтры задаются регулярными выражениями, совпадающими с именем интерфейса целиком.
+type NetCollectorConf struct {
+	CollectorConf
+	IncludeInterfaces []string `env:"INCLUDE_INTERFACES" json:"include_interfaces"`
+	ExcludeInterfaces []string `env:"EXCLUDE_INTERFACES" json:"exclude_interfaces"`
+	TCP               bool     `env:"TCP" json:"tcp"`
+}
+
+// ProcessCollectorConf Настройки источника метрик процессов агента.
+// Процессы отбираются по регулярному выражению имени, файлу с идентификатором процесса
+// или пути контрольной группы относительно /sys/fs/cgroup.
+type ProcessCollectorConf struct {
+	CollectorConf
+	Names    []string `env:"NAMES" json:"names"`
+	PidFiles []string `env:"PID_FILES" json:"pid_files"`
+	Cgroups  []string `env:"CGROUPS" json:"cgroups"`
+}
+
+// CgroupCollectorConf Настройки источника метрик контрольной группы cgroup v2 агента.
+type CgroupCollectorConf struct {
+	CollectorConf
+	Root string `env:"ROOT" json:"root"`
+}
+
+// ExecCollectorConf Настройки внешней команды, выводящей метрики.
+// Команды задаются только в файле конфигурации.
+type ExecCollectorConf struct {
+	CollectorConf
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Env     []string `json:"env"`
+	Dir     string   `json:"dir"`
+}
+
+// CollectorsConf Настройки источников метрик агента.
+type CollectorsConf struct {
+	Runtime  CollectorConf        `envPrefix:"COLLECTOR_RUNTIME_" json:"runtime"`
+	Gopsutil CollectorConf        `envPrefix:"COLLECTOR_GOPSUTIL_" json:"gopsutil"`
+	CPU      CollectorConf        `envPrefix:"COLLECTOR_CPU_" json:"cpu"`
+	Disk     DiskCollectorConf    `envPrefix:"COLLECTOR_DISK_" json:"disk"`
+	Net      NetCollectorConf     `envPrefix:"COLLECTOR_NET_" json:"net"`
+	Process  ProcessCollectorConf `envPrefix:"COLLECTOR_PROCESS_" json:"process"`
+	Cgroup   CgroupCollectorConf  `envPrefix:"COLLECTOR_CGROUP_" json:"cgroup"`
+	Exec     []ExecCollectorConf  `json:"exec"`
+}
+
+// ScrapeTargetConf Настройки источника метрик в текстовом формате Prometheus, опрашиваемого сервером.
+// Цели задаются только в файле конфигурации.
+type ScrapeTargetConf struct {
+	Job       string            `json:"job"`
+	URL       string            `json:"url"`
+	Interval  Duration          `json:"interval"`
+	Timeout   Duration          `json:"timeout"`
+	Labels    map[string]string `json:"labels"`
+	BasicAuth *BasicAuthConf    `json:"basic_auth"`
+	TLS       TLSConf           `json:"tls"`
+}
+
+// BasicAuthConf Учётные данные базовой аутентификации HTTP.
+type BasicAuthConf struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordFile string `json:"password_file"`
+}
+
+// TLSConf Настройки TLS-соединения.
+type TLSConf struct {
+	CAFile             string `json:"ca_file"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	ServerName         string `json:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
 type ServerConf struct {
-	Addr            string        `env:"ADDRESS" json:"address"`
-	GRPCAddr        string        `env:"GRPC_ADDRESS" json:"grpc_addr"`
-	StoreFile       string        `env:"STORE_FILE" json:"store_file"`
-	Restore         bool          `env:"RESTORE" json:"restore"`
-	MyStoreInterval Duration      `json:"store_interval"`
-	StoreInterval   time.Duration `env:"STORE_INTERVAL"`
-	DatabaseDSN     string        `env:"DATABASE_DSN" json:"database_dsn"`
-	CryptoKey       string        `env:"CRYPTO_KEY" json:"crypto_key"`
-	Key             string        `env:"KEY"`
-	TrustedSubnet   string        `env:"TRUSTED_SUBNET"`
-	ConfigFile      string
-	PrivateKey      *rsa.PrivateKey
+	Addr             string        `env:"ADDRESS" json:"address"`
+	GRPCAddr         string        `env:"GRPC_ADDRESS" json:"grpc_addr"`
+	StoreFile        string        `env:"STORE_FILE" json:"store_file"`
+	Restore          bool          `env:"RESTORE" json:"restore"`
+	BatchFile        string        `env:"BATCH_FILE" json:"batch_file"`
+	MyStoreInterval  Duration      `json:"store_interval"`
+	StoreInterval    time.Duration `env:"STORE_INTERVAL"`
+	DatabaseDSN      string        `env:"DATABASE_DSN" json:"database_dsn"`
+	HistoryRetention Duration      `env:"HISTORY_RETENTION" json:"history_retention"`
+	LogSync          string        `env:"LOG_SYNC" json:"log_sync"`
+	LogSyncInterval  Duration      `env:"LOG_SYNC_INTERVAL" json:"log_sync_interval"`
+	LogCompactSize   int64         `env:"LOG_COMPACT_SIZE" json:"log_compact_size"`
+	StatsDAddr       string        `env:"STATSD_ADDRESS" json:"statsd_address"`
+	StatsDTCPAddr    string        `env:"STATSD_TCP_ADDRESS" json:"statsd_tcp_address"`
+	StatsDFlush      time.Duration `env:"STATSD_FLUSH_INTERVAL"`
+	InfluxTemplate   string        `env:"INFLUX_ID_TEMPLATE" json:"influx_id_template"`
+	InfluxCounters   []string      `env:"INFLUX_COUNTERS" envSeparator:";" json:"influx_counters"`
+	GraphiteAddr     string        `env:"GRAPHITE_ADDRESS" json:"graphite_address"`
+	GraphiteUDPAddr  string        `env:"GRAPHITE_UDP_ADDRESS" json:"graphite_udp_address"`
+	GraphiteFlush    time.Duration `env:"GRAPHITE_FLUSH_INTERVAL"`
+	GraphiteRules    []string      `env:"GRAPHITE_RULES" envSeparator:";" json:"graphite_rules"`
+	ScrapeInterval   time.Duration `env:"SCRAPE_INTERVAL"`
+	ScrapeTimeout    time.Duration `env:"SCRAPE_TIMEOUT"`
+	SetResetInterval time.Duration `env:"SET_RESET_INTERVAL"`
+	CryptoKey        string        `env:"CRYPTO_KEY" json:"crypto_key"`
+	Key              string        `env:"KEY"`
+	TrustedSubnet    string        `env:"TRUSTED_SUBNET"`
+	ConfigFile       string
+	PrivateKey       *rsa.PrivateKey
+
+	// ScrapeTargets Цели опроса задаются только в файле конфигурации.
+	ScrapeTargets []ScrapeTargetConf `json:"scrape_targets"`
 }
 
 func NewServerConf() *ServerConf {
 	defaultCfg := &ServerConf{
-		Addr:          "127.0.0.1:8080",
-		GRPCAddr:      ":3200",
-		StoreFile:     "/tmp/devops-metrics-pgsql.json",
-		Restore:       true,
-		StoreInterval: 300 * time.Second,
+		Addr:             "127.0.0.1:8080",
+		GRPCAddr:         ":3200",
+		StoreFile:        "/tmp/devops-metrics-pgsql.json",
+		Restore:          true,
+		BatchFile:        "/tmp/devops-metrics-batches",
+		StoreInterval:    300 * time.Second,
+		HistoryRetention: Duration{24 * time.Hour},
+		LogSync:          "interval",
+		LogSyncInterval:  Duration{time.Second},
+		StatsDFlush:      10 * time.Second,
+		GraphiteFlush:    10 * time.Second,
+		ScrapeInterval:   15 * time.Second,
+		ScrapeTimeout:    10 * time.Second,
 	}
 
 	if cfgFile, ok := getConfigFile(); ok {
@@ -82,27 +210,100 @@ func (c *ServerConf) Init() {
 }
 
 type AgentConfig struct {
-	Addr             string        `env:"ADDRESS" json:"address"`
-	GRPCAddr         string        `env:"GRPC_ADDRESS" json:"grpc_addr"`
-	MyReportInterval Duration      `json:"report_interval"`
-	MyPollInterval   Duration      `json:"poll_interval"`
-	ReportInterval   time.Duration `env:"REPORT_INTERVAL"`
-	PollInterval     time.Duration `env:"POLL_INTERVAL"`
-	Key              string        `env:"KEY"`
-	CryptoKey        string        `env:"CRYPTO_KEY"`
+	Addr             string         `env:"ADDRESS" json:"address"`
+	GRPCAddr         string         `env:"GRPC_ADDRESS" json:"grpc_addr"`
+	MyReportInterval Duration       `json:"report_interval"`
+	MyPollInterval   Duration       `json:"poll_interval"`
+	ReportInterval   time.Duration  `env:"REPORT_INTERVAL"`
+	PollInterval     time.Duration  `env:"POLL_INTERVAL"`
+	Key              string         `env:"KEY"`
+	CryptoKey        string         `env:"CRYPTO_KEY"`
+	ID               string         `env:"AGENT_ID" json:"agent_id"`
+	IDFile           string         `env:"AGENT_ID_FILE" json:"agent_id_file"`
+	IdentityLabels   bool           `env:"AGENT_LABELS" json:"agent_labels"`
+	OutboxDir        string         `env:"OUTBOX_DIR" json:"outbox_dir"`
+	OutboxSize       int            `env:"OUTBOX_SIZE" json:"outbox_size"`
+	RequestTimeout   time.Duration  `env:"REQUEST_TIMEOUT"`
+	RetryMax         int            `env:"RETRY_MAX" json:"retry_max"`
+	RetryInterval    time.Duration  `env:"RETRY_INTERVAL"`
+	RetryMaxInterval time.Duration  `env:"RETRY_MAX_INTERVAL"`
+	RetryMultiplier  float64        `env:"RETRY_MULTIPLIER" json:"retry_multiplier"`
+	RetryJitter      float64        `env:"RETRY_JITTER" json:"retry_jitter"`
+	RetryMaxElapsed  time.Duration  `env:"RETRY_MAX_ELAPSED"`
+	PushAddr         string         `env:"PUSH_ADDRESS" json:"push_address"`
+	StatsDAddr       string         `env:"STATSD_ADDRESS" json:"statsd_address"`
+	Collectors       CollectorsConf `json:"collectors"`
 	ConfigFile       string
 }
 
-func NewAgentConf() *AgentConfig {
-	defaultCfg := &AgentConfig{
-		Addr:           "127.0.0.1:8080",
-		GRPCAddr:       ":3200",
-		ReportInterval: 10 * time.Second,
-		PollInterval:   2 * time.Second,
+// defaultIDFile Возвращает путь к файлу идентификатора агента в каталоге настроек пользователя,
+// чтобы идентификатор сохранялся после перезагрузки; при недоступности каталога — в рабочем каталоге.
+func defaultIDFile() string {
+	return statePath("agent.id")
+}
+
+// defaultOutboxDir Возвращает путь к очереди неотправленных отчётов агента рядом с файлом идентификатора,
+// чтобы отчёты сохранялись после перезагрузки.
+func defaultOutboxDir() string {
+	return statePath("outbox")
+}
+
+// statePath Возвращает путь к файлу состояния агента в каталоге настроек пользователя;
+// при недоступности каталога — в рабочем каталоге с префиксом `metricser-`.
+func statePath(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "metricser-" + name
+	}
+
+	return filepath.Join(dir, "metricser", name)
+}
+
+// defaultAgentConf Возвращает настройки агента по умолчанию.
+func defaultAgentConf() *AgentConfig {
+	return &AgentConfig{
+		Addr:             "127.0.0.1:8080",
+		GRPCAddr:         ":3200",
+		ReportInterval:   10 * time.Second,
+		PollInterval:     2 * time.Second,
+		IDFile:           defaultIDFile(),
+		OutboxDir:        defaultOutboxDir(),
+		OutboxSize:       1000,
+		RequestTimeout:   4 * time.Second,
+		RetryMax:         5,
+		RetryInterval:    500 * time.Millisecond,
+		RetryMaxInterval: 5 * time.Second,
+		RetryMultiplier:  2,
+		RetryJitter:      0.2,
+		RetryMaxElapsed:  15 * time.Second,
+		Collectors: CollectorsConf{
+			Runtime:  CollectorConf{Enabled: true},
+			Gopsutil: CollectorConf{Enabled: true},
+			CPU:      CollectorConf{Enabled: true},
+			Disk: DiskCollectorConf{
+				CollectorConf:      CollectorConf{Enabled: true},
+				ExcludeFSTypes:     []string{"tmpfs", "devtmpfs", "overlay", "squashfs", "iso9660"},
+				ExcludeMountpoints: []string{"/(dev|proc|sys|run)(/.*)?", "/snap/.*", "/var/lib/docker/.*"},
+			},
+			Net: NetCollectorConf{
+				CollectorConf:     CollectorConf{Enabled: true},
+				ExcludeInterfaces: []string{"lo", "veth.*", "docker.*", "br-.*", "cali.*", "flannel.*", "cni.*"},
+				TCP:               true,
+			},
+			Cgroup: CgroupCollectorConf{
+				CollectorConf: CollectorConf{Enabled: true},
+				Root:          "/sys/fs/cgroup",
+			},
+		},
 	}
+}
+
+func NewAgentConf() *AgentConfig {
+	defaultCfg := defaultAgentConf()
 
 	if cfgFile, ok := getConfigFile(); ok {
-		cfg := &AgentConfig{}
+		// значения, не указанные в файле, остаются значениями по умолчанию
+		cfg := defaultAgentConf()
 		err := LoadFromFile(cfgFile, cfg)
 		if err != nil {
 			log.Println("[ERROR]", err)
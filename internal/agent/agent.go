@@ -5,20 +5,29 @@ import (
 	"context"
 	"crypto/rsa"
 	"github.com/go-resty/resty/v2"
+	"github.com/sergeysynergy/metricser/internal/agent/collector"
 	"github.com/sergeysynergy/metricser/internal/service/data/repository/memory"
 	storage2 "github.com/sergeysynergy/metricser/internal/service/storage"
 	"log"
 	"math/rand"
+	"net"
 	"os"
 	"os/signal"
+	"runtime"
+	"sync"
 	"syscall"
 	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/sergeysynergy/metricser/pkg/retry"
 )
 
 type Agent struct {
+	batchSeq       uint64 // Порядковый номер пакета метрик; первым полем для атомарного доступа
 	ctx            context.Context
 	cancel         context.CancelFunc
 	client         *resty.Client
+	timeout        time.Duration // Таймаут одного запроса отправки отчёта по gRPC
 	storage        storage2.Repo
 	pollInterval   time.Duration
 	reportInterval time.Duration
@@ -28,6 +37,24 @@ type Agent struct {
 	gRPCaddr       string
 	key            string
 	publicKey      *rsa.PublicKey
+	id             string // Идентификатор агента
+	idFile         string // Файл, в котором сохраняется сгенерированный идентификатор агента
+	version        string // Версия сборки агента
+	info           metrics.AgentInfo
+	identityLabels bool           // Добавлять ко всем отправляемым метрикам метки агента и хоста
+	labels         metrics.Labels // Метки, добавляемые ко всем отправляемым метрикам
+	startedAt      int64          // Время запуска агента, входит в идентификаторы пакетов метрик
+	outboxDir      string         // Каталог очереди неотправленных пакетов, пустое значение отключает очередь
+	outboxSize     int            // Максимальное количество пакетов в очереди
+	outbox         *outbox
+	retry          retry.Policy // Правила повтора неудачной отправки отчёта
+	collectors     *collector.Registry
+	gopsutil       *collector.Gopsutil
+	pushAddr       string     // Адрес приёма метрик приложений по HTTP, пустое значение отключает приём
+	statsdAddr     string     // Адрес приёма метрик приложений по протоколу StatsD (UDP)
+	pushHTTPAddr   net.Addr   // Фактический адрес приёма метрик по HTTP
+	pushStatsDAddr net.Addr   // Фактический адрес приёма метрик StatsD
+	pushMu         sync.Mutex // Защищает изменение gauge относительно текущего значения и извлечение скетчей для отчёта
 }
 
 type Option func(agent *Agent)
@@ -41,6 +68,8 @@ func New(opts ...Option) *Agent {
 		defaultAddress        = "127.0.0.1:8080"
 		defaultProtocol       = "http://"
 		defaultTimeout        = 4 * time.Second
+		defaultVersion        = "N/A"
+		defaultOutboxSize     = 1000
 	)
 
 	// Проверим, что репозиторий реализует контракт интерфейса.
@@ -59,19 +88,85 @@ func New(opts ...Option) *Agent {
 		reportInterval: defaultReportInterval,
 		protocol:       defaultProtocol,
 		addr:           defaultAddress,
+		version:        defaultVersion,
+		startedAt:      time.Now().UnixNano(),
+		outboxSize:     defaultOutboxSize,
+		collectors:     collector.NewRegistry(),
+		gopsutil:       collector.NewGopsutil(),
 	}
 	a.client.SetTimeout(defaultTimeout)
 
+	// Источники метрик по умолчанию опрашиваются с частотой опроса агента.
+	a.registerCollector(collector.NewRuntime(), collector.Settings{Enabled: true})
+	a.registerCollector(a.gopsutil, collector.Settings{Enabled: true})
+	a.registerCollector(collector.NewCPU(), collector.Settings{Enabled: true})
+
 	// Применяем в цикле каждую опцию
 	for _, opt := range opts {
 		// вызываем функцию, предоставляющую экземпляр *Agent в качестве аргумента
 		opt(a)
 	}
 
+	a.initIdentity()
+	a.initOutbox()
+
 	// вернуть измененный экземпляр Server
 	return a
 }
 
+// initOutbox Создаёт очередь неотправленных пакетов метрик на диске, если задан её каталог.
+func (a *Agent) initOutbox() {
+	if a.outboxDir == "" {
+		return
+	}
+
+	o, err := newOutbox(a.outboxDir, a.outboxSize)
+	if err != nil {
+		a.handleError(err)
+		return
+	}
+	a.outbox = o
+
+	if n := o.Len(); n > 0 {
+		log.Printf("[INFO] В очереди %d неотправленных отчётов\n", n)
+	}
+}
+
+// initIdentity Определяет идентификатор агента и сведения о хосте, передаваемые вместе с отчётами.
+func (a *Agent) initIdentity() {
+	if a.id == "" {
+		id, err := loadOrCreateID(a.idFile)
+		if err != nil {
+			a.handleError(err)
+		}
+		a.id = id
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		a.handleError(err)
+	}
+
+	a.info = metrics.AgentInfo{
+		ID:       a.id,
+		Hostname: hostname,
+		OS:       runtime.GOOS + "/" + runtime.GOARCH,
+		Version:  a.version,
+	}
+	// метки агента меняют ключи всех серий, поэтому добавляются только по явному запросу
+	if a.identityLabels {
+		a.labels = metrics.Labels{
+			metrics.LabelAgent: a.id,
+		}
+		if hostname != "" {
+			a.labels[metrics.LabelHost] = hostname
+		}
+	}
+
+	log.Printf("[INFO] Agent ID: %s; hostname: %s; OS: %s; version: %s\n",
+		a.info.ID, a.info.Hostname, a.info.OS, a.info.Version)
+}
+
 func WithPublicKey(key *rsa.PublicKey) Option {
 	return func(a *Agent) {
 		a.publicKey = key
@@ -116,6 +211,102 @@ func WithKey(key string) Option {
 	}
 }
 
+// WithID Использует переданный идентификатор агента.
+func WithID(id string) Option {
+	return func(a *Agent) {
+		a.id = id
+	}
+}
+
+// WithIDFile Использует переданный файл для хранения сгенерированного идентификатора агента.
+func WithIDFile(path string) Option {
+	return func(a *Agent) {
+		a.idFile = path
+	}
+}
+
+// WithIdentityLabels Добавляет ко всем отправляемым метрикам метки с идентификатором агента и именем хоста.
+func WithIdentityLabels(enabled bool) Option {
+	return func(a *Agent) {
+		a.identityLabels = enabled
+	}
+}
+
+// WithVersion Использует переданную версию сборки агента.
+func WithVersion(version string) Option {
+	return func(a *Agent) {
+		if version != "" {
+			a.version = version
+		}
+	}
+}
+
+// WithOutbox Использует каталог dir для очереди неотправленных пакетов метрик размером не более size пакетов.
+func WithOutbox(dir string, size int) Option {
+	return func(a *Agent) {
+		a.outboxDir = dir
+		if size > 0 {
+			a.outboxSize = size
+		}
+	}
+}
+
+// WithTimeout Устанавливает таймаут одного запроса отправки отчёта.
+func WithTimeout(timeout time.Duration) Option {
+	return func(a *Agent) {
+		if timeout > 0 {
+			a.client.SetTimeout(timeout)
+			a.timeout = timeout
+		}
+	}
+}
+
+// WithRetry Устанавливает правила повтора неудачной отправки отчёта.
+func WithRetry(p retry.Policy) Option {
+	return func(a *Agent) {
+		a.retry = p
+	}
+}
+
+// WithCollector Добавляет источник метрик c настройками опроса s.
+func WithCollector(c collector.Collector, s collector.Settings) Option {
+	return func(a *Agent) {
+		a.registerCollector(c, s)
+	}
+}
+
+// WithMemoryLimit Использует ограничение памяти l, например контрольной группы контейнера,
+// для метрик TotalMemory и FreeMemory.
+func WithMemoryLimit(l collector.MemoryLimiter) Option {
+	return func(a *Agent) {
+		a.gopsutil.SetMemoryLimiter(l)
+	}
+}
+
+// WithCollectorSettings Изменяет настройки опроса зарегистрированного источника метрик name.
+func WithCollectorSettings(name string, s collector.Settings) Option {
+	return func(a *Agent) {
+		err := a.collectors.Configure(name, s)
+		if err != nil {
+			a.handleError(err)
+		}
+	}
+}
+
+// WithPush Включает приём метрик приложений по HTTP на адресе addr.
+func WithPush(addr string) Option {
+	return func(a *Agent) {
+		a.pushAddr = addr
+	}
+}
+
+// WithStatsD Включает приём метрик приложений по протоколу StatsD (UDP) на адресе addr.
+func WithStatsD(addr string) Option {
+	return func(a *Agent) {
+		a.statsdAddr = addr
+	}
+}
+
 func WithGRPC(grpc bool) Option {
 	return func(a *Agent) {
 		a.grpc = grpc
@@ -127,8 +318,18 @@ func (a *Agent) Run() {
 	// и произойдёт утечка памяти.
 	defer a.cancel()
 
-	go a.pollTicker()
-	go a.gopsutilTicker()
+	err := a.startPush()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for _, e := range a.collectors.Entries() {
+		if !e.Enabled {
+			log.Printf("[INFO] Источник метрик `%s` отключён\n", e.Name())
+			continue
+		}
+		go a.collectTicker(e)
+	}
 	go a.reportTicker()
 
 	// Агент должен штатно завершаться по сигналам: syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT.
@@ -139,6 +340,13 @@ func (a *Agent) Run() {
 	log.Println("Работа агента штатно завершена")
 }
 
+func (a *Agent) registerCollector(c collector.Collector, s collector.Settings) {
+	err := a.collectors.Register(c, s)
+	if err != nil {
+		a.handleError(err)
+	}
+}
+
 func (a *Agent) handleError(err error) {
 	log.Println("Ошибка -", err)
 }
@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/sergeysynergy/metricser/internal/agent/collector"
 	"github.com/sergeysynergy/metricser/internal/service/storage"
 	"github.com/stretchr/testify/assert"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sergeysynergy/metricser/internal/service/delivery/http/handlers"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/sergeysynergy/metricser/pkg/retry"
 )
 
 func TestAgentSendJsonRequest(t *testing.T) {
@@ -180,3 +185,347 @@ func TestAgentSendJsonRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentReportIdentity(t *testing.T) {
+	st := storage.New()
+	handler := handlers.New(st)
+	ts := httptest.NewServer(handler.GetRouter())
+	defer ts.Close()
+
+	a := New(
+		WithAddress(ts.URL[7:]),
+		WithID("test-agent"),
+		WithVersion("v1.0.0"),
+	)
+	err := a.storage.Put(metrics.Alloc, metrics.Gauge(41))
+	assert.NoError(t, err)
+
+	a.report(context.Background())
+
+	// по умолчанию метки агента не добавляются и ключи серий не меняются
+	value, err := st.Get(metrics.Alloc)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(41), value)
+
+	a = New(
+		WithAddress(ts.URL[7:]),
+		WithID("test-agent"),
+		WithVersion("v1.0.0"),
+		WithIdentityLabels(true),
+	)
+	err = a.storage.Put(metrics.Alloc, metrics.Gauge(42))
+	assert.NoError(t, err)
+
+	a.report(context.Background())
+
+	agents, err := st.GetAgents()
+	assert.NoError(t, err)
+	if assert.Len(t, agents, 1) {
+		assert.Equal(t, "test-agent", agents[0].ID)
+		assert.Equal(t, "v1.0.0", agents[0].Version)
+		assert.Equal(t, a.info.Hostname, agents[0].Hostname)
+		assert.NotEmpty(t, agents[0].OS)
+	}
+
+	key := metrics.SeriesKey(metrics.Alloc, metrics.Labels{
+		metrics.LabelAgent: "test-agent",
+		metrics.LabelHost:  a.info.Hostname,
+	})
+	value, err = st.Get(key)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(42), value)
+}
+
+func TestAgentRestoreCounters(t *testing.T) {
+	a := New(
+		WithID("test-agent"),
+		WithIdentityLabels(true),
+	)
+
+	delta := int64(2)
+	orders := metrics.Labels{"shop": "test-agent", "env": ""}
+	for k, v := range a.labels {
+		orders[k] = v
+	}
+	jobs := metrics.Labels{metrics.LabelAgent: "other"}
+	a.restoreCounters([]metrics.Metrics{
+		{ID: "Orders", MType: metrics.TypeCounter, Delta: &delta, Labels: orders},
+		{ID: "Jobs", MType: metrics.TypeCounter, Delta: &delta, Labels: jobs},
+	})
+
+	// метки агента удаляются по имени: метки приложения, в том числе с пустым значением или
+	// значением метки агента, и переопределённые приложением метки агента сохраняются
+	value, err := a.storage.Get(metrics.SeriesKey("Orders", metrics.Labels{"shop": "test-agent", "env": ""}))
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(2), value)
+
+	value, err = a.storage.Get(metrics.SeriesKey("Jobs", jobs))
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(2), value)
+}
+
+func TestAgentOutboxReplay(t *testing.T) {
+	st := storage.New()
+	router := handlers.New(st).GetRouter()
+
+	// первые запросы сервер не принимает
+	down := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	a := New(
+		WithAddress(ts.URL[7:]),
+		WithID("test-agent"),
+		WithOutbox(t.TempDir(), 10),
+	)
+
+	for i := 0; i < 3; i++ {
+		err := a.storage.Put(metrics.PollCount, metrics.Counter(1))
+		assert.NoError(t, err)
+		a.report(context.Background())
+	}
+	assert.Equal(t, 3, a.outbox.Len())
+
+	down = false
+	a.report(context.Background())
+	assert.Equal(t, 0, a.outbox.Len())
+
+	key := metrics.SeriesKey(metrics.PollCount, a.labels)
+	value, err := st.Get(key)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(3), value)
+
+	// повторная доставка уже принятого пакета не изменяет значение счётчика
+	b := &Batch{ID: "test-agent-duplicate", Metrics: []metrics.Metrics{
+		{ID: metrics.PollCount, MType: metrics.TypeCounter, Delta: func() *int64 { v := int64(5); return &v }(), Labels: a.labels},
+	}}
+	for i := 0; i < 2; i++ {
+		_, err = a.sendHTTPReport(context.Background(), b)
+		assert.NoError(t, err)
+	}
+	value, err = st.Get(key)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(8), value)
+}
+
+func TestAgentReportRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		wantErr  bool
+	}{
+		{
+			name:     "Retry on service unavailable",
+			status:   http.StatusServiceUnavailable,
+			attempts: 3,
+		},
+		{
+			name:     "Retry on too many requests",
+			status:   http.StatusTooManyRequests,
+			attempts: 3,
+		},
+		{
+			name:     "No retry on bad request",
+			status:   http.StatusBadRequest,
+			attempts: 1,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := handlers.New(storage.New()).GetRouter()
+
+			// первые два запроса сервер отклоняет
+			attempts := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= 2 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.status)
+					return
+				}
+				router.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			a := New(
+				WithAddress(ts.URL[7:]),
+				WithID("test-agent"),
+				WithRetry(retry.Policy{
+					MaxRetries:      3,
+					InitialInterval: time.Millisecond,
+					Multiplier:      2,
+				}),
+			)
+
+			value := 42.0
+			b := &Batch{ID: "test-agent-retry", Metrics: []metrics.Metrics{
+				{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: &value},
+			}}
+			err := a.sendBatch(context.Background(), b)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.attempts, attempts)
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), retryAfter(""))
+	assert.Equal(t, 2*time.Second, retryAfter("2"))
+	assert.Equal(t, time.Duration(0), retryAfter("-1"))
+	assert.Equal(t, time.Duration(0), retryAfter("garbage"))
+
+	d := retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.Greater(t, d, 50*time.Second)
+	assert.LessOrEqual(t, d, time.Minute)
+}
+
+// testCollector Источник метрик для проверки регистрации пользовательских источников.
+type testCollector struct{}
+
+func (c testCollector) Name() string {
+	return "test"
+}
+
+func (c testCollector) Collect(_ context.Context) (*metrics.ProxyMetrics, error) {
+	prm := metrics.NewProxyMetrics()
+	prm.Gauges["TestGauge"] = 1
+	return prm, nil
+}
+
+func TestAgentCollectors(t *testing.T) {
+	a := New(
+		WithID("test-agent"),
+		WithCollector(testCollector{}, collector.Settings{Enabled: true, Interval: time.Second}),
+		WithCollectorSettings(collector.NameGopsutil, collector.Settings{Enabled: false}),
+	)
+
+	entries := a.collectors.Entries()
+	if assert.Len(t, entries, 4) {
+		assert.Equal(t, collector.NameRuntime, entries[0].Name())
+		assert.False(t, entries[1].Enabled)
+		assert.Equal(t, "test", entries[3].Name())
+	}
+
+	a.collect(entries[3].Collector, time.Second)
+	value, err := a.storage.Get("TestGauge")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(1), value)
+}
+
+func TestAgentPush(t *testing.T) {
+	a := New(
+		WithID("test-agent"),
+		WithPush("127.0.0.1:0"),
+		WithStatsD("127.0.0.1:0"),
+	)
+	defer a.cancel()
+
+	err := a.startPush()
+	assert.NoError(t, err)
+
+	client := resty.New()
+	endpoint := "http://" + a.pushHTTPAddr.String()
+
+	resp, err := client.R().
+		SetBody(`{"id":"Orders","type":"counter","delta":2,"labels":{"shop":"main"}}`).
+		Post(endpoint + "/update/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = client.R().
+		SetBody(`[{"id":"Orders","type":"counter","delta":3,"labels":{"shop":"main"}},{"id":"QueueSize","type":"gauge","value":5}]`).
+		Post(endpoint + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = client.R().
+		SetBody("QueueSize gauge 7\n").
+		Post(endpoint + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = client.R().
+		SetBody(`{"id":"QueueSize","type":"gauge"}`).
+		Post(endpoint + "/update/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	orders := metrics.SeriesKey("Orders", metrics.Labels{"shop": "main"})
+	value, err := a.storage.Get(orders)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(5), value)
+	value, err = a.storage.Get("QueueSize")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(7), value)
+
+	conn, err := net.Dial("udp", a.pushStatsDAddr.String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("Orders:1|c|#shop:main\nOrders:1|c|@0.5|#shop:main\nQueueSize:+3|g\nbad line\n"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		v, errGet := a.storage.Get(orders)
+		return errGet == nil && v == metrics.Counter(8)
+	}, time.Second, 10*time.Millisecond)
+	value, err = a.storage.Get("QueueSize")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(10), value)
+
+	// значения таймера накапливаются в скетче с учётом доли отправленных значений
+	_, err = conn.Write([]byte("Latency:10|ms|@0.5\nLatency:30|ms\n"))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		v, errGet := a.storage.Get("Latency")
+		s, ok := v.(metrics.Summary)
+		return errGet == nil && ok && s.Count == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// значения множества накапливаются в метрике `set`
+	_, err = conn.Write([]byte("Users:alice|s\nUsers:bob|s\nUsers:alice|s\n"))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		v, errGet := a.storage.Get("Users")
+		s, ok := v.(metrics.Set)
+		return errGet == nil && ok && s.Estimate() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	hm, err := a.takeMetrics()
+	assert.NoError(t, err)
+	found := 0
+	for _, m := range hm {
+		switch m.ID {
+		case "Latency":
+			found++
+			assert.Equal(t, metrics.TypeSummary, m.MType)
+			assert.Equal(t, 50.0, m.Summary.Sum)
+		case "Users":
+			found++
+			assert.Equal(t, metrics.TypeSet, m.MType)
+			assert.Equal(t, uint64(2), m.Set.Estimate())
+		}
+	}
+	assert.Equal(t, 2, found)
+	prm, err := a.storage.GetMetrics()
+	assert.NoError(t, err)
+	assert.Empty(t, prm.Summaries)
+	assert.Empty(t, prm.Sets)
+
+	resp, err = client.R().
+		SetBody(strings.Repeat("QueueSize gauge 7\n", maxPushBody/10)).
+		Post(endpoint + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode())
+}
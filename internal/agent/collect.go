@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/agent/collector"
+)
+
+// Выполняем регулярный опрос источника метрик пока не пришёл сигнал отмены.
+func (a *Agent) collectTicker(e collector.Entry) {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = a.pollInterval
+	}
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = interval
+	}
+
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-ticker.C:
+			a.collect(e.Collector, timeout)
+		case <-a.ctx.Done():
+			log.Printf("[INFO] Штатное завершение работы источника метрик `%s`\n", e.Name())
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// collect Опрашивает источник метрик и сохраняет полученные значения в хранилище агента.
+func (a *Agent) collect(c collector.Collector, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(a.ctx, timeout)
+	defer cancel()
+
+	prm, err := c.Collect(ctx)
+	if err != nil {
+		a.handleError(fmt.Errorf("ошибка опроса источника метрик `%s` - %w", c.Name(), err))
+		return
+	}
+
+	err = a.storage.PutMetrics(prm)
+	if err != nil {
+		a.handleError(fmt.Errorf("ошибка обновления метрик источника `%s` - %w", c.Name(), err))
+		return
+	}
+
+	log.Printf("[INFO] Выполнено обновление метрик источника `%s`\n", c.Name())
+}
@@ -0,0 +1,326 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	NameCgroup = "cgroup"
+
+	defaultSelfCgroup   = "/proc/self/cgroup"
+	defaultSelfCgroupNS = "/proc/self/ns/cgroup"
+	defaultInitCgroupNS = "/proc/1/ns/cgroup"
+
+	// initCgroupNSInode Номер inode начального пространства имён cgroup в ядре Linux
+	initCgroupNSInode = 0xEFFFFFFB
+)
+
+// Cgroup Собирает потребление ресурсов контрольной группы cgroup v2, в которой запущен агент:
+// память, процессорное время и его ограничение, ввод-вывод и количество процессов.
+// Накопительные значения передаются приращениями между опросами,
+// поэтому первый опрос только запоминает их.
+type Cgroup struct {
+	mu         sync.Mutex
+	dir        string // Каталог контрольной группы
+	namespaced bool   // Агент запущен в собственном пространстве имён cgroup, например в контейнере
+	prev       map[string]uint64
+	prevAt     time.Time
+	now        func() time.Time
+}
+
+// NewCgroup Определяет контрольную группу агента в иерархии cgroup v2, смонтированной в root.
+// Пустое значение root соответствует /sys/fs/cgroup.
+func NewCgroup(root string) (*Cgroup, error) {
+	if root == "" {
+		root = defaultCgroupRoot
+	}
+
+	return newCgroup(root, defaultSelfCgroup, defaultSelfCgroupNS, defaultInitCgroupNS)
+}
+
+func newCgroup(root, selfCgroup, selfNS, initNS string) (*Cgroup, error) {
+	// в гибридном режиме иерархия cgroup v2 смонтирована в подкаталог unified
+	if !fileExists(filepath.Join(root, "cgroup.controllers")) {
+		unified := filepath.Join(root, "unified")
+		if !fileExists(filepath.Join(unified, "cgroup.controllers")) {
+			return nil, fmt.Errorf("cgroup v2 hierarchy not found in %s", root)
+		}
+		root = unified
+	}
+
+	path, err := readSelfCgroup(selfCgroup)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cgroup{
+		dir:  root,
+		prev: make(map[string]uint64),
+		now:  time.Now,
+	}
+
+	namespaced, ok := cgroupNamespaced(selfNS, initNS)
+	if !ok {
+		// без доступа к пространствам имён считаем, что корнем иерархии группа агента видна только в собственном
+		namespaced = path == "/"
+	}
+	c.namespaced = namespaced
+
+	// группа агента находится по пути из /proc/self/cgroup, если иерархия смонтирована целиком;
+	// в собственном пространстве имён агент видит свою группу корнем иерархии
+	if path != "/" {
+		if dir := filepath.Join(root, path); fileExists(dir) {
+			c.dir = dir
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Cgroup) Name() string {
+	return NameCgroup
+}
+
+// Dir Возвращает каталог контрольной группы агента.
+func (c *Cgroup) Dir() string {
+	return c.dir
+}
+
+// Namespaced Сообщает, запущен ли агент в собственном пространстве имён cgroup.
+func (c *Cgroup) Namespaced() bool {
+	return c.namespaced
+}
+
+// MemoryLimit Возвращает ограничение памяти контрольной группы и текущее потребление памяти.
+// Ограничение учитывается, только если агент запущен в собственном пространстве имён cgroup:
+// на хосте ограничение группы агента не описывает память машины.
+func (c *Cgroup) MemoryLimit() (limit, usage uint64, ok bool) {
+	if !c.namespaced {
+		return 0, 0, false
+	}
+
+	// значение max означает отсутствие ограничения
+	limit, ok = c.readValue("memory.max")
+	if !ok {
+		return 0, 0, false
+	}
+	usage, ok = c.readValue("memory.current")
+	if !ok {
+		return 0, 0, false
+	}
+
+	return limit, usage, true
+}
+
+func (c *Cgroup) Collect(_ context.Context) (*metrics.ProxyMetrics, error) {
+	prm := metrics.NewProxyMetrics()
+	cur := make(map[string]uint64)
+
+	if v, ok := c.readValue("memory.current"); ok {
+		prm.Gauges[metrics.CgroupMemoryCurrent] = metrics.Gauge(v)
+	}
+	// значение max означает отсутствие ограничения
+	if v, ok := c.readValue("memory.max"); ok {
+		prm.Gauges[metrics.CgroupMemoryMax] = metrics.Gauge(v)
+	}
+	if v, ok := c.readValue("pids.current"); ok {
+		prm.Gauges[metrics.CgroupPids] = metrics.Gauge(v)
+	}
+	if v, ok := c.readValue("pids.max"); ok {
+		prm.Gauges[metrics.CgroupPidsMax] = metrics.Gauge(v)
+	}
+
+	if events, err := c.readKeyValues("memory.events"); err == nil {
+		cur[metrics.CgroupOOMKills] = events["oom_kill"]
+	}
+
+	if stat, err := c.readKeyValues("cpu.stat"); err == nil {
+		cur[metrics.CgroupCPUUsage] = stat["usage_usec"]
+		cur[metrics.CgroupCPUUser] = stat["user_usec"]
+		cur[metrics.CgroupCPUSystem] = stat["system_usec"]
+		cur[metrics.CgroupCPUPeriods] = stat["nr_periods"]
+		cur[metrics.CgroupCPUThrottled] = stat["nr_throttled"]
+		cur[metrics.CgroupCPUThrottledTime] = stat["throttled_usec"]
+	}
+
+	if io, err := c.readIOStat(); err == nil {
+		for device, stat := range io {
+			labels := metrics.Labels{LabelDevice: device}
+			cur[metrics.SeriesKey(metrics.CgroupIOReadBytes, labels)] = stat["rbytes"]
+			cur[metrics.SeriesKey(metrics.CgroupIOWriteBytes, labels)] = stat["wbytes"]
+			cur[metrics.SeriesKey(metrics.CgroupIOReads, labels)] = stat["rios"]
+			cur[metrics.SeriesKey(metrics.CgroupIOWrites, labels)] = stat["wios"]
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if !c.prevAt.IsZero() {
+		for k, v := range cur {
+			prev, ok := c.prev[k]
+			if !ok {
+				continue
+			}
+			prm.Counters[k] = counterDelta(prev, v)
+		}
+
+		// загрузка процессора в процентах от одного ядра
+		if elapsed := now.Sub(c.prevAt).Microseconds(); elapsed > 0 {
+			if usage, ok := cur[metrics.CgroupCPUUsage]; ok {
+				delta := float64(counterDelta(c.prev[metrics.CgroupCPUUsage], usage))
+				prm.Gauges[metrics.CgroupCPUPercent] = metrics.Gauge(delta / float64(elapsed) * 100)
+			}
+		}
+	}
+	c.prev = cur
+	c.prevAt = now
+
+	return prm, nil
+}
+
+// readValue Читает числовое значение из файла контрольной группы.
+// Возвращает false, если файл отсутствует или значение не задано.
+func (c *Cgroup) readValue(name string) (uint64, bool) {
+	data, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		return 0, false
+	}
+
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// readKeyValues Читает файл контрольной группы из строк вида `ключ значение`.
+func (c *Cgroup) readKeyValues(name string) (map[string]uint64, error) {
+	f, err := os.Open(filepath.Join(c.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, errParse := strconv.ParseUint(fields[1], 10, 64)
+		if errParse != nil {
+			continue
+		}
+		res[fields[0]] = v
+	}
+
+	return res, scanner.Err()
+}
+
+// readIOStat Читает файл io.stat из строк вида `8:0 rbytes=1 wbytes=2 rios=3 wios=4`.
+func (c *Cgroup) readIOStat() (map[string]map[string]uint64, error) {
+	f, err := os.Open(filepath.Join(c.dir, "io.stat"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res := make(map[string]map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		stat := make(map[string]uint64, len(fields)-1)
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, errParse := strconv.ParseUint(kv[1], 10, 64)
+			if errParse != nil {
+				continue
+			}
+			stat[kv[0]] = v
+		}
+		res[fields[0]] = stat
+	}
+
+	return res, scanner.Err()
+}
+
+// cgroupNamespaced Определяет, запущен ли агент в собственном пространстве имён cgroup, сравнивая inode
+// пространства имён агента с пространством имён процесса init и с начальным пространством имён ядра:
+// в контейнере со своим пространством имён pid процесс init разделяет пространство имён cgroup с агентом.
+// Возвращает false вторым значением, если пространство имён агента определить не удалось.
+func cgroupNamespaced(selfNS, initNS string) (bool, bool) {
+	self, err := nsInode(selfNS)
+	if err != nil {
+		return false, false
+	}
+
+	if init, errInit := nsInode(initNS); errInit == nil && init != self {
+		return true, true
+	}
+
+	return self != initCgroupNSInode, true
+}
+
+// nsInode Возвращает inode пространства имён по ссылке вида `cgroup:[4026531835]`.
+func nsInode(link string) (uint64, error) {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return 0, err
+	}
+
+	start := strings.IndexByte(target, '[')
+	end := strings.LastIndexByte(target, ']')
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("unexpected namespace link %q", target)
+	}
+
+	return strconv.ParseUint(target[start+1:end], 10, 64)
+}
+
+// readSelfCgroup Возвращает путь контрольной группы cgroup v2 из файла вида /proc/self/cgroup.
+func readSelfCgroup(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// строка иерархии cgroup v2 имеет вид `0::/путь`
+		if line := scanner.Text(); strings.HasPrefix(line, "0::") {
+			return line[3:], nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("cgroup v2 entry not found in %s", path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		assert.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0644)
+		assert.NoError(t, err)
+	}
+}
+
+func TestNewCgroup(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		selfNS     string // Цель ссылки на пространство имён агента, пустое значение — ссылка недоступна
+		initNS     string
+		wantDir    string
+		namespaced bool
+		wantErr    bool
+	}{
+		{
+			name: "Container with cgroup namespace",
+			files: map[string]string{
+				"root/cgroup.controllers": "cpu memory io pids",
+				"self":                    "0::/\n",
+			},
+			wantDir:    "root",
+			namespaced: true,
+		},
+		{
+			name: "Container with private cgroup and pid namespaces",
+			files: map[string]string{
+				"root/cgroup.controllers": "cpu memory io pids",
+				"self":                    "0::/\n",
+			},
+			selfNS:     "cgroup:[4026532500]",
+			initNS:     "cgroup:[4026532500]",
+			wantDir:    "root",
+			namespaced: true,
+		},
+		{
+			name: "Root cgroup on host",
+			files: map[string]string{
+				"root/cgroup.controllers": "cpu memory io pids",
+				"self":                    "0::/\n",
+			},
+			selfNS:  "cgroup:[4026531835]",
+			initNS:  "cgroup:[4026531835]",
+			wantDir: "root",
+		},
+		{
+			name: "Namespace differs from init",
+			files: map[string]string{
+				"root/cgroup.controllers": "cpu memory io pids",
+				"self":                    "0::/\n",
+			},
+			selfNS:     "cgroup:[4026531835]",
+			initNS:     "cgroup:[4026532500]",
+			wantDir:    "root",
+			namespaced: true,
+		},
+		{
+			name: "Systemd service on host",
+			files: map[string]string{
+				"root/cgroup.controllers":                            "cpu memory io pids",
+				"root/system.slice/agent.service/cgroup.controllers": "",
+				"self": "0::/system.slice/agent.service\n",
+			},
+			wantDir: "root/system.slice/agent.service",
+		},
+		{
+			name: "Hybrid hierarchy",
+			files: map[string]string{
+				"root/unified/cgroup.controllers": "",
+				"self":                            "1:cpu:/\n0::/\n",
+			},
+			wantDir:    "root/unified",
+			namespaced: true,
+		},
+		{
+			name: "No cgroup v2",
+			files: map[string]string{
+				"root/memory/memory.usage_in_bytes": "1",
+				"self":                              "4:memory:/\n",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			for name, target := range map[string]string{"self_ns": tt.selfNS, "init_ns": tt.initNS} {
+				if target != "" {
+					assert.NoError(t, os.Symlink(target, filepath.Join(dir, name)))
+				}
+			}
+
+			c, err := newCgroup(filepath.Join(dir, "root"), filepath.Join(dir, "self"),
+				filepath.Join(dir, "self_ns"), filepath.Join(dir, "init_ns"))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, filepath.Join(dir, tt.wantDir), c.Dir())
+			assert.Equal(t, tt.namespaced, c.Namespaced())
+		})
+	}
+}
+
+func TestCgroupCollect(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"root/cgroup.controllers": "cpu memory io pids",
+		"root/memory.current":     "1048576\n",
+		"root/memory.max":         "max\n",
+		"root/memory.events":      "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n",
+		"root/pids.current":       "7\n",
+		"root/pids.max":           "100\n",
+		"root/cpu.stat":           "usage_usec 1000000\nuser_usec 600000\nsystem_usec 400000\nnr_periods 10\nnr_throttled 2\nthrottled_usec 5000\n",
+		"root/io.stat":            "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n",
+		"self":                    "0::/\n",
+	})
+
+	c, err := newCgroup(filepath.Join(dir, "root"), filepath.Join(dir, "self"), "", "")
+	assert.NoError(t, err)
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	prm, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(1048576), prm.Gauges[metrics.CgroupMemoryCurrent])
+	assert.NotContains(t, prm.Gauges, metrics.CgroupMemoryMax)
+	assert.Equal(t, metrics.Gauge(7), prm.Gauges[metrics.CgroupPids])
+	assert.Equal(t, metrics.Gauge(100), prm.Gauges[metrics.CgroupPidsMax])
+	// первый опрос только запоминает накопительные значения
+	assert.Empty(t, prm.Counters)
+
+	now = now.Add(2 * time.Second)
+	writeFiles(t, dir, map[string]string{
+		"root/memory.max": "2097152\n",
+		"root/cpu.stat":   "usage_usec 2000000\nuser_usec 1200000\nsystem_usec 800000\nnr_periods 20\nnr_throttled 5\nthrottled_usec 9000\n",
+		"root/io.stat":    "8:0 rbytes=8192 wbytes=8192 rios=2 wios=2 dbytes=0 dios=0\n",
+	})
+
+	prm, err = c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(2097152), prm.Gauges[metrics.CgroupMemoryMax])
+	assert.InDelta(t, 50, float64(prm.Gauges[metrics.CgroupCPUPercent]), 1e-9)
+	assert.Equal(t, metrics.Counter(1000000), prm.Counters[metrics.CgroupCPUUsage])
+	assert.Equal(t, metrics.Counter(3), prm.Counters[metrics.CgroupCPUThrottled])
+	assert.Equal(t, metrics.Counter(4000), prm.Counters[metrics.CgroupCPUThrottledTime])
+	assert.Equal(t, metrics.Counter(0), prm.Counters[metrics.CgroupOOMKills])
+
+	dev := metrics.Labels{LabelDevice: "8:0"}
+	assert.Equal(t, metrics.Counter(4096), prm.Counters[metrics.SeriesKey(metrics.CgroupIOReadBytes, dev)])
+	assert.Equal(t, metrics.Counter(0), prm.Counters[metrics.SeriesKey(metrics.CgroupIOWriteBytes, dev)])
+}
+
+func TestCgroupMemoryLimit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"root/cgroup.controllers": "cpu memory io pids",
+		"root/memory.current":     "1048576\n",
+		"root/memory.max":         "max\n",
+		"self":                    "0::/\n",
+	})
+
+	c, err := newCgroup(filepath.Join(dir, "root"), filepath.Join(dir, "self"), "", "")
+	assert.NoError(t, err)
+	assert.True(t, c.Namespaced())
+
+	// без ограничения метрики памяти берутся у машины
+	_, _, ok := c.MemoryLimit()
+	assert.False(t, ok)
+
+	writeFiles(t, dir, map[string]string{"root/memory.max": "4194304\n"})
+	limit, usage, ok := c.MemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(4194304), limit)
+	assert.Equal(t, uint64(1048576), usage)
+
+	g := NewGopsutil()
+	g.SetMemoryLimiter(c)
+	prm, err := g.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(4194304), prm.Gauges[metrics.TotalMemory])
+	assert.Equal(t, metrics.Gauge(3145728), prm.Gauges[metrics.FreeMemory])
+
+	// на хосте ограничение группы агента не описывает память машины
+	c.namespaced = false
+	_, _, ok = c.MemoryLimit()
+	assert.False(t, ok)
+}
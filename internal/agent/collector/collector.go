@@ -0,0 +1,88 @@
+// Package collector Пакет описывает источники метрик агента и реестр для их регистрации.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Collector Источник метрик агента.
+type Collector interface {
+	// Name Возвращает уникальное имя источника.
+	Name() string
+	// Collect Собирает текущие значения метрик.
+	Collect(ctx context.Context) (*metrics.ProxyMetrics, error)
+}
+
+// Settings Настройки опроса источника метрик.
+type Settings struct {
+	Enabled  bool
+	Interval time.Duration // Частота опроса, 0 — частота опроса агента по умолчанию
+	Timeout  time.Duration // Ограничение времени одного опроса, 0 — равно частоте опроса
+}
+
+// Entry Зарегистрированный источник метрик и его настройки.
+type Entry struct {
+	Collector
+	Settings
+}
+
+// Registry Реестр источников метрик агента.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+	order   []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: make(map[string]*Entry),
+	}
+}
+
+// Register Добавляет источник метрик в реестр.
+func (r *Registry) Register(c Collector, s Settings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := c.Name()
+	if _, ok := r.entries[name]; ok {
+		return fmt.Errorf("collector %q already registered", name)
+	}
+
+	r.entries[name] = &Entry{Collector: c, Settings: s}
+	r.order = append(r.order, name)
+
+	return nil
+}
+
+// Configure Изменяет настройки зарегистрированного источника метрик.
+func (r *Registry) Configure(name string, s Settings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[name]
+	if !ok {
+		return fmt.Errorf("collector %q not registered", name)
+	}
+	e.Settings = s
+
+	return nil
+}
+
+// Entries Возвращает источники метрик в порядке регистрации.
+func (r *Registry) Entries() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(r.order))
+	for _, name := range r.order {
+		entries = append(entries, *r.entries[name])
+	}
+
+	return entries
+}
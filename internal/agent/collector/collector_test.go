@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	err := r.Register(NewRuntime(), Settings{Enabled: true})
+	assert.NoError(t, err)
+	err = r.Register(NewGopsutil(), Settings{Enabled: true, Interval: time.Second})
+	assert.NoError(t, err)
+
+	err = r.Register(NewRuntime(), Settings{})
+	assert.Error(t, err)
+
+	err = r.Configure(NameGopsutil, Settings{Enabled: false})
+	assert.NoError(t, err)
+	err = r.Configure("unknown", Settings{})
+	assert.Error(t, err)
+
+	entries := r.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, NameRuntime, entries[0].Name())
+		assert.True(t, entries[0].Enabled)
+		assert.Equal(t, NameGopsutil, entries[1].Name())
+		assert.False(t, entries[1].Enabled)
+	}
+}
+
+func TestRuntimeCollect(t *testing.T) {
+	prm, err := NewRuntime().Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, prm.Gauges, metrics.Alloc)
+	assert.Equal(t, metrics.Counter(1), prm.Counters[metrics.PollCount])
+}
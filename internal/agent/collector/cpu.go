@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const NameCPU = "cpu"
+
+// CPU Собирает загрузку каждого ядра процессора и суммарное распределение времени процессора.
+// Значения вычисляются по разнице счётчиков времени между опросами,
+// поэтому первый опрос только запоминает счётчики и не возвращает метрик.
+type CPU struct {
+	mu      sync.Mutex
+	perCPU  []cpu.TimesStat
+	total   *cpu.TimesStat
+	timesFn func(ctx context.Context, perCPU bool) ([]cpu.TimesStat, error)
+}
+
+func NewCPU() *CPU {
+	return &CPU{
+		timesFn: cpu.TimesWithContext,
+	}
+}
+
+func (c *CPU) Name() string {
+	return NameCPU
+}
+
+func (c *CPU) Collect(ctx context.Context) (*metrics.ProxyMetrics, error) {
+	perCPU, err := c.timesFn(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения времени ядер процессора - %w", err)
+	}
+	total, err := c.timesFn(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения времени процессора - %w", err)
+	}
+	if len(total) == 0 {
+		return nil, fmt.Errorf("пустые сведения о времени процессора")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prm := metrics.NewProxyMetrics()
+
+	// количество ядер могло измениться, тогда сравнивать с прошлым опросом нельзя
+	if len(c.perCPU) == len(perCPU) {
+		for k := range perCPU {
+			busy, _, ok := cpuDelta(c.perCPU[k], perCPU[k])
+			if !ok {
+				continue
+			}
+			prm.Gauges[metrics.CPUutilization+strconv.Itoa(k+1)] = metrics.Gauge(busy)
+		}
+	}
+
+	if c.total != nil {
+		if _, d, ok := cpuDelta(*c.total, total[0]); ok {
+			prm.Gauges[metrics.CPUUser] = metrics.Gauge(d.User)
+			prm.Gauges[metrics.CPUSystem] = metrics.Gauge(d.System)
+			prm.Gauges[metrics.CPUIowait] = metrics.Gauge(d.Iowait)
+			prm.Gauges[metrics.CPUIdle] = metrics.Gauge(d.Idle)
+		}
+	}
+
+	c.perCPU = perCPU
+	c.total = &total[0]
+
+	return prm, nil
+}
+
+// cpuDelta Вычисляет загрузку процессора в процентах и долю каждого вида времени
+// в процентах за период между двумя замерами.
+func cpuDelta(prev, cur cpu.TimesStat) (float64, cpu.TimesStat, bool) {
+	total := cpuTotal(cur) - cpuTotal(prev)
+	if total <= 0 {
+		return 0, cpu.TimesStat{}, false
+	}
+
+	percent := func(prev, cur float64) float64 {
+		d := (cur - prev) / total * 100
+		if d < 0 {
+			return 0
+		}
+		if d > 100 {
+			return 100
+		}
+		return d
+	}
+
+	d := cpu.TimesStat{
+		CPU:    cur.CPU,
+		User:   percent(prev.User, cur.User),
+		System: percent(prev.System, cur.System),
+		Idle:   percent(prev.Idle, cur.Idle),
+		Iowait: percent(prev.Iowait, cur.Iowait),
+	}
+	busy := 100 - percent(prev.Idle+prev.Iowait, cur.Idle+cur.Iowait)
+
+	return busy, d, true
+}
+
+// cpuTotal Возвращает суммарное время процессора.
+// Время гостевых систем уже учтено во времени User и Nice.
+func cpuTotal(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
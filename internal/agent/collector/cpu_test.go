@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestCPUCollect(t *testing.T) {
+	polls := []struct {
+		perCPU []cpu.TimesStat
+		total  cpu.TimesStat
+	}{
+		{
+			perCPU: []cpu.TimesStat{
+				{CPU: "cpu0", User: 10, System: 10, Idle: 80},
+				{CPU: "cpu1", User: 0, System: 0, Idle: 100},
+			},
+			total: cpu.TimesStat{CPU: "cpu-total", User: 10, System: 10, Idle: 180},
+		},
+		{
+			perCPU: []cpu.TimesStat{
+				{CPU: "cpu0", User: 60, System: 20, Idle: 120},
+				{CPU: "cpu1", User: 0, System: 0, Idle: 190, Iowait: 10},
+			},
+			total: cpu.TimesStat{CPU: "cpu-total", User: 60, System: 20, Idle: 310, Iowait: 10},
+		},
+	}
+
+	poll := 0
+	c := NewCPU()
+	c.timesFn = func(_ context.Context, perCPU bool) ([]cpu.TimesStat, error) {
+		if perCPU {
+			return polls[poll].perCPU, nil
+		}
+		return []cpu.TimesStat{polls[poll].total}, nil
+	}
+
+	// первый опрос только запоминает счётчики
+	prm, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, prm.Gauges)
+
+	poll++
+	prm, err = c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.InDelta(t, 60, float64(prm.Gauges[metrics.CPUutilization1]), 1e-9)
+	assert.InDelta(t, 0, float64(prm.Gauges["CPUutilization2"]), 1e-9)
+	assert.InDelta(t, 25, float64(prm.Gauges[metrics.CPUUser]), 1e-9)
+	assert.InDelta(t, 5, float64(prm.Gauges[metrics.CPUSystem]), 1e-9)
+	assert.InDelta(t, 5, float64(prm.Gauges[metrics.CPUIowait]), 1e-9)
+	assert.InDelta(t, 65, float64(prm.Gauges[metrics.CPUIdle]), 1e-9)
+}
+
+func TestCPUCollectReal(t *testing.T) {
+	c := NewCPU()
+
+	_, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+	_, err = c.Collect(context.Background())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	NameDisk = "disk"
+
+	LabelMountpoint = "mountpoint"
+	LabelFSType     = "fstype"
+	LabelDevice     = "device"
+)
+
+// DiskFilter Списки регулярных выражений для отбора файловых систем.
+type DiskFilter struct {
+	IncludeFSTypes     []string
+	ExcludeFSTypes     []string
+	IncludeMountpoints []string
+	ExcludeMountpoints []string
+}
+
+// Disk Собирает заполненность файловых систем по точкам монтирования
+// и счётчики операций ввода-вывода по устройствам.
+// Счётчики ввода-вывода передаются приращениями между опросами,
+// поэтому первый опрос только запоминает их значения.
+type Disk struct {
+	mu          sync.Mutex
+	fsTypes     *Filter
+	mountpoints *Filter
+	prevIO      map[string]disk.IOCountersStat
+
+	partitionsFn func(ctx context.Context, all bool) ([]disk.PartitionStat, error)
+	usageFn      func(ctx context.Context, path string) (*disk.UsageStat, error)
+	ioCountersFn func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+}
+
+func NewDisk(f DiskFilter) (*Disk, error) {
+	fsTypes, err := NewFilter(f.IncludeFSTypes, f.ExcludeFSTypes)
+	if err != nil {
+		return nil, err
+	}
+	mountpoints, err := NewFilter(f.IncludeMountpoints, f.ExcludeMountpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Disk{
+		fsTypes:      fsTypes,
+		mountpoints:  mountpoints,
+		partitionsFn: disk.PartitionsWithContext,
+		usageFn:      disk.UsageWithContext,
+		ioCountersFn: disk.IOCountersWithContext,
+	}, nil
+}
+
+func (c *Disk) Name() string {
+	return NameDisk
+}
+
+func (c *Disk) Collect(ctx context.Context) (*metrics.ProxyMetrics, error) {
+	partitions, err := c.partitionsFn(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка файловых систем - %w", err)
+	}
+
+	prm := metrics.NewProxyMetrics()
+	devices := make([]string, 0, len(partitions))
+	seen := make(map[string]bool, len(partitions))
+
+	for _, p := range partitions {
+		if !c.fsTypes.Match(p.Fstype) || !c.mountpoints.Match(p.Mountpoint) {
+			continue
+		}
+
+		u, errUsage := c.usageFn(ctx, p.Mountpoint)
+		if errUsage != nil {
+			// недоступная точка монтирования не должна мешать остальным
+			continue
+		}
+
+		labels := metrics.Labels{LabelMountpoint: p.Mountpoint, LabelFSType: p.Fstype}
+		prm.Gauges[metrics.SeriesKey(metrics.DiskTotal, labels)] = metrics.Gauge(u.Total)
+		prm.Gauges[metrics.SeriesKey(metrics.DiskUsed, labels)] = metrics.Gauge(u.Used)
+		prm.Gauges[metrics.SeriesKey(metrics.DiskFree, labels)] = metrics.Gauge(u.Free)
+		prm.Gauges[metrics.SeriesKey(metrics.DiskUsedPercent, labels)] = metrics.Gauge(u.UsedPercent)
+		prm.Gauges[metrics.SeriesKey(metrics.DiskInodesTotal, labels)] = metrics.Gauge(u.InodesTotal)
+		prm.Gauges[metrics.SeriesKey(metrics.DiskInodesUsed, labels)] = metrics.Gauge(u.InodesUsed)
+		prm.Gauges[metrics.SeriesKey(metrics.DiskInodesFree, labels)] = metrics.Gauge(u.InodesFree)
+
+		if name := deviceName(p.Device); name != "" && !seen[name] {
+			seen[name] = true
+			devices = append(devices, name)
+		}
+	}
+
+	if len(devices) == 0 {
+		return prm, nil
+	}
+
+	io, err := c.ioCountersFn(ctx, devices...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения счётчиков ввода-вывода - %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.prevIO != nil {
+		for name, cur := range io {
+			prev, ok := c.prevIO[name]
+			if !ok {
+				continue
+			}
+
+			labels := metrics.Labels{LabelDevice: name}
+			prm.Counters[metrics.SeriesKey(metrics.DiskReadBytes, labels)] = counterDelta(prev.ReadBytes, cur.ReadBytes)
+			prm.Counters[metrics.SeriesKey(metrics.DiskWriteBytes, labels)] = counterDelta(prev.WriteBytes, cur.WriteBytes)
+			prm.Counters[metrics.SeriesKey(metrics.DiskReads, labels)] = counterDelta(prev.ReadCount, cur.ReadCount)
+			prm.Counters[metrics.SeriesKey(metrics.DiskWrites, labels)] = counterDelta(prev.WriteCount, cur.WriteCount)
+		}
+	}
+	c.prevIO = io
+
+	return prm, nil
+}
+
+// deviceName Возвращает имя блочного устройства, под которым оно учитывается в счётчиках ввода-вывода.
+func deviceName(device string) string {
+	if !strings.HasPrefix(device, "/dev/") {
+		return ""
+	}
+
+	// устройства LVM и другие символьные ссылки учитываются под именем реального устройства
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved
+	}
+
+	return filepath.Base(device)
+}
+
+// counterDelta Возвращает приращение накопительного счётчика; при сбросе счётчика приращением считается
+// его текущее значение.
+func counterDelta(prev, cur uint64) metrics.Counter {
+	if cur < prev {
+		return metrics.Counter(cur)
+	}
+	return metrics.Counter(cur - prev)
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestDiskCollect(t *testing.T) {
+	c, err := NewDisk(DiskFilter{
+		ExcludeFSTypes:     []string{"tmpfs"},
+		ExcludeMountpoints: []string{"/boot.*"},
+	})
+	assert.NoError(t, err)
+
+	c.partitionsFn = func(_ context.Context, _ bool) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/sda2", Mountpoint: "/boot/efi", Fstype: "vfat"},
+			{Device: "tmpfs", Mountpoint: "/tmp", Fstype: "tmpfs"},
+			{Device: "/dev/sdb1", Mountpoint: "/broken", Fstype: "xfs"},
+		}, nil
+	}
+	c.usageFn = func(_ context.Context, path string) (*disk.UsageStat, error) {
+		if path != "/" {
+			return nil, fmt.Errorf("unexpected path %s", path)
+		}
+		return &disk.UsageStat{Total: 100, Used: 60, Free: 40, UsedPercent: 60, InodesTotal: 10, InodesUsed: 3, InodesFree: 7}, nil
+	}
+	reads := uint64(10)
+	var names []string
+	c.ioCountersFn = func(_ context.Context, n ...string) (map[string]disk.IOCountersStat, error) {
+		names = n
+		return map[string]disk.IOCountersStat{
+			"sda1": {Name: "sda1", ReadBytes: reads * 512, WriteBytes: 1024, ReadCount: reads, WriteCount: 2},
+		}, nil
+	}
+
+	prm, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sda1"}, names)
+
+	root := metrics.Labels{LabelMountpoint: "/", LabelFSType: "ext4"}
+	assert.Equal(t, metrics.Gauge(60), prm.Gauges[metrics.SeriesKey(metrics.DiskUsed, root)])
+	assert.Equal(t, metrics.Gauge(40), prm.Gauges[metrics.SeriesKey(metrics.DiskFree, root)])
+	assert.Equal(t, metrics.Gauge(7), prm.Gauges[metrics.SeriesKey(metrics.DiskInodesFree, root)])
+	assert.Len(t, prm.Gauges, 7)
+	// первый опрос только запоминает счётчики ввода-вывода
+	assert.Empty(t, prm.Counters)
+
+	reads = 15
+	prm, err = c.Collect(context.Background())
+	assert.NoError(t, err)
+
+	dev := metrics.Labels{LabelDevice: "sda1"}
+	assert.Equal(t, metrics.Counter(5), prm.Counters[metrics.SeriesKey(metrics.DiskReads, dev)])
+	assert.Equal(t, metrics.Counter(5*512), prm.Counters[metrics.SeriesKey(metrics.DiskReadBytes, dev)])
+	assert.Equal(t, metrics.Counter(0), prm.Counters[metrics.SeriesKey(metrics.DiskWrites, dev)])
+}
+
+func TestNewDiskInvalidFilter(t *testing.T) {
+	_, err := NewDisk(DiskFilter{IncludeMountpoints: []string{"["}})
+	assert.Error(t, err)
+}
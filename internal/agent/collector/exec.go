@@ -0,0 +1,223 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const NameExecPrefix = "exec:"
+
+// execPassEnv Переменные окружения агента, передаваемые команде. Остальные переменные, в том числе
+// ключи подписи и шифрования, команде недоступны и задаются явно в ExecCommand.Env.
+var execPassEnv = []string{"PATH", "HOME", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+// ExecCommand Внешняя команда, выводящая метрики.
+type ExecCommand struct {
+	Name    string   // Имя команды, уникальное среди источников метрик
+	Command string   // Путь к исполняемому файлу
+	Args    []string // Аргументы команды
+	Env     []string // Переменные окружения вида KEY=value в дополнение к execPassEnv
+	Dir     string   // Рабочий каталог команды
+}
+
+// Exec Запускает внешнюю команду и собирает метрики из её стандартного вывода.
+// Команда выводит либо строки вида `имя тип значение`, либо JSON-массив метрик `metrics.Metrics`.
+// Значения счётчиков считаются приращениями и добавляются к хранимым значениям.
+// Время работы команды ограничивается таймаутом опроса источника.
+type Exec struct {
+	cmd ExecCommand
+}
+
+func NewExec(cmd ExecCommand) (*Exec, error) {
+	if cmd.Name == "" {
+		return nil, fmt.Errorf("exec collector name is empty")
+	}
+	if cmd.Command == "" {
+		return nil, fmt.Errorf("exec collector %q: command is empty", cmd.Name)
+	}
+
+	return &Exec{cmd: cmd}, nil
+}
+
+func (c *Exec) Name() string {
+	return NameExecPrefix + c.cmd.Name
+}
+
+func (c *Exec) Collect(ctx context.Context) (*metrics.ProxyMetrics, error) {
+	cmd := exec.CommandContext(ctx, c.cmd.Command, c.cmd.Args...)
+	cmd.Env = append(execEnv(), c.cmd.Env...)
+	cmd.Dir = c.cmd.Dir
+
+	// вывод пишется во временные файлы, а не в каналы: иначе дочерний процесс команды, унаследовавший
+	// стандартный вывод, задержит завершение Run после завершения самой команды по таймауту
+	stdout, err := os.CreateTemp("", "metricser-exec-*")
+	if err != nil {
+		return nil, err
+	}
+	defer removeTemp(stdout)
+	stderr, err := os.CreateTemp("", "metricser-exec-*")
+	if err != nil {
+		return nil, err
+	}
+	defer removeTemp(stderr)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("команда `%s` прервана по таймауту - %w", c.cmd.Command, ctx.Err())
+		}
+		msg, _ := os.ReadFile(stderr.Name())
+		return nil, fmt.Errorf("ошибка выполнения команды `%s` - %w: %s", c.cmd.Command, err, strings.TrimSpace(string(msg)))
+	}
+
+	out, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseOutput(out)
+}
+
+// execEnv Возвращает переменные окружения агента из списка execPassEnv.
+func execEnv() []string {
+	env := make([]string, 0, len(execPassEnv))
+	for _, key := range execPassEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	return env
+}
+
+func removeTemp(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// ParseOutput Разбирает метрики в виде JSON-массива `metrics.Metrics` или строк вида `имя тип значение`.
+// Имя может содержать метки: `name{label="value"}`. Пустые строки и строки, начинающиеся с #, пропускаются.
+func ParseOutput(data []byte) (*metrics.ProxyMetrics, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return parseJSON(data)
+	}
+
+	prm := metrics.NewProxyMetrics()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		err := parseLine(prm, line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+
+	return prm, scanner.Err()
+}
+
+// parseLine Разбирает строку вида `имя тип значение`; имя с метками может содержать пробелы.
+func parseLine(prm *metrics.ProxyMetrics, line string) error {
+	valuePos := strings.LastIndexByte(line, ' ')
+	if valuePos < 0 {
+		return fmt.Errorf("expected `name type value`, got %q", line)
+	}
+	value := line[valuePos+1:]
+	rest := strings.TrimSpace(line[:valuePos])
+
+	typePos := strings.LastIndexByte(rest, ' ')
+	if typePos < 0 {
+		return fmt.Errorf("expected `name type value`, got %q", line)
+	}
+	mType := rest[typePos+1:]
+	name := strings.TrimSpace(rest[:typePos])
+
+	key, err := normalizeKey(name)
+	if err != nil {
+		return err
+	}
+
+	switch mType {
+	case metrics.TypeGauge:
+		var g metrics.Gauge
+		if err = g.FromString(value); err != nil {
+			return err
+		}
+		prm.Gauges[key] = g
+	case metrics.TypeCounter:
+		var c metrics.Counter
+		if err = c.FromString(value); err != nil {
+			return err
+		}
+		prm.Counters[key] += c
+	default:
+		return fmt.Errorf("unknown metric type %q", mType)
+	}
+
+	return nil
+}
+
+func parseJSON(data []byte) (*metrics.ProxyMetrics, error) {
+	var hm []metrics.Metrics
+	err := json.Unmarshal(data, &hm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metrics: %w", err)
+	}
+
+	prm := metrics.NewProxyMetrics()
+	for _, m := range hm {
+		if m.ID == "" {
+			return nil, fmt.Errorf("empty metric name")
+		}
+		if err = metrics.CheckLabels(m.Labels); err != nil {
+			return nil, err
+		}
+
+		switch m.MType {
+		case metrics.TypeGauge:
+			if m.Value == nil {
+				return nil, fmt.Errorf("metric %q: value is empty", m.ID)
+			}
+			prm.Gauges[m.Key()] = metrics.Gauge(*m.Value)
+		case metrics.TypeCounter:
+			if m.Delta == nil {
+				return nil, fmt.Errorf("metric %q: delta is empty", m.ID)
+			}
+			prm.Counters[m.Key()] += metrics.Counter(*m.Delta)
+		default:
+			return nil, fmt.Errorf("metric %q: unknown type %q", m.ID, m.MType)
+		}
+	}
+
+	return prm, nil
+}
+
+// normalizeKey Проверяет имя метрики с метками и приводит его к ключу серии.
+func normalizeKey(name string) (string, error) {
+	id, labels, err := metrics.ParseSeriesKey(name)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("empty metric name")
+	}
+	if err = metrics.CheckLabels(labels); err != nil {
+		return "", err
+	}
+
+	return metrics.SeriesKey(id, labels), nil
+}
@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		gauges   map[string]metrics.Gauge
+		counters map[string]metrics.Counter
+		wantErr  bool
+	}{
+		{
+			name: "Lines",
+			data: "# business metrics\n\nOrders counter 3\nQueueSize gauge 12.5\nOrders counter 2\n",
+			gauges: map[string]metrics.Gauge{
+				"QueueSize": 12.5,
+			},
+			counters: map[string]metrics.Counter{
+				"Orders": 5,
+			},
+		},
+		{
+			name: "Lines with labels",
+			data: `QueueSize{queue="high prio",env="prod"} gauge 1`,
+			gauges: map[string]metrics.Gauge{
+				metrics.SeriesKey("QueueSize", metrics.Labels{"env": "prod", "queue": "high prio"}): 1,
+			},
+			counters: map[string]metrics.Counter{},
+		},
+		{
+			name: "JSON",
+			data: `[{"id":"QueueSize","type":"gauge","value":7,"labels":{"queue":"low"}},{"id":"Orders","type":"counter","delta":4}]`,
+			gauges: map[string]metrics.Gauge{
+				metrics.SeriesKey("QueueSize", metrics.Labels{"queue": "low"}): 7,
+			},
+			counters: map[string]metrics.Counter{
+				"Orders": 4,
+			},
+		},
+		{
+			name:    "Unknown type",
+			data:    "Orders histogram 1",
+			wantErr: true,
+		},
+		{
+			name:    "Bad value",
+			data:    "Orders counter 1.5",
+			wantErr: true,
+		},
+		{
+			name:    "Bad label",
+			data:    `Orders{1bad="x"} counter 1`,
+			wantErr: true,
+		},
+		{
+			name:    "Missing value",
+			data:    "Orders",
+			wantErr: true,
+		},
+		{
+			name:    "JSON without value",
+			data:    `[{"id":"QueueSize","type":"gauge"}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prm, err := ParseOutput([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.gauges, prm.Gauges)
+			assert.Equal(t, tt.counters, prm.Counters)
+		})
+	}
+}
+
+func TestExecCollect(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "metrics.sh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\necho \"Jobs gauge $JOBS\"\necho \"Files gauge $(ls | wc -l)\"\necho \"Leaked gauge ${KEY:-0}\"\n"), 0755)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "data"), nil, 0644)
+	assert.NoError(t, err)
+
+	c, err := NewExec(ExecCommand{
+		Name:    "jobs",
+		Command: script,
+		Env:     []string{"JOBS=42"},
+		Dir:     dir,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "exec:jobs", c.Name())
+
+	// ключ подписи агента команде не передаётся
+	t.Setenv("KEY", "1")
+
+	prm, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(42), prm.Gauges["Jobs"])
+	assert.Equal(t, metrics.Gauge(0), prm.Gauges["Leaked"])
+	assert.Equal(t, metrics.Gauge(2), prm.Gauges["Files"])
+}
+
+func TestExecCollectTimeout(t *testing.T) {
+	c, err := NewExec(ExecCommand{Name: "sleep", Command: "sleep", Args: []string{"10"}})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = c.Collect(ctx)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// фоновый процесс, унаследовавший стандартный вывод, не задерживает завершение по таймауту
+	c, err = NewExec(ExecCommand{Name: "background", Command: "sh", Args: []string{"-c", "sleep 10 & sleep 10"}})
+	assert.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+	_, err = c.Collect(ctx)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
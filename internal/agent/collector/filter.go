@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Filter Отбирает значения по спискам регулярных выражений включения и исключения.
+// Выражение должно совпадать со значением целиком.
+type Filter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func NewFilter(include, exclude []string) (*Filter, error) {
+	f := &Filter{}
+
+	var err error
+	f.include, err = compilePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	f.exclude, err = compilePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Match Проверяет значение: пустой список включения допускает любое значение,
+// список исключения имеет приоритет.
+func (f *Filter) Match(s string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, re := range f.exclude {
+		if re.MatchString(s) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+
+	return res, nil
+}
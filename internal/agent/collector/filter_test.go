@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		value   string
+		want    bool
+	}{
+		{
+			name:  "Empty filter matches everything",
+			value: "/",
+			want:  true,
+		},
+		{
+			name:    "Include matches",
+			include: []string{"ext4", "xfs"},
+			value:   "xfs",
+			want:    true,
+		},
+		{
+			name:    "Include does not match",
+			include: []string{"ext4"},
+			value:   "ext4dev",
+			want:    false,
+		},
+		{
+			name:    "Exclude wins over include",
+			include: []string{"/.*"},
+			exclude: []string{"/snap/.*"},
+			value:   "/snap/core/1",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.include, tt.exclude)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, f.Match(tt.value))
+		})
+	}
+
+	_, err := NewFilter([]string{"("}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/mem"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const NameGopsutil = "gopsutil"
+
+// MemoryLimiter Источник ограничения доступной памяти, например контрольная группа контейнера.
+type MemoryLimiter interface {
+	// MemoryLimit Возвращает ограничение памяти и её текущее потребление; false, если ограничение не задано.
+	MemoryLimit() (limit, usage uint64, ok bool)
+}
+
+// Gopsutil Собирает метрики памяти посредством пакета `gopsutil`.
+// Если задано ограничение памяти меньше памяти машины, метрики памяти вычисляются по ограничению.
+type Gopsutil struct {
+	limiter MemoryLimiter
+}
+
+func NewGopsutil() *Gopsutil {
+	return &Gopsutil{}
+}
+
+// SetMemoryLimiter Задаёт источник ограничения памяти; вызывается до начала опроса.
+func (c *Gopsutil) SetMemoryLimiter(l MemoryLimiter) {
+	c.limiter = l
+}
+
+func (c *Gopsutil) Name() string {
+	return NameGopsutil
+}
+
+func (c *Gopsutil) Collect(ctx context.Context) (*metrics.ProxyMetrics, error) {
+	prm := metrics.NewProxyMetrics()
+	gauges := make(map[string]metrics.Gauge, 2)
+
+	v, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения метрик посредством пакета `gopsutil` - %w", err)
+	}
+	gauges[metrics.TotalMemory] = metrics.Gauge(v.Total)
+	gauges[metrics.FreeMemory] = metrics.Gauge(v.Free)
+
+	if c.limiter != nil {
+		if limit, usage, ok := c.limiter.MemoryLimit(); ok && limit < v.Total {
+			free := uint64(0)
+			if usage < limit {
+				free = limit - usage
+			}
+			gauges[metrics.TotalMemory] = metrics.Gauge(limit)
+			gauges[metrics.FreeMemory] = metrics.Gauge(free)
+		}
+	}
+
+	prm.Gauges = gauges
+
+	return prm, nil
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/net"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	NameNet = "net"
+
+	LabelInterface = "interface"
+	LabelState     = "state"
+)
+
+// tcpStates Состояния TCP-соединений; количество передаётся для каждого состояния,
+// чтобы значения исчезнувших состояний не оставались в хранилище агента.
+var tcpStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+// NetFilter Настройки источника сетевых метрик.
+type NetFilter struct {
+	IncludeInterfaces []string // Регулярные выражения разрешённых имён интерфейсов
+	ExcludeInterfaces []string // Регулярные выражения запрещённых имён интерфейсов
+	TCP               bool     // Подсчитывать TCP-соединения по состояниям
+}
+
+// Net Собирает счётчики сетевых интерфейсов и количество TCP-соединений по состояниям.
+// Счётчики интерфейсов передаются приращениями между опросами,
+// поэтому первый опрос только запоминает их значения.
+type Net struct {
+	mu         sync.Mutex
+	interfaces *Filter
+	tcp        bool
+	prev       map[string]net.IOCountersStat
+
+	ioCountersFn  func(ctx context.Context, pernic bool) ([]net.IOCountersStat, error)
+	connectionsFn func(ctx context.Context, kind string) ([]net.ConnectionStat, error)
+}
+
+func NewNet(f NetFilter) (*Net, error) {
+	interfaces, err := NewFilter(f.IncludeInterfaces, f.ExcludeInterfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Net{
+		interfaces:    interfaces,
+		tcp:           f.TCP,
+		ioCountersFn:  net.IOCountersWithContext,
+		connectionsFn: net.ConnectionsWithoutUidsWithContext,
+	}, nil
+}
+
+func (c *Net) Name() string {
+	return NameNet
+}
+
+func (c *Net) Collect(ctx context.Context) (*metrics.ProxyMetrics, error) {
+	stats, err := c.ioCountersFn(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения счётчиков сетевых интерфейсов - %w", err)
+	}
+
+	prm := metrics.NewProxyMetrics()
+
+	c.mu.Lock()
+	cur := make(map[string]net.IOCountersStat, len(stats))
+	for _, s := range stats {
+		if !c.interfaces.Match(s.Name) {
+			continue
+		}
+		cur[s.Name] = s
+
+		prev, ok := c.prev[s.Name]
+		if !ok {
+			continue
+		}
+
+		labels := metrics.Labels{LabelInterface: s.Name}
+		prm.Counters[metrics.SeriesKey(metrics.NetBytesRecv, labels)] = counterDelta(prev.BytesRecv, s.BytesRecv)
+		prm.Counters[metrics.SeriesKey(metrics.NetBytesSent, labels)] = counterDelta(prev.BytesSent, s.BytesSent)
+		prm.Counters[metrics.SeriesKey(metrics.NetPacketsRecv, labels)] = counterDelta(prev.PacketsRecv, s.PacketsRecv)
+		prm.Counters[metrics.SeriesKey(metrics.NetPacketsSent, labels)] = counterDelta(prev.PacketsSent, s.PacketsSent)
+		prm.Counters[metrics.SeriesKey(metrics.NetErrorsIn, labels)] = counterDelta(prev.Errin, s.Errin)
+		prm.Counters[metrics.SeriesKey(metrics.NetErrorsOut, labels)] = counterDelta(prev.Errout, s.Errout)
+		prm.Counters[metrics.SeriesKey(metrics.NetDropsIn, labels)] = counterDelta(prev.Dropin, s.Dropin)
+		prm.Counters[metrics.SeriesKey(metrics.NetDropsOut, labels)] = counterDelta(prev.Dropout, s.Dropout)
+	}
+	c.prev = cur
+	c.mu.Unlock()
+
+	if !c.tcp {
+		return prm, nil
+	}
+
+	conns, err := c.connectionsFn(ctx, "tcp")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка TCP-соединений - %w", err)
+	}
+
+	counts := make(map[string]int, len(tcpStates))
+	for _, conn := range conns {
+		counts[conn.Status]++
+	}
+	for _, state := range tcpStates {
+		key := metrics.SeriesKey(metrics.TCPConnections, metrics.Labels{LabelState: state})
+		prm.Gauges[key] = metrics.Gauge(counts[state])
+	}
+
+	return prm, nil
+}
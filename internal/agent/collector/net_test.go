@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestNetCollect(t *testing.T) {
+	c, err := NewNet(NetFilter{
+		ExcludeInterfaces: []string{"veth.*"},
+		TCP:               true,
+	})
+	assert.NoError(t, err)
+
+	recv := uint64(1000)
+	c.ioCountersFn = func(_ context.Context, _ bool) ([]net.IOCountersStat, error) {
+		return []net.IOCountersStat{
+			{Name: "eth0", BytesRecv: recv, BytesSent: 500, PacketsRecv: 10, Dropin: 1},
+			{Name: "veth12ab", BytesRecv: recv},
+		}, nil
+	}
+	c.connectionsFn = func(_ context.Context, _ string) ([]net.ConnectionStat, error) {
+		return []net.ConnectionStat{
+			{Status: "ESTABLISHED"},
+			{Status: "ESTABLISHED"},
+			{Status: "LISTEN"},
+		}, nil
+	}
+
+	prm, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+	// первый опрос только запоминает счётчики интерфейсов
+	assert.Empty(t, prm.Counters)
+	assert.Equal(t, metrics.Gauge(2), prm.Gauges[metrics.SeriesKey(metrics.TCPConnections, metrics.Labels{LabelState: "ESTABLISHED"})])
+	assert.Equal(t, metrics.Gauge(1), prm.Gauges[metrics.SeriesKey(metrics.TCPConnections, metrics.Labels{LabelState: "LISTEN"})])
+	assert.Equal(t, metrics.Gauge(0), prm.Gauges[metrics.SeriesKey(metrics.TCPConnections, metrics.Labels{LabelState: "TIME_WAIT"})])
+	assert.Len(t, prm.Gauges, len(tcpStates))
+
+	recv = 1500
+	prm, err = c.Collect(context.Background())
+	assert.NoError(t, err)
+
+	eth0 := metrics.Labels{LabelInterface: "eth0"}
+	assert.Equal(t, metrics.Counter(500), prm.Counters[metrics.SeriesKey(metrics.NetBytesRecv, eth0)])
+	assert.Equal(t, metrics.Counter(0), prm.Counters[metrics.SeriesKey(metrics.NetBytesSent, eth0)])
+	assert.Len(t, prm.Counters, 8)
+}
@@ -0,0 +1,297 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	NameProcess = "process"
+
+	LabelProcess = "process"
+
+	defaultCgroupRoot = "/sys/fs/cgroup"
+)
+
+// ProcessFilter Способы отбора процессов; процесс отбирается, если подходит под любой из них.
+type ProcessFilter struct {
+	Names    []string // Регулярные выражения имён процессов
+	PidFiles []string // Файлы с идентификаторами процессов
+	Cgroups  []string // Пути контрольных групп относительно /sys/fs/cgroup
+}
+
+// procStat Сведения об отдельном процессе.
+type procStat struct {
+	Name       string
+	CreateTime int64   // Время запуска процесса в миллисекундах
+	CPUTime    float64 // Суммарное процессорное время в секундах
+	RSS        uint64
+	FDs        int32
+	Threads    int32
+}
+
+// procCPU Процессорное время процесса при предыдущем опросе.
+type procCPU struct {
+	createTime int64
+	cpuTime    float64
+	at         time.Time
+}
+
+// Process Собирает метрики отобранных процессов, сгруппированные по имени процесса:
+// загрузку процессора, занятую память, количество открытых файлов и потоков, время работы.
+// Загрузка процессора вычисляется по приращению процессорного времени между опросами.
+type Process struct {
+	mu         sync.Mutex
+	names      *Filter // Отбор по имени; nil, если имена не заданы
+	pidFiles   []string
+	cgroups    []string
+	cgroupRoot string
+	prev       map[int32]procCPU
+	seen       map[string]bool // Имена процессов, о которых передавались метрики
+
+	pidsFn func(ctx context.Context) ([]int32, error)
+	nameFn func(ctx context.Context, pid int32) (string, error)
+	statFn func(ctx context.Context, pid int32) (*procStat, error)
+	now    func() time.Time
+}
+
+func NewProcess(f ProcessFilter) (*Process, error) {
+	c := &Process{
+		pidFiles:   f.PidFiles,
+		cgroups:    f.Cgroups,
+		cgroupRoot: defaultCgroupRoot,
+		prev:       make(map[int32]procCPU),
+		seen:       make(map[string]bool),
+		pidsFn:     process.PidsWithContext,
+		nameFn:     procNameByPid,
+		statFn:     procStatByPid,
+		now:        time.Now,
+	}
+
+	if len(f.Names) > 0 {
+		names, err := NewFilter(f.Names, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.names = names
+	}
+
+	return c, nil
+}
+
+func (c *Process) Name() string {
+	return NameProcess
+}
+
+func (c *Process) Collect(ctx context.Context) (*metrics.ProxyMetrics, error) {
+	pids, err := c.selectPids(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	type group struct {
+		count   int
+		cpu     float64
+		rss     uint64
+		fds     int32
+		threads int32
+		uptime  float64
+	}
+	groups := make(map[string]*group)
+	prev := make(map[int32]procCPU, len(pids))
+
+	for pid, st := range pids {
+		g, ok := groups[st.Name]
+		if !ok {
+			g = &group{}
+			groups[st.Name] = g
+		}
+
+		g.count++
+		g.rss += st.RSS
+		g.fds += st.FDs
+		g.threads += st.Threads
+		if uptime := now.Sub(time.UnixMilli(st.CreateTime)).Seconds(); uptime > g.uptime {
+			g.uptime = uptime
+		}
+
+		// загрузка процессора считается только для того же процесса, а не для процесса с повторно выданным pid
+		if p, ok := c.prev[pid]; ok && p.createTime == st.CreateTime {
+			if elapsed := now.Sub(p.at).Seconds(); elapsed > 0 && st.CPUTime >= p.cpuTime {
+				g.cpu += (st.CPUTime - p.cpuTime) / elapsed * 100
+			}
+		}
+		prev[pid] = procCPU{createTime: st.CreateTime, cpuTime: st.CPUTime, at: now}
+	}
+	c.prev = prev
+
+	// для завершившихся процессов однократно передаём нулевые значения, чтобы их отсутствие было заметно,
+	// и забываем их имена, чтобы не накапливать имена короткоживущих процессов
+	for name := range c.seen {
+		if _, ok := groups[name]; !ok {
+			groups[name] = &group{}
+			delete(c.seen, name)
+		}
+	}
+
+	prm := metrics.NewProxyMetrics()
+	for name, g := range groups {
+		labels := metrics.Labels{LabelProcess: name}
+		prm.Gauges[metrics.SeriesKey(metrics.ProcessCount, labels)] = metrics.Gauge(g.count)
+		prm.Gauges[metrics.SeriesKey(metrics.ProcessCPUPercent, labels)] = metrics.Gauge(g.cpu)
+		prm.Gauges[metrics.SeriesKey(metrics.ProcessRSS, labels)] = metrics.Gauge(g.rss)
+		prm.Gauges[metrics.SeriesKey(metrics.ProcessFDs, labels)] = metrics.Gauge(g.fds)
+		prm.Gauges[metrics.SeriesKey(metrics.ProcessThreads, labels)] = metrics.Gauge(g.threads)
+		prm.Gauges[metrics.SeriesKey(metrics.ProcessUptime, labels)] = metrics.Gauge(g.uptime)
+		if g.count > 0 {
+			c.seen[name] = true
+		}
+	}
+
+	return prm, nil
+}
+
+// selectPids Возвращает сведения об отобранных процессах.
+func (c *Process) selectPids(ctx context.Context) (map[int32]*procStat, error) {
+	selected := make(map[int32]*procStat)
+
+	add := func(pid int32) {
+		if _, ok := selected[pid]; ok {
+			return
+		}
+		st, err := c.statFn(ctx, pid)
+		if err != nil {
+			// процесс мог завершиться между получением списка и опросом
+			return
+		}
+		selected[pid] = st
+	}
+
+	for _, path := range c.pidFiles {
+		pid, err := readPidFile(path)
+		if err != nil {
+			continue
+		}
+		add(pid)
+	}
+
+	for _, cg := range c.cgroups {
+		pids, err := readCgroupProcs(filepath.Join(c.cgroupRoot, cg))
+		if err != nil {
+			continue
+		}
+		for _, pid := range pids {
+			add(pid)
+		}
+	}
+
+	if c.names != nil {
+		pids, err := c.pidsFn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка процессов - %w", err)
+		}
+		for _, pid := range pids {
+			if _, ok := selected[pid]; ok {
+				continue
+			}
+			// полные сведения запрашиваем только для процессов, подходящих по имени
+			name, errName := c.nameFn(ctx, pid)
+			if errName != nil || !c.names.Match(name) {
+				continue
+			}
+			add(pid)
+		}
+	}
+
+	return selected, nil
+}
+
+// procNameByPid Получает имя процесса посредством пакета `gopsutil`.
+func procNameByPid(ctx context.Context, pid int32) (string, error) {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return "", err
+	}
+
+	return p.NameWithContext(ctx)
+}
+
+// procStatByPid Получает сведения о процессе посредством пакета `gopsutil`.
+func procStatByPid(ctx context.Context, pid int32) (*procStat, error) {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &procStat{}
+	if st.Name, err = p.NameWithContext(ctx); err != nil {
+		return nil, err
+	}
+	if st.CreateTime, err = p.CreateTimeWithContext(ctx); err != nil {
+		return nil, err
+	}
+	if times, errTimes := p.TimesWithContext(ctx); errTimes == nil {
+		st.CPUTime = times.User + times.System
+	}
+	if mem, errMem := p.MemoryInfoWithContext(ctx); errMem == nil {
+		st.RSS = mem.RSS
+	}
+	// количество открытых файлов чужих процессов доступно не всегда
+	if fds, errFDs := p.NumFDsWithContext(ctx); errFDs == nil {
+		st.FDs = fds
+	}
+	if threads, errThreads := p.NumThreadsWithContext(ctx); errThreads == nil {
+		st.Threads = threads
+	}
+
+	return st, nil
+}
+
+func readPidFile(path string) (int32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %w", path, err)
+	}
+
+	return int32(pid), nil
+}
+
+func readCgroupProcs(dir string) ([]int32, error) {
+	f, err := os.Open(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pids []int32
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pid, errParse := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 32)
+		if errParse != nil {
+			continue
+		}
+		pids = append(pids, int32(pid))
+	}
+
+	return pids, scanner.Err()
+}
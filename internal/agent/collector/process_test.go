@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestProcessCollect(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "app.pid")
+	err := os.WriteFile(pidFile, []byte("30\n"), 0644)
+	assert.NoError(t, err)
+	err = os.MkdirAll(filepath.Join(dir, "system.slice", "db.service"), 0755)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "system.slice", "db.service", "cgroup.procs"), []byte("40\n41\n"), 0644)
+	assert.NoError(t, err)
+
+	c, err := NewProcess(ProcessFilter{
+		Names:    []string{"nginx"},
+		PidFiles: []string{pidFile},
+		Cgroups:  []string{"system.slice/db.service"},
+	})
+	assert.NoError(t, err)
+	c.cgroupRoot = dir
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start.Add(time.Minute)
+	c.now = func() time.Time { return now }
+
+	cpuTime := 10.0
+	procs := map[int32]*procStat{
+		10: {Name: "nginx", RSS: 100, FDs: 5, Threads: 1},
+		11: {Name: "nginx", RSS: 200, FDs: 7, Threads: 2},
+		20: {Name: "bash", RSS: 300},
+		30: {Name: "app", RSS: 400, Threads: 8},
+		40: {Name: "postgres", RSS: 500},
+		41: {Name: "postgres", RSS: 600},
+	}
+	c.pidsFn = func(_ context.Context) ([]int32, error) {
+		pids := make([]int32, 0, len(procs))
+		for pid := range procs {
+			pids = append(pids, pid)
+		}
+		return pids, nil
+	}
+	c.nameFn = func(_ context.Context, pid int32) (string, error) {
+		st, ok := procs[pid]
+		if !ok {
+			return "", fmt.Errorf("process %d not found", pid)
+		}
+		return st.Name, nil
+	}
+	stats := make(map[int32]int)
+	c.statFn = func(_ context.Context, pid int32) (*procStat, error) {
+		stats[pid]++
+		st, ok := procs[pid]
+		if !ok {
+			return nil, fmt.Errorf("process %d not found", pid)
+		}
+		res := *st
+		res.CreateTime = start.UnixMilli()
+		if pid == 10 {
+			res.CPUTime = cpuTime
+		}
+		return &res, nil
+	}
+
+	prm, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+
+	nginx := metrics.Labels{LabelProcess: "nginx"}
+	assert.Equal(t, metrics.Gauge(2), prm.Gauges[metrics.SeriesKey(metrics.ProcessCount, nginx)])
+	assert.Equal(t, metrics.Gauge(300), prm.Gauges[metrics.SeriesKey(metrics.ProcessRSS, nginx)])
+	assert.Equal(t, metrics.Gauge(12), prm.Gauges[metrics.SeriesKey(metrics.ProcessFDs, nginx)])
+	assert.Equal(t, metrics.Gauge(3), prm.Gauges[metrics.SeriesKey(metrics.ProcessThreads, nginx)])
+	assert.Equal(t, metrics.Gauge(60), prm.Gauges[metrics.SeriesKey(metrics.ProcessUptime, nginx)])
+	assert.Equal(t, metrics.Gauge(0), prm.Gauges[metrics.SeriesKey(metrics.ProcessCPUPercent, nginx)])
+	assert.Equal(t, metrics.Gauge(8), prm.Gauges[metrics.SeriesKey(metrics.ProcessThreads, metrics.Labels{LabelProcess: "app"})])
+	assert.Equal(t, metrics.Gauge(1100), prm.Gauges[metrics.SeriesKey(metrics.ProcessRSS, metrics.Labels{LabelProcess: "postgres"})])
+	assert.NotContains(t, prm.Gauges, metrics.SeriesKey(metrics.ProcessCount, metrics.Labels{LabelProcess: "bash"}))
+	// полные сведения о неотобранных процессах не запрашиваются
+	assert.Zero(t, stats[20])
+
+	// за 10 секунд процесс использовал 5 секунд процессорного времени
+	now = now.Add(10 * time.Second)
+	cpuTime += 5
+	delete(procs, 30)
+
+	prm, err = c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.InDelta(t, 50, float64(prm.Gauges[metrics.SeriesKey(metrics.ProcessCPUPercent, nginx)]), 1e-9)
+	// о завершившемся процессе передаются нулевые значения
+	assert.Equal(t, metrics.Gauge(0), prm.Gauges[metrics.SeriesKey(metrics.ProcessCount, metrics.Labels{LabelProcess: "app"})])
+	assert.Equal(t, metrics.Gauge(0), prm.Gauges[metrics.SeriesKey(metrics.ProcessRSS, metrics.Labels{LabelProcess: "app"})])
+
+	// нулевые значения передаются однократно
+	prm, err = c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.NotContains(t, prm.Gauges, metrics.SeriesKey(metrics.ProcessCount, metrics.Labels{LabelProcess: "app"}))
+	assert.NotContains(t, c.seen, "app")
+}
+
+func TestProcessCollectReal(t *testing.T) {
+	c, err := NewProcess(ProcessFilter{Names: []string{".*"}})
+	assert.NoError(t, err)
+
+	prm, err := c.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, prm.Gauges)
+}
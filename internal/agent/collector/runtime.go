@@ -1,31 +1,27 @@
-package agent
+package collector
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"math/rand"
 	"runtime"
-	"time"
 
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 )
 
-// Выполняем регулярное обновление метрик пока не пришёл сигнал отмены.
-func (a *Agent) pollTicker() {
-	ticker := time.NewTicker(a.pollInterval)
-	for {
-		select {
-		case <-ticker.C:
-			a.pollUpdate()
-		case <-a.ctx.Done():
-			log.Println("[INFO] Штатное завершение работы обновления метрик")
-			ticker.Stop()
-			return
-		}
-	}
+const NameRuntime = "runtime"
+
+// Runtime Собирает метрики среды выполнения Go из `runtime.MemStats`.
+type Runtime struct{}
+
+func NewRuntime() *Runtime {
+	return &Runtime{}
 }
 
-func (a *Agent) pollUpdate() {
+func (c *Runtime) Name() string {
+	return NameRuntime
+}
+
+func (c *Runtime) Collect(_ context.Context) (*metrics.ProxyMetrics, error) {
 	ms := &runtime.MemStats{}
 	runtime.ReadMemStats(ms)
 
@@ -65,10 +61,5 @@ func (a *Agent) pollUpdate() {
 
 	prm.Counters[metrics.PollCount] = 1
 
-	err := a.storage.PutMetrics(prm)
-	if err != nil {
-		a.handleError(fmt.Errorf("ошибка обновления метрик - %w", err))
-	}
-
-	log.Println("[INFO] Выполнено обновление метрик")
+	return prm, nil
 }
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadOrCreateID Возвращает идентификатор агента, сохранённый в файле;
+// при первом запуске генерирует новый идентификатор и сохраняет его.
+func loadOrCreateID(path string) (string, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return id, nil
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to read agent ID file: %w", err)
+		}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	if path != "" {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return id, fmt.Errorf("failed to create agent ID directory: %w", err)
+		}
+		err = os.WriteFile(path, []byte(id+"\n"), 0644)
+		if err != nil {
+			return id, fmt.Errorf("failed to save agent ID: %w", err)
+		}
+	}
+
+	return id, nil
+}
+
+// newID Генерирует случайный идентификатор в формате UUID версии 4.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate agent ID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// outboundIP Определяет IP-адрес интерфейса, через который агент обращается к серверу.
+func outboundIP(addr string) string {
+	const defaultIP = "127.0.0.1"
+
+	// для UDP соединение не устанавливается, выбирается только маршрут
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return defaultIP
+	}
+	defer conn.Close()
+
+	udpAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return defaultIP
+	}
+
+	return udpAddr.IP.String()
+}
@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent", "id")
+
+	id, err := loadOrCreateID(path)
+	require.NoError(t, err)
+	assert.Len(t, id, 36)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, id+"\n", string(data))
+
+	// повторный запуск возвращает сохранённый идентификатор
+	again, err := loadOrCreateID(path)
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	// без файла идентификатор генерируется каждый раз заново
+	other, err := loadOrCreateID("")
+	require.NoError(t, err)
+	assert.NotEqual(t, id, other)
+}
@@ -0,0 +1,279 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	outboxExt    = ".json"
+	outboxTmpExt = ".tmp"
+)
+
+// Batch Пакет метрик одного отчёта агента; идентификатор позволяет серверу отбросить повторную доставку.
+type Batch struct {
+	ID      string            `json:"id"`
+	Metrics []metrics.Metrics `json:"metrics"`
+}
+
+// outbox Ограниченная по размеру очередь неотправленных пакетов метрик на диске.
+// Каждый пакет хранится в отдельном файле, имена файлов задают порядок отправки.
+type outbox struct {
+	mu   sync.Mutex
+	dir  string
+	size int
+	last int64 // Последняя использованная метка времени в имени файла
+}
+
+func newOutbox(dir string, size int) (*outbox, error) {
+	if size < 3 {
+		return nil, fmt.Errorf("outbox size should be >= 3, got %d", size)
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	o := &outbox{dir: dir, size: size}
+
+	// продолжим нумерацию после уже сохранённых пакетов
+	names, err := o.list()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		fmt.Sscanf(names[len(names)-1], "%d", &o.last)
+	}
+
+	return o, nil
+}
+
+// Push Сохраняет пакет в конец очереди. При переполнении объединяются два самых старых пакета после
+// первого: первый пакет мог быть уже принят сервером без получения ответа, и объединённый с ним пакет
+// был бы отброшен сервером как повторный. Объединение описано в mergeBatches.
+func (o *outbox) Push(b *Batch) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	names, err := o.list()
+	if err != nil {
+		return err
+	}
+
+	for len(names) >= o.size {
+		err = o.mergeOldest(names[1], names[2])
+		if err != nil {
+			return err
+		}
+		names = append(names[:1], names[2:]...)
+	}
+
+	seq := time.Now().UnixNano()
+	if seq <= o.last {
+		seq = o.last + 1
+	}
+	o.last = seq
+
+	return o.write(fmt.Sprintf("%020d%s", seq, outboxExt), b)
+}
+
+// Peek Возвращает самый старый пакет очереди и имя его файла; nil, если очередь пуста.
+// Повреждённые файлы удаляются.
+func (o *outbox) Peek() (*Batch, string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	names, err := o.list()
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, name := range names {
+		b, errRead := o.read(name)
+		if errRead != nil {
+			log.Printf("[WARNING] Removing broken outbox file %s - %s\n", name, errRead)
+			os.Remove(filepath.Join(o.dir, name))
+			continue
+		}
+
+		return b, name, nil
+	}
+
+	return nil, "", nil
+}
+
+// Remove Удаляет отправленный пакет из очереди.
+func (o *outbox) Remove(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return os.Remove(filepath.Join(o.dir, name))
+}
+
+// Len Возвращает количество пакетов в очереди.
+func (o *outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	names, err := o.list()
+	if err != nil {
+		return 0
+	}
+
+	return len(names)
+}
+
+func (o *outbox) list() ([]string, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), outboxExt) {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (o *outbox) read(name string) (*Batch, error) {
+	data, err := os.ReadFile(filepath.Join(o.dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Batch{}
+	err = json.Unmarshal(data, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// write Атомарно записывает пакет: через временный файл, сброс на диск и переименование.
+func (o *outbox) write(name string, b *Batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(o.dir, name+outboxTmpExt)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(o.dir, name))
+}
+
+// mergeOldest Объединяет пакет older с более новым пакетом newer, сохраняя результат в файл newer.
+func (o *outbox) mergeOldest(older, newer string) error {
+	ob, err := o.read(older)
+	if err != nil {
+		log.Printf("[WARNING] Removing broken outbox file %s - %s\n", older, err)
+		return os.Remove(filepath.Join(o.dir, older))
+	}
+	nb, err := o.read(newer)
+	if err != nil {
+		log.Printf("[WARNING] Removing broken outbox file %s - %s\n", newer, err)
+		os.Remove(filepath.Join(o.dir, newer))
+		return o.write(newer, ob)
+	}
+
+	err = o.write(newer, mergeBatches(ob, nb))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[WARNING] Outbox is full, batch %s merged into the older batch %s\n", nb.ID, ob.ID)
+	return os.Remove(filepath.Join(o.dir, older))
+}
+
+// mergeBatches Объединяет два ещё не отправленных пакета метрик; идентификатор берётся у более старого пакета.
+// Значения счётчиков суммируются, гистограммы, скетчи `summary` и множества `set` объединяются,
+// для gauge-метрик сохраняется более новое значение. Если гистограммы, скетчи или множества
+// объединить нельзя, сохраняется более новое значение.
+func mergeBatches(older, newer *Batch) *Batch {
+	merged := &Batch{ID: older.ID, Metrics: make([]metrics.Metrics, 0, len(older.Metrics)+len(newer.Metrics))}
+	index := make(map[string]int, len(older.Metrics)+len(newer.Metrics))
+
+	for _, b := range []*Batch{older, newer} {
+		for _, m := range b.Metrics {
+			key := m.MType + ":" + m.Key()
+			k, ok := index[key]
+			if !ok {
+				index[key] = len(merged.Metrics)
+				merged.Metrics = append(merged.Metrics, m)
+				continue
+			}
+
+			err := mergeMetric(&merged.Metrics[k], m)
+			if err != nil {
+				log.Printf("[WARNING] Outbox merge replaced %s %s with the newer value - %s\n", m.MType, m.Key(), err)
+				merged.Metrics[k] = m
+			}
+			// хэш суммарного значения будет пересчитан перед отправкой
+			merged.Metrics[k].Hash = ""
+		}
+	}
+
+	return merged
+}
+
+// mergeMetric Добавляет к значению метрики dst значение той же метрики из более нового пакета.
+func mergeMetric(dst *metrics.Metrics, m metrics.Metrics) error {
+	switch {
+	case m.MType == metrics.TypeCounter && m.Delta != nil && dst.Delta != nil:
+		delta := *dst.Delta + *m.Delta
+		dst.Delta = &delta
+	case m.MType == metrics.TypeHistogram && m.Histogram != nil && dst.Histogram != nil:
+		h, err := dst.Histogram.Merge(*m.Histogram)
+		if err != nil {
+			return err
+		}
+		dst.Histogram = &h
+	case m.MType == metrics.TypeSummary && m.Summary != nil && dst.Summary != nil:
+		sm, err := dst.Summary.Merge(*m.Summary)
+		if err != nil {
+			return err
+		}
+		dst.Summary = &sm
+	case m.MType == metrics.TypeSet && m.Set != nil && dst.Set != nil:
+		set, err := dst.Set.Merge(*m.Set)
+		if err != nil {
+			return err
+		}
+		dst.Set = &set
+	default:
+		*dst = m
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func testBatch(id string, gauge float64, delta int64) *Batch {
+	return &Batch{
+		ID: id,
+		Metrics: []metrics.Metrics{
+			{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: &gauge},
+			{ID: metrics.PollCount, MType: metrics.TypeCounter, Delta: &delta},
+		},
+	}
+}
+
+func TestOutboxOrder(t *testing.T) {
+	dir := t.TempDir()
+	o, err := newOutbox(dir, 10)
+	require.NoError(t, err)
+
+	for _, id := range []string{"b1", "b2", "b3"} {
+		require.NoError(t, o.Push(testBatch(id, 1, 1)))
+	}
+	assert.Equal(t, 3, o.Len())
+
+	// очередь переживает перезапуск агента
+	o, err = newOutbox(dir, 10)
+	require.NoError(t, err)
+	require.NoError(t, o.Push(testBatch("b4", 1, 1)))
+
+	for _, want := range []string{"b1", "b2", "b3", "b4"} {
+		b, name, errPeek := o.Peek()
+		require.NoError(t, errPeek)
+		require.NotNil(t, b)
+		assert.Equal(t, want, b.ID)
+		require.NoError(t, o.Remove(name))
+	}
+
+	b, _, err := o.Peek()
+	require.NoError(t, err)
+	assert.Nil(t, b)
+}
+
+func TestOutboxOverflow(t *testing.T) {
+	o, err := newOutbox(t.TempDir(), 3)
+	require.NoError(t, err)
+
+	require.NoError(t, o.Push(testBatch("b1", 1, 1)))
+	require.NoError(t, o.Push(testBatch("b2", 2, 2)))
+	require.NoError(t, o.Push(testBatch("b3", 3, 4)))
+	require.NoError(t, o.Push(testBatch("b4", 4, 8)))
+	assert.Equal(t, 3, o.Len())
+
+	// первый пакет не изменяется, следующие за ним объединены:
+	// счётчики сложены, gauge взят из более нового пакета
+	b, name, err := o.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "b1", b.ID)
+	require.NoError(t, o.Remove(name))
+
+	b, _, err = o.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "b2", b.ID)
+	for _, m := range b.Metrics {
+		switch m.MType {
+		case metrics.TypeGauge:
+			assert.Equal(t, 3.0, *m.Value)
+		case metrics.TypeCounter:
+			assert.Equal(t, int64(6), *m.Delta)
+		}
+	}
+}
+
+func TestOutboxOverflowAcceptedHead(t *testing.T) {
+	o, err := newOutbox(t.TempDir(), 3)
+	require.NoError(t, err)
+
+	// сервер принял первый пакет, но ответ до агента не дошёл, и пакет остался в очереди
+	accepted := map[string]bool{"b1": true}
+	total := int64(1)
+
+	for i, id := range []string{"b1", "b2", "b3", "b4", "b5"} {
+		require.NoError(t, o.Push(testBatch(id, float64(i), 1)))
+	}
+	assert.Equal(t, 3, o.Len())
+
+	// при повторной доставке сервер отбрасывает уже принятые пакеты, но счётчики остальных доходят
+	for {
+		b, name, errPeek := o.Peek()
+		require.NoError(t, errPeek)
+		if b == nil {
+			break
+		}
+		if !accepted[b.ID] {
+			accepted[b.ID] = true
+			for _, m := range b.Metrics {
+				if m.MType == metrics.TypeCounter {
+					total += *m.Delta
+				}
+			}
+		}
+		require.NoError(t, o.Remove(name))
+	}
+	assert.Equal(t, int64(5), total)
+}
+
+func TestMergeBatchesSketches(t *testing.T) {
+	s1, s2 := metrics.NewSummary(0), metrics.NewSummary(0)
+	s1.Observe(1)
+	s2.Observe(2)
+	set1, set2 := metrics.NewSet(metrics.DefaultSetPrecision), metrics.NewSet(metrics.DefaultSetPrecision)
+	set1.Add("a")
+	set2.Add("b")
+
+	older := &Batch{ID: "b1", Metrics: []metrics.Metrics{
+		{ID: "Latency", MType: metrics.TypeSummary, Summary: &s1},
+		{ID: "Users", MType: metrics.TypeSet, Set: &set1},
+	}}
+	newer := &Batch{ID: "b2", Metrics: []metrics.Metrics{
+		{ID: "Latency", MType: metrics.TypeSummary, Summary: &s2},
+		{ID: "Users", MType: metrics.TypeSet, Set: &set2},
+	}}
+
+	// скетчи и множества объединяются, а не заменяются более новыми значениями
+	merged := mergeBatches(older, newer)
+	require.Len(t, merged.Metrics, 2)
+	assert.Equal(t, uint64(2), merged.Metrics[0].Summary.Count)
+	assert.Equal(t, uint64(2), merged.Metrics[1].Set.Estimate())
+}
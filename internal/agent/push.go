@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/agent/collector"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/sergeysynergy/metricser/pkg/statsd"
+)
+
+// maxPushBody Максимальный размер тела запроса или UDP-пакета с метриками приложений.
+const maxPushBody = 1 << 20
+
+// startPush Запускает локальные приёмники метрик от приложений, если заданы их адреса.
+// Принятые метрики сохраняются в хранилище агента и уходят на сервер с очередным отчётом.
+func (a *Agent) startPush() error {
+	if a.pushAddr != "" {
+		ln, err := net.Listen("tcp", a.pushAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start push listener: %w", err)
+		}
+		a.pushHTTPAddr = ln.Addr()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/update/", a.pushHandler)
+		mux.HandleFunc("/updates/", a.pushHandler)
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+		go func() {
+			errServe := srv.Serve(ln)
+			if errServe != nil && !errors.Is(errServe, http.ErrServerClosed) {
+				a.handleError(errServe)
+			}
+		}()
+		go func() {
+			<-a.ctx.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_ = srv.Shutdown(ctx)
+		}()
+		log.Println("[INFO] Приём метрик приложений по HTTP на", ln.Addr())
+	}
+
+	if a.statsdAddr != "" {
+		conn, err := net.ListenPacket("udp", a.statsdAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start statsd listener: %w", err)
+		}
+		a.pushStatsDAddr = conn.LocalAddr()
+
+		go a.serveStatsD(conn)
+		go func() {
+			<-a.ctx.Done()
+			conn.Close()
+		}()
+		log.Println("[INFO] Приём метрик приложений по протоколу StatsD на", conn.LocalAddr())
+	}
+
+	return nil
+}
+
+// pushHandler Принимает метрики в виде `metrics.Metrics`, JSON-массива таких метрик
+// или строк вида `имя тип значение`.
+func (a *Agent) pushHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushBody))
+	if err != nil {
+		// MaxBytesReader отдаёт тело целиком до превышения предела
+		if len(body) >= maxPushBody {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// одиночную метрику разбираем как массив из одного элемента
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '{' {
+		body = append(append([]byte{'['}, body...), ']')
+	}
+
+	prm, err := collector.ParseOutput(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = a.storage.PutMetrics(prm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveStatsD Принимает UDP-пакеты StatsD до закрытия соединения.
+func (a *Agent) serveStatsD(conn net.PacketConn) {
+	buf := make([]byte, maxPushBody)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if a.ctx.Err() == nil {
+				a.handleError(fmt.Errorf("ошибка приёма метрик StatsD - %w", err))
+			}
+			return
+		}
+
+		ms, errs := statsd.Parse(buf[:n])
+		for _, errParse := range errs {
+			log.Println("[WARNING] Некорректная метрика StatsD -", errParse)
+		}
+		a.putStatsD(ms)
+	}
+}
+
+// putStatsD Сохраняет метрики StatsD в хранилище агента: счётчики суммируются с учётом доли отправленных
+// значений, gauge получает последнее значение, значения таймеров и гистограмм добавляются
+// в скетч `summary` с тем же учётом доли отправленных значений, а значения множеств — в метрику `set`.
+func (a *Agent) putStatsD(ms []statsd.Metric) {
+	a.pushMu.Lock()
+	defer a.pushMu.Unlock()
+
+	prm := metrics.NewProxyMetrics()
+	for _, m := range ms {
+		key := m.Key()
+
+		switch m.Type {
+		case statsd.TypeCounter:
+			prm.Counters[key] += metrics.Counter(math.Round(m.Value / m.SampleRate))
+		case statsd.TypeGauge:
+			if !m.Relative {
+				prm.Gauges[key] = metrics.Gauge(m.Value)
+				continue
+			}
+			// относительное изменение применяется к значению из этого же пакета или из хранилища
+			current, ok := prm.Gauges[key]
+			if !ok {
+				if v, err := a.storage.Get(key); err == nil {
+					current, _ = v.(metrics.Gauge)
+				}
+			}
+			prm.Gauges[key] = current + metrics.Gauge(m.Value)
+		case statsd.TypeTimer, statsd.TypeHistogram:
+			s, ok := prm.Summaries[key]
+			if !ok {
+				s = metrics.NewSummary(metrics.DefaultSummaryAlpha)
+			}
+			s.ObserveN(m.Value, uint64(math.Max(1, math.Round(1/m.SampleRate))))
+			prm.Summaries[key] = s
+		case statsd.TypeSet:
+			s, ok := prm.Sets[key]
+			if !ok {
+				s = metrics.NewSet(metrics.DefaultSetPrecision)
+			}
+			s.Add(m.Raw)
+			prm.Sets[key] = s
+		default:
+			log.Printf("[WARNING] Метрики StatsD типа `%s` не поддерживаются: %s\n", m.Type, m.Name)
+		}
+	}
+
+	if prm.Empty() {
+		return
+	}
+
+	err := a.storage.PutMetrics(prm)
+	if err != nil {
+		a.handleError(err)
+	}
+}
@@ -2,10 +2,13 @@ package agent
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/sergeysynergy/metricser/pkg/retry"
 )
 
 // Выполняем регулярную отправку метрик на сервер пока не пришёл сигнал отмены.
@@ -24,63 +27,285 @@ func (a *Agent) reportTicker() {
 }
 
 // Выполняем отправку запросов метрик на сервер.
+// Пакет метрик сначала сохраняется в очередь на диске, затем очередь отправляется по порядку;
+// при недоступности сервера пакеты остаются в очереди до следующей попытки.
 func (a *Agent) report(ctx context.Context) {
-	hm := make([]metrics.Metrics, 0, metrics.TypeGaugeLen+metrics.TypeCounterLen)
-
-	prm, err := a.storage.GetMetrics()
+	hm, err := a.takeMetrics()
 	if err != nil {
 		a.handleError(err)
 		return
 	}
 
-	var hash string
+	if len(hm) == 0 {
+		log.Println("[WARNING] Пустой массив метрик, отправлять нечего")
+	} else {
+		b := &Batch{ID: a.nextBatchID(), Metrics: hm}
+
+		if a.outbox == nil {
+			err = a.sendBatch(ctx, b)
+			if err != nil {
+				a.handleError(err)
+				// вернём приращения счётчиков и скетчи в хранилище, чтобы отправить их со следующим отчётом
+				a.restoreCounters(hm)
+				return
+			}
+			log.Println("[INFO] Выполнена отправка отчёта")
+			return
+		}
+
+		err = a.outbox.Push(b)
+		if err != nil {
+			a.handleError(fmt.Errorf("не удалось сохранить отчёт в очередь - %w", err))
+			a.restoreCounters(hm)
+			return
+		}
+	}
+
+	if a.outbox != nil {
+		a.flushOutbox(ctx)
+	}
+}
+
+// flushOutbox Отправляет пакеты из очереди в порядке их создания до первой ошибки.
+func (a *Agent) flushOutbox(ctx context.Context) {
+	sent := 0
+	for {
+		b, name, err := a.outbox.Peek()
+		if err != nil {
+			a.handleError(err)
+			return
+		}
+		if b == nil {
+			break
+		}
+
+		err = a.sendBatch(ctx, b)
+		if err != nil {
+			a.handleError(fmt.Errorf("отправка отчёта отложена, в очереди %d - %w", a.outbox.Len(), err))
+			return
+		}
+
+		err = a.outbox.Remove(name)
+		if err != nil {
+			a.handleError(err)
+			return
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		log.Printf("[INFO] Выполнена отправка отчётов: %d\n", sent)
+	}
+}
+
+// sendBatch Отправляет пакет метрик на сервер выбранным способом.
+// При временной недоступности сервера отправка повторяется согласно правилам повтора агента.
+func (a *Agent) sendBatch(ctx context.Context, b *Batch) error {
+	a.sign(b.Metrics)
+
+	return retry.Do(ctx, a.retry, func(ctx context.Context) error {
+		var err error
+		if a.grpc {
+			err = a.sendGRPCReport(ctx, b)
+		} else {
+			_, err = a.sendHTTPReport(ctx, b)
+		}
+
+		if retry.IsRetryable(err) {
+			log.Println("[WARNING] Временная ошибка отправки отчёта -", err)
+		}
+		return err
+	})
+}
+
+// takeMetrics Извлекает метрики из хранилища агента для отправки.
+// Отправленные приращения счётчиков вычитаются из хранилища, а скетчи и множества удаляются, так что
+// каждый отчёт содержит только приращения с момента предыдущего отчёта.
+func (a *Agent) takeMetrics() ([]metrics.Metrics, error) {
+	// скетчи извлекаются вместе с удалением, поэтому наблюдения не должны добавляться между ними
+	a.pushMu.Lock()
+	defer a.pushMu.Unlock()
+
+	prm, err := a.storage.GetMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	hm := make([]metrics.Metrics, 0, len(prm.Gauges)+len(prm.Counters)+len(prm.Summaries)+len(prm.Sets))
+	taken := metrics.NewProxyMetrics()
 
 	for k, v := range prm.Gauges {
 		value := float64(v)
-
-		// добавляем хэш, если задан ключ key
-		if a.key != "" {
-			hash = metrics.GaugeHash(a.key, k, value)
+		id, labels, errKey := a.seriesLabels(k)
+		if errKey != nil {
+			a.handleError(errKey)
+			continue
 		}
 
 		hm = append(hm, metrics.Metrics{
-			ID:    k,
-			MType: metrics.TypeGauge,
-			Value: &value,
-			Hash:  hash,
+			ID:     id,
+			MType:  metrics.TypeGauge,
+			Value:  &value,
+			Labels: labels,
 		})
 	}
 
 	for k, v := range prm.Counters {
 		delta := int64(v)
+		id, labels, errKey := a.seriesLabels(k)
+		if errKey != nil {
+			a.handleError(errKey)
+			continue
+		}
 
-		// добавляем хэш, если задан ключ key
-		if a.key != "" {
-			hash = metrics.CounterHash(a.key, k, delta)
+		hm = append(hm, metrics.Metrics{
+			ID:     id,
+			MType:  metrics.TypeCounter,
+			Delta:  &delta,
+			Labels: labels,
+		})
+		taken.Counters[k] = -v
+	}
+
+	for k, v := range prm.Summaries {
+		s := v
+		id, labels, errKey := a.seriesLabels(k)
+		if errKey != nil {
+			a.handleError(errKey)
+			continue
 		}
 
 		hm = append(hm, metrics.Metrics{
-			ID:    k,
-			MType: metrics.TypeCounter,
-			Delta: &delta,
-			Hash:  hash,
+			ID:      id,
+			MType:   metrics.TypeSummary,
+			Summary: &s,
+			Labels:  labels,
 		})
 	}
 
-	if len(hm) == 0 {
-		log.Println("[WARNING] Пустой массив метрик, отправлять нечего")
-		return
+	for k, v := range prm.Sets {
+		set := v
+		id, labels, errKey := a.seriesLabels(k)
+		if errKey != nil {
+			a.handleError(errKey)
+			continue
+		}
+
+		hm = append(hm, metrics.Metrics{
+			ID:     id,
+			MType:  metrics.TypeSet,
+			Set:    &set,
+			Labels: labels,
+		})
 	}
 
-	if a.grpc {
-		a.sendGRPCReport(hm)
-	} else {
-		_, err = a.sendHTTPReport(ctx, hm)
+	if len(taken.Counters) > 0 {
+		err = a.storage.PutMetrics(taken)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(prm.Summaries) > 0 || len(prm.Sets) > 0 {
+		err = a.storage.Restore(&metrics.ProxyMetrics{
+			Summaries: make(map[string]metrics.Summary),
+			Sets:      make(map[string]metrics.Set),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hm, nil
+}
+
+// restoreCounters Возвращает в хранилище приращения счётчиков, скетчи и множества неотправленного отчёта.
+func (a *Agent) restoreCounters(hm []metrics.Metrics) {
+	prm := metrics.NewProxyMetrics()
+	for _, m := range hm {
+		// метки агента будут снова добавлены при следующем отчёте; удаляются только метки с именами
+		// меток агента, значения которых не переопределены метриками приложения
+		labels := make(metrics.Labels, len(m.Labels))
+		for k, v := range m.Labels {
+			if own, ok := a.labels[k]; ok && own == v {
+				continue
+			}
+			labels[k] = v
+		}
+		key := metrics.SeriesKey(m.ID, labels)
+
+		switch {
+		case m.MType == metrics.TypeCounter && m.Delta != nil:
+			prm.Counters[key] += metrics.Counter(*m.Delta)
+		case m.MType == metrics.TypeSummary && m.Summary != nil:
+			prm.Summaries[key] = *m.Summary
+		case m.MType == metrics.TypeSet && m.Set != nil:
+			prm.Sets[key] = *m.Set
+		}
+	}
+
+	if prm.Empty() {
+		return
 	}
+
+	err := a.storage.PutMetrics(prm)
 	if err != nil {
 		a.handleError(err)
+	}
+}
+
+// sign Добавляет хэш к каждой метрике, если задан ключ key.
+func (a *Agent) sign(hm []metrics.Metrics) {
+	if a.key == "" {
 		return
 	}
 
-	log.Println("[INFO] Выполнена отправка отчёта")
+	for k, m := range hm {
+		switch m.MType {
+		case metrics.TypeGauge:
+			if m.Value != nil {
+				hm[k].Hash = metrics.GaugeHash(a.key, m.Key(), *m.Value)
+			}
+		case metrics.TypeCounter:
+			if m.Delta != nil {
+				hm[k].Hash = metrics.CounterHash(a.key, m.Key(), *m.Delta)
+			}
+		case metrics.TypeSummary:
+			if m.Summary != nil {
+				hm[k].Hash = metrics.SummaryHash(a.key, m.Key(), *m.Summary)
+			}
+		case metrics.TypeSet:
+			if m.Set != nil {
+				hm[k].Hash = metrics.SetHash(a.key, m.Key(), *m.Set)
+			}
+		}
+	}
+}
+
+// nextBatchID Возвращает уникальный идентификатор очередного пакета метрик.
+func (a *Agent) nextBatchID() string {
+	seq := atomic.AddUint64(&a.batchSeq, 1)
+	return fmt.Sprintf("%s-%d-%d", a.id, a.startedAt, seq)
+}
+
+// seriesLabels Разбирает ключ серии и дополняет метки серии метками агента.
+// Метки, заданные для самой метрики, имеют приоритет.
+func (a *Agent) seriesLabels(key string) (string, metrics.Labels, error) {
+	id, labels, err := metrics.ParseSeriesKey(key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(a.labels) == 0 {
+		return id, labels, nil
+	}
+
+	merged := make(metrics.Labels, len(a.labels)+len(labels))
+	for k, v := range a.labels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	return id, merged, nil
 }
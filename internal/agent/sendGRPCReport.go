@@ -3,20 +3,30 @@ package agent
 import (
 	"context"
 	"crypto/rsa"
+	"fmt"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"log"
 
 	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/sergeysynergy/metricser/pkg/retry"
 	pb "github.com/sergeysynergy/metricser/proto"
 )
 
-func (a *Agent) sendGRPCReport(hm []metrics.Metrics) {
+func (a *Agent) sendGRPCReport(ctx context.Context, b *Batch) error {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
 	// устанавливаем соединение с сервером
 	conn, err := grpc.Dial(a.gRPCaddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to dial gRPC server: %w", err)
 	}
 	defer conn.Close()
 	// получаем переменную интерфейсного типа UsersClient,
@@ -24,26 +34,52 @@ func (a *Agent) sendGRPCReport(hm []metrics.Metrics) {
 	c := pb.NewMetricsClient(conn)
 
 	// функция, в которой будем отправлять сообщения
-	send(a.ctx, a.publicKey, c, hm)
+	return send(ctx, a.publicKey, a.info, b.ID, c, b.Metrics)
 }
 
 // sendReport Отправляет значения всех метрик на сервер.
-func send(ctx context.Context, publicKey *rsa.PublicKey, c pb.MetricsClient, hm []metrics.Metrics) {
+func send(ctx context.Context, publicKey *rsa.PublicKey, info metrics.AgentInfo, batchID string, c pb.MetricsClient, hm []metrics.Metrics) error {
 	// Преобразуем метрики для отправки на сервер.
-	prm := metrics.NewProxyMetrics()
-	gauges := make([]*pb.Gauge, 0, len(prm.Gauges))
-	counters := make([]*pb.Counter, 0, len(prm.Counters))
+	gauges := make([]*pb.Gauge, 0, len(hm))
+	counters := make([]*pb.Counter, 0, len(hm))
+	summaries := make([]*pb.Summary, 0)
+	sets := make([]*pb.Set, 0)
 	for _, v := range hm {
 		switch v.MType {
 		case "gauge":
 			gauges = append(gauges, &pb.Gauge{
-				Id:    v.ID,
-				Value: *v.Value,
+				Id:     v.ID,
+				Value:  *v.Value,
+				Labels: v.Labels,
 			})
 		case "counter":
 			counters = append(counters, &pb.Counter{
-				Id:    v.ID,
-				Delta: *v.Delta,
+				Id:     v.ID,
+				Delta:  *v.Delta,
+				Labels: v.Labels,
+			})
+		case metrics.TypeSummary:
+			summaries = append(summaries, &pb.Summary{
+				Id:       v.ID,
+				Alpha:    v.Summary.Alpha,
+				Positive: v.Summary.Positive,
+				Negative: v.Summary.Negative,
+				Zero:     v.Summary.Zero,
+				Count:    v.Summary.Count,
+				Sum:      v.Summary.Sum,
+				Min:      v.Summary.Min,
+				Max:      v.Summary.Max,
+				Labels:   v.Labels,
+			})
+		case metrics.TypeSet:
+			data, err := v.Set.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			sets = append(sets, &pb.Set{
+				Id:     v.ID,
+				Data:   data,
+				Labels: v.Labels,
 			})
 		}
 	}
@@ -61,16 +97,32 @@ func send(ctx context.Context, publicKey *rsa.PublicKey, c pb.MetricsClient, hm
 		//	log.Println("[INFO] Тело запроса было зашифровано")
 		//}
 	}
+	// Передадим сведения об агенте и идентификатор пакета.
+	md = metadata.Join(md, metadata.Pairs(
+		metrics.HeaderAgentID, info.ID,
+		metrics.HeaderAgentHostname, info.Hostname,
+		metrics.HeaderAgentOS, info.OS,
+		metrics.HeaderAgentVersion, info.Version,
+		metrics.HeaderBatchID, batchID,
+	))
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	// отправим метрики на сервер
 	_, err := c.AddMetrics(ctx, &pb.AddMetricsRequest{
-		Gauges:   gauges,
-		Counters: counters,
+		Gauges:    gauges,
+		Counters:  counters,
+		Summaries: summaries,
+		Sets:      sets,
 	})
 	if err != nil {
-		log.Println("[ERROR] Неудача отправки метрик -", err)
+		code := status.Code(err)
+		err = fmt.Errorf("failed to send metrics using gRPC: %w", err)
+		if code == codes.Unavailable || code == codes.ResourceExhausted {
+			return retry.Retryable(err, 0)
+		}
+		return err
 	}
 
 	log.Println("[DEBUG] Метрики успешно отправлены на сервер по gRPC")
+	return nil
 }
@@ -7,14 +7,19 @@ import (
 	"github.com/go-resty/resty/v2"
 	"github.com/sergeysynergy/metricser/pkg/crypter"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/sergeysynergy/metricser/pkg/retry"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // sendReport Отправляет значения всех метрик на сервер.
-func (a *Agent) sendHTTPReport(ctx context.Context, hm []metrics.Metrics) (*resty.Response, error) {
+func (a *Agent) sendHTTPReport(ctx context.Context, b *Batch) (*resty.Response, error) {
+	hm := b.Metrics
+
 	endpoint := a.protocol + a.addr + "/updates/" // адрес по которому отправляются метрики на сервер
-	localIP := "127.0.0.1"                        // IP адрес клиента
+	localIP := outboundIP(a.addr)                 // IP адрес клиента
 	encoding := ""                                // значение указывает зашифровано ли сообщение
 
 	body, err := json.Marshal(hm)
@@ -44,17 +49,52 @@ func (a *Agent) sendHTTPReport(ctx context.Context, hm []metrics.Metrics) (*rest
 		SetHeader("Content-Type", "application/json").
 		SetHeader("Content-Encoding", encoding).
 		SetHeader("X-Real-IP", localIP).
+		SetHeader(metrics.HeaderAgentID, a.info.ID).
+		SetHeader(metrics.HeaderAgentHostname, a.info.Hostname).
+		SetHeader(metrics.HeaderAgentOS, a.info.OS).
+		SetHeader(metrics.HeaderAgentVersion, a.info.Version).
+		SetHeader(metrics.HeaderBatchID, b.ID).
 		SetContext(ctx).
 		SetBody(body).
 		Post(endpoint)
 
 	if err != nil {
-		return nil, err
+		// сетевые ошибки допускают повтор, если отправка не была отменена
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, retry.Retryable(err, 0)
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return resp, fmt.Errorf("invalid status code %v", resp.StatusCode())
+		err = fmt.Errorf("invalid status code %v", resp.StatusCode())
+		if resp.StatusCode() >= http.StatusInternalServerError || resp.StatusCode() == http.StatusTooManyRequests {
+			return resp, retry.Retryable(err, retryAfter(resp.Header().Get("Retry-After")))
+		}
+		return resp, err
 	}
 
 	return resp, nil
 }
+
+// retryAfter Разбирает значение заголовка Retry-After: количество секунд или дату в формате HTTP.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// SeriesColumns Разбирает ключ серии на имя метрики и набор меток в формате JSON для записи в БД.
+func SeriesColumns(key string) (string, sql.NullString) {
+	id, labels, err := metrics.ParseSeriesKey(key)
+	if err != nil || len(labels) == 0 {
+		return key, sql.NullString{}
+	}
+
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return id, sql.NullString{}
+	}
+
+	return id, sql.NullString{String: string(b), Valid: true}
+}
+
+// JSONColumn Представляет гистограмму или скетч в формате JSON для записи в БД.
+func JSONColumn(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// ScanHistogram Разбирает гистограмму, прочитанную из БД в формате JSON.
+func ScanHistogram(col sql.NullString) (metrics.Histogram, error) {
+	h := metrics.Histogram{}
+	if !col.Valid {
+		return h, fmt.Errorf("NULL histogram value")
+	}
+
+	err := json.Unmarshal([]byte(col.String), &h)
+	return h, err
+}
+
+// ScanSummary Разбирает скетч `summary`, прочитанный из БД в формате JSON.
+func ScanSummary(col sql.NullString) (metrics.Summary, error) {
+	sm := metrics.Summary{}
+	if !col.Valid {
+		return sm, fmt.Errorf("NULL summary value")
+	}
+
+	err := json.Unmarshal([]byte(col.String), &sm)
+	return sm, err
+}
+
+// ScanSet Разбирает множество `set`, прочитанное из БД в двоичном виде.
+func ScanSet(col []byte) (metrics.Set, error) {
+	set := metrics.Set{}
+	if col == nil {
+		return set, fmt.Errorf("NULL set value")
+	}
+
+	err := set.UnmarshalBinary(col)
+	return set, err
+}
@@ -2,12 +2,30 @@
 // на основании которых производится запись/чтение в БД.
 package model
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
 // Metrics хранит информацию о метрике в формате БД.
 type Metrics struct {
-	ID    string
-	MType string
-	Value sql.NullFloat64
-	Delta sql.NullInt64
+	ID        string
+	MType     string
+	Value     sql.NullFloat64
+	Delta     sql.NullInt64
+	Histogram sql.NullString
+	Summary   sql.NullString
+	Set       []byte
+}
+
+// Sample хранит информацию об отсчёте метрики в формате БД.
+type Sample struct {
+	ID        string
+	MType     string
+	Value     sql.NullFloat64
+	Delta     sql.NullInt64
+	Histogram sql.NullString
+	Summary   sql.NullString
+	Set       []byte
+	CreatedAt time.Time
 }
@@ -0,0 +1,46 @@
+package filestore
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"log"
+	"os"
+)
+
+// JustReadHistory Извлекает из файла истории все отсчёты метрик; отсутствие файла не считается ошибкой.
+func (fs *FileStore) JustReadHistory() ([]metrics.Sample, error) {
+	fs.historyMu.Lock()
+	defer fs.historyMu.Unlock()
+
+	samples := make([]metrics.Sample, 0)
+
+	f, err := os.Open(fs.historyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return samples, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxHistoryLine)
+	for scanner.Scan() {
+		sample := metrics.Sample{}
+		err = json.Unmarshal(scanner.Bytes(), &sample)
+		if err != nil {
+			log.Println("[WARNING] Skip broken history line -", err)
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
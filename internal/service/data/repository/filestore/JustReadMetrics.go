@@ -20,7 +20,7 @@ func (fs *FileStore) JustReadMetrics() (*metrics.ProxyMetrics, error) {
 		return nil, fs.removeBrokenFile(err)
 	}
 
-	if len(prm.Gauges) == 0 && len(prm.Counters) == 0 {
+	if prm.Empty() {
 		err = fmt.Errorf("metrics not found in file '%s'", fs.storeFile)
 		return nil, fs.removeBrokenFile(err)
 	}
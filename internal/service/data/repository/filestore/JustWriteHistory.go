@@ -0,0 +1,38 @@
+package filestore
+
+import (
+	"encoding/json"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"os"
+)
+
+// JustWriteHistory Перезаписывает файл истории переданными отсчётами метрик: по одному JSON-объекту на строку.
+// Данные пишутся во временный файл, который затем заменяет прежний, чтобы сбой при записи не испортил историю.
+func (fs *FileStore) JustWriteHistory(samples []metrics.Sample) error {
+	data := make([]byte, 0, len(samples)*64)
+	for _, sample := range samples {
+		line, err := json.Marshal(sample)
+		if err != nil {
+			return err
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	fs.historyMu.Lock()
+	defer fs.historyMu.Unlock()
+
+	tmp := fs.historyFile + ".tmp"
+	err := os.WriteFile(tmp, data, 0777)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, fs.historyFile)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
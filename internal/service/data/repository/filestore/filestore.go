@@ -1,10 +1,17 @@
 // Package filestore Пакет предназначен для записи/извлечения значений метрик в файл.
 package filestore
 
+import "sync"
+
+// maxHistoryLine Наибольшая длина строки файла истории: отсчёты сводок и множеств могут быть большими.
+const maxHistoryLine = 16 << 20
+
 // FileStore содержит реализацию репозитория работы с БД, контекст выполнения;
 // реализует возможность записи и извлечения значений всех метрик из файла.
 type FileStore struct {
 	storeFile    string // Имя файла, где хранятся значения метрик (пустое значение — отключает функцию записи на диск).
+	historyFile  string // Имя файла, где хранится история отсчётов метрик.
+	historyMu    sync.Mutex
 	removeBroken bool
 }
 
@@ -13,8 +20,9 @@ type Options func(fs *FileStore)
 // New Создаёт новый объект файлового хранилища FileStorer.
 func New(opts ...Options) *FileStore {
 	const (
-		defaultStoreFile    = "/tmp/devops-metrics-pgsql.json"
-		defaultRemoveBroken = false
+		defaultStoreFile     = "/tmp/devops-metrics-pgsql.json"
+		defaultHistorySuffix = ".history"
+		defaultRemoveBroken  = false
 	)
 
 	fs := &FileStore{
@@ -30,6 +38,11 @@ func New(opts ...Options) *FileStore {
 		return nil
 	}
 
+	// по умолчанию история хранится рядом с файлом значений метрик
+	if fs.historyFile == "" {
+		fs.historyFile = fs.storeFile + defaultHistorySuffix
+	}
+
 	return fs
 }
 
@@ -39,3 +52,10 @@ func WithStoreFile(filename string) Options {
 		fs.storeFile = filename
 	}
 }
+
+// WithHistoryFile Использует переданный путь к файлу истории отсчётов метрик.
+func WithHistoryFile(filename string) Options {
+	return func(fs *FileStore) {
+		fs.historyFile = filename
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 /*
@@ -146,3 +148,57 @@ func TestJustWriteMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestJustWriteReadSketches(t *testing.T) {
+	f, err := os.CreateTemp("/tmp", "restore-metrics-test*.json")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	h := metrics.NewHistogram([]float64{1, 10})
+	s := metrics.NewSummary(0)
+	for _, v := range []float64{0.5, 3, 30} {
+		h.Observe(v)
+		s.Observe(v)
+	}
+	prm := metrics.NewProxyMetrics()
+	prm.Histograms["latency"] = h
+	prm.Summaries["duration"] = s
+
+	fs := New(WithStoreFile(f.Name()))
+	err = fs.JustWriteMetrics(prm)
+	assert.NoError(t, err)
+
+	result, err := fs.JustReadMetrics()
+	assert.NoError(t, err)
+	assert.Equal(t, h, result.Histograms["latency"])
+	assert.Equal(t, s.Quantiles(metrics.DefaultQuantiles), result.Summaries["duration"].Quantiles(metrics.DefaultQuantiles))
+	assert.Equal(t, s.Count, result.Summaries["duration"].Count)
+}
+
+func TestJustWriteReadHistory(t *testing.T) {
+	fs := New(WithStoreFile(filepath.Join(t.TempDir(), "metrics.json")))
+
+	// отсутствующий файл истории — пустая история
+	samples, err := fs.JustReadHistory()
+	assert.NoError(t, err)
+	assert.Empty(t, samples)
+
+	ts := time.Unix(1650034139, 0)
+	written := []metrics.Sample{
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: 1, Timestamp: ts},
+		{ID: "PollCount", MType: metrics.TypeCounter, Delta: 2, Timestamp: ts},
+	}
+	err = fs.JustWriteHistory(written)
+	assert.NoError(t, err)
+	err = fs.JustWriteHistory(written[1:])
+	assert.NoError(t, err)
+
+	// запись заменяет прежнюю историю
+	samples, err = fs.JustReadHistory()
+	assert.NoError(t, err)
+	if assert.Len(t, samples, 1) {
+		assert.Equal(t, "PollCount", samples[0].ID)
+		assert.Equal(t, int64(2), samples[0].Delta)
+		assert.True(t, ts.Equal(samples[0].Timestamp))
+	}
+}
@@ -0,0 +1,78 @@
+package logstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Compact Заменяет журнал одной записью со снимком текущих значений метрик и истории.
+func (r *Repo) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return ErrClosed
+	}
+
+	return r.compact()
+}
+
+// compact Записывает снимок во временный файл и атомарно подменяет им журнал.
+// При сбое до переименования остаётся прежний журнал, после — новый. Если новый журнал не удалось
+// открыть, журнал закрывается: записи в прежний дескриптор попали бы в удалённый файл.
+func (r *Repo) compact() error {
+	prm, err := r.index.GetMetrics()
+	if err != nil {
+		return err
+	}
+	rec := record{Restore: prm, History: r.index.History()}
+
+	tmp := r.path + ".compact"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	n, err := writeRecord(f, rec)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	err = os.Rename(tmp, r.path)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// прежний дескриптор указывает на удалённый файл
+	file, err := os.OpenFile(r.path, os.O_RDWR|os.O_APPEND, 0o644)
+	r.file.Close()
+	if err != nil {
+		r.file = nil
+		return fmt.Errorf("%w: failed to reopen compacted log - %s", ErrClosed, err)
+	}
+	r.file = file
+	r.size = int64(n)
+	r.baseSize = int64(n)
+	r.dirty = false
+
+	return syncDir(filepath.Dir(r.path))
+}
+
+// syncDir Сбрасывает на диск запись каталога, чтобы переименование файла пережило сбой.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,22 @@
+package logstore
+
+import (
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Get Возвращает значение метрики для заданного ID.
+func (r *Repo) Get(id string) (interface{}, error) {
+	return r.index.Get(id)
+}
+
+// GetMetrics Возвращает значения всех метрик.
+func (r *Repo) GetMetrics() (*metrics.ProxyMetrics, error) {
+	return r.index.GetMetrics()
+}
+
+// GetHistory Возвращает отсчёты метрики с заданным ID за интервал [from, to].
+func (r *Repo) GetHistory(id string, from, to time.Time) ([]metrics.Sample, error) {
+	return r.index.GetHistory(id, from, to)
+}
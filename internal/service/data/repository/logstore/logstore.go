@@ -0,0 +1,298 @@
+// Package logstore Пакет реализует встроенное хранилище метрик на диске: журнал изменений с дозаписью
+// и индекс текущих значений в памяти, который восстанавливается из журнала при запуске.
+//
+// Каждое изменение записывается в журнал отдельной записью с длиной и контрольной суммой,
+// поэтому запись, оборванная при аварийном завершении, отбрасывается при восстановлении.
+// Повреждённая запись в середине журнала не отбрасывается: журнал не открывается, пока его не исправят.
+// Когда журнал разрастается, он сжимается: заменяется одной записью со снимком текущего состояния.
+package logstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/service/data/repository/memory"
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+)
+
+// Проверка на выполнение контракта интерфейса.
+var _ storage.Repo = new(Repo)
+
+var (
+	// ErrClosed Журнал закрыт вызовом Shutdown или после сбоя повторного открытия при сжатии.
+	ErrClosed = errors.New("log store closed")
+	// ErrCorrupted Журнал повреждён не в конце: отбросить повреждённую запись вместе с последующими
+	// нельзя без потери данных, поэтому файл нужно восстановить или убрать вручную.
+	ErrCorrupted = errors.New("log store corrupted")
+)
+
+// SyncPolicy Определяет, когда записи журнала сбрасываются на диск вызовом fsync.
+type SyncPolicy int
+
+const (
+	SyncInterval SyncPolicy = iota // fsync с периодом syncInterval; при сбое теряются записи последнего периода
+	SyncAlways                     // fsync после каждой записи
+	SyncNone                       // сброс на диск оставлен операционной системе
+)
+
+// ParseSyncPolicy Разбирает название политики сброса на диск: `interval`, `always` или `none`;
+// пустая строка соответствует `interval`.
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "interval":
+		return SyncInterval, nil
+	case "always":
+		return SyncAlways, nil
+	case "none":
+		return SyncNone, nil
+	}
+
+	return SyncInterval, fmt.Errorf("unknown sync policy %q", s)
+}
+
+// Repo Хранилище метрик на базе журнала изменений.
+type Repo struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64 // Текущий размер журнала
+	baseSize int64 // Размер журнала после последнего сжатия
+	dirty    bool  // В журнале есть записи, не сброшенные на диск
+
+	index   *memory.Repo
+	memOpts []memory.Option
+
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration
+	compactSize  int64 // Размер журнала, при превышении которого журнал сжимается
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type Option func(r *Repo)
+
+// New Открывает журнал по заданному пути, создавая его при отсутствии, и восстанавливает из него метрики.
+func New(path string, opts ...Option) (*Repo, error) {
+	const (
+		defaultSyncInterval = time.Second
+		defaultCompactSize  = 64 << 20
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &Repo{
+		path:         path,
+		syncPolicy:   SyncInterval,
+		syncInterval: defaultSyncInterval,
+		compactSize:  defaultCompactSize,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.index = memory.New(r.memOpts...)
+
+	err := r.open()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if r.syncPolicy == SyncInterval {
+		r.wg.Add(1)
+		go r.syncTicker()
+	}
+
+	return r, nil
+}
+
+// WithSyncPolicy Определяет политику сброса записей журнала на диск.
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(r *Repo) {
+		r.syncPolicy = policy
+	}
+}
+
+// WithSyncInterval Определяет период сброса записей на диск для политики SyncInterval.
+func WithSyncInterval(interval time.Duration) Option {
+	return func(r *Repo) {
+		if interval > 0 {
+			r.syncInterval = interval
+		}
+	}
+}
+
+// WithCompactSize Определяет размер журнала в байтах, при превышении которого журнал сжимается.
+func WithCompactSize(size int64) Option {
+	return func(r *Repo) {
+		if size > 0 {
+			r.compactSize = size
+		}
+	}
+}
+
+// WithHistory Включает хранение истории принятых значений метрик с заданным сроком хранения.
+func WithHistory(retention time.Duration) Option {
+	return func(r *Repo) {
+		r.memOpts = append(r.memOpts, memory.WithHistory(retention))
+	}
+}
+
+// open Открывает журнал и восстанавливает индекс; оборванная запись в конце журнала отбрасывается,
+// а при повреждённой записи в середине журнала возвращается ErrCorrupted.
+func (r *Repo) open() error {
+	err := os.MkdirAll(filepath.Dir(r.path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	valid, err := replay(f, r.apply)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("log store %s: %w", r.path, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if info.Size() > valid {
+		log.Printf("[WARNING] Log store %s: dropping %d bytes of a torn tail\n", r.path, info.Size()-valid)
+		err = f.Truncate(valid)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		err = f.Sync()
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	r.file = f
+	r.size = valid
+	r.baseSize = valid
+
+	return nil
+}
+
+// apply Применяет запись журнала к индексу.
+func (r *Repo) apply(rec record) error {
+	if rec.Restore != nil {
+		err := r.index.Restore(rec.Restore)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rec.History) > 0 {
+		r.index.RestoreHistory(rec.History)
+	}
+	if len(rec.Samples) > 0 {
+		err := r.index.PutSamples(rec.Samples)
+		if err != nil {
+			return err
+		}
+	}
+	if rec.ResetSets {
+		return r.index.ResetSets()
+	}
+
+	return nil
+}
+
+// commit Применяет запись к индексу и дописывает её в журнал. Запись, которую не удалось применить,
+// в журнал не попадает, поэтому при восстановлении применяются те же изменения в том же порядке.
+func (r *Repo) commit(rec record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return ErrClosed
+	}
+
+	err := r.apply(rec)
+	if err != nil {
+		return err
+	}
+	if rec.Restore != nil {
+		// Restore заменяет только переданные типы метрик, поэтому в журнал пишем полный снимок значений
+		rec.Restore, err = r.index.GetMetrics()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := writeRecord(r.file, rec)
+	if err != nil {
+		// отрезаем недописанную запись, иначе при восстановлении будут отброшены и все последующие
+		if n > 0 {
+			if errTrunc := r.file.Truncate(r.size); errTrunc != nil {
+				log.Println("[ERROR] Failed to truncate log store -", errTrunc)
+			}
+		}
+		return fmt.Errorf("failed to write log record - %w", err)
+	}
+	r.size += int64(n)
+
+	switch r.syncPolicy {
+	case SyncAlways:
+		err = r.file.Sync()
+		if err != nil {
+			return err
+		}
+	case SyncInterval:
+		r.dirty = true
+	}
+
+	// сжимаем журнал, когда он вырос вдвое с прошлого сжатия и превысил порог
+	if r.size >= r.compactSize && r.size >= 2*r.baseSize {
+		err = r.compact()
+		if err != nil {
+			log.Println("[ERROR] Failed to compact log store -", err)
+		}
+	}
+
+	return nil
+}
+
+// syncTicker Периодически сбрасывает записи журнала на диск.
+func (r *Repo) syncTicker() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.Lock()
+			if r.dirty && r.file != nil {
+				if err := r.file.Sync(); err != nil {
+					log.Println("[ERROR] Failed to sync log store -", err)
+				} else {
+					r.dirty = false
+				}
+			}
+			r.mu.Unlock()
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package logstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+
+	h := metrics.NewHistogram(metrics.DefaultBuckets)
+	h.Observe(0.3)
+	s := metrics.NewSummary(metrics.DefaultSummaryAlpha)
+	s.Observe(42)
+	set := metrics.NewSet(metrics.DefaultSetPrecision)
+	set.Add("user-1")
+
+	tests := []struct {
+		name   string
+		policy SyncPolicy
+	}{
+		{name: "Sync always", policy: SyncAlways},
+		{name: "Sync interval", policy: SyncInterval},
+		{name: "Sync none", policy: SyncNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Remove(path)
+
+			r, err := New(path, WithSyncPolicy(tt.policy), WithHistory(time.Hour))
+			require.NoError(t, err)
+			require.NoError(t, r.Put("Alloc", metrics.Gauge(1.5)))
+			require.NoError(t, r.Put("PollCount", metrics.Counter(2)))
+			require.NoError(t, r.Put("PollCount", metrics.Counter(3)))
+			require.NoError(t, r.PutMetrics(&metrics.ProxyMetrics{
+				Histograms: map[string]metrics.Histogram{"latency": h},
+				Summaries:  map[string]metrics.Summary{"size": s},
+				Sets:       map[string]metrics.Set{"users": set},
+			}))
+			// отклонённое значение не попадает в журнал
+			assert.Error(t, r.Put("latency", metrics.NewHistogram([]float64{1})))
+			want, err := r.GetMetrics()
+			require.NoError(t, err)
+			require.NoError(t, r.Shutdown())
+			assert.ErrorIs(t, r.Put("Alloc", metrics.Gauge(2)), ErrClosed)
+
+			r, err = New(path, WithHistory(time.Hour))
+			require.NoError(t, err)
+			defer r.Shutdown()
+
+			got, err := r.GetMetrics()
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+
+			history, err := r.GetHistory("PollCount", time.Time{}, time.Now())
+			require.NoError(t, err)
+			assert.Len(t, history, 2)
+		})
+	}
+}
+
+func TestLogStoreTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+
+	r, err := New(path, WithSyncPolicy(SyncAlways))
+	require.NoError(t, err)
+	require.NoError(t, r.Put("Alloc", metrics.Gauge(1)))
+	require.NoError(t, r.Put("Alloc", metrics.Gauge(2)))
+	require.NoError(t, r.Shutdown())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	// обрываем последнюю запись, как при сбое во время записи
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	r, err = New(path, WithSyncPolicy(SyncAlways))
+	require.NoError(t, err)
+	value, err := r.Get("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(1), value)
+
+	// новые записи дописываются после последней целой записи
+	require.NoError(t, r.Put("Alloc", metrics.Gauge(3)))
+	require.NoError(t, r.Shutdown())
+
+	r, err = New(path)
+	require.NoError(t, err)
+	defer r.Shutdown()
+	value, err = r.Get("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(3), value)
+}
+
+func TestLogStoreCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+
+	r, err := New(path, WithSyncPolicy(SyncAlways))
+	require.NoError(t, err)
+	require.NoError(t, r.Put("Alloc", metrics.Gauge(1)))
+	first, err := os.Stat(path)
+	require.NoError(t, err)
+	// запись значения gauge занимает десятки байт
+	assert.Less(t, first.Size(), int64(64))
+	require.NoError(t, r.Put("Alloc", metrics.Gauge(2)))
+	require.NoError(t, r.Shutdown())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// повреждённая последняя запись считается оборванной и отбрасывается
+	tail := append([]byte(nil), data...)
+	tail[len(tail)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, tail, 0o644))
+	r, err = New(path)
+	require.NoError(t, err)
+	value, err := r.Get("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(1), value)
+	require.NoError(t, r.Shutdown())
+
+	// за повреждённой записью в середине журнала следуют данные: журнал не открывается и не обрезается
+	middle := append([]byte(nil), data...)
+	middle[first.Size()-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, middle, 0o644))
+	_, err = New(path)
+	assert.ErrorIs(t, err, ErrCorrupted)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, middle, after)
+}
+
+func TestLogStoreCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+
+	r, err := New(path, WithSyncPolicy(SyncNone), WithCompactSize(4096), WithHistory(time.Hour))
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, r.Put("PollCount", metrics.Counter(1)))
+	}
+	require.NoError(t, r.Restore(&metrics.ProxyMetrics{Gauges: map[string]metrics.Gauge{"Alloc": 7}}))
+	require.NoError(t, r.Put("users", metrics.NewSet(metrics.DefaultSetPrecision)))
+	require.NoError(t, r.ResetSets())
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, r.Compact())
+	want, err := r.GetMetrics()
+	require.NoError(t, err)
+	require.NoError(t, r.Shutdown())
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	r, err = New(path, WithHistory(time.Hour))
+	require.NoError(t, err)
+	defer r.Shutdown()
+
+	got, err := r.GetMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, metrics.Counter(1000), got.Counters["PollCount"])
+	assert.Empty(t, got.Sets)
+
+	history, err := r.GetHistory("PollCount", time.Time{}, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, history, 1000)
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	for s, want := range map[string]SyncPolicy{"": SyncInterval, "always": SyncAlways, "None": SyncNone} {
+		policy, err := ParseSyncPolicy(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, policy)
+	}
+
+	_, err := ParseSyncPolicy("sometimes")
+	assert.Error(t, err)
+}
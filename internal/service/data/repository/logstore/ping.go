@@ -0,0 +1,13 @@
+package logstore
+
+// Ping Проверяет, что журнал открыт.
+func (r *Repo) Ping() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return ErrClosed
+	}
+
+	return nil
+}
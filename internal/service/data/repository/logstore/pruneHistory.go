@@ -0,0 +1,9 @@
+package logstore
+
+import "time"
+
+// PruneHistory Удаляет из индекса отсчёты истории с метками времени раньше before.
+// В журнал удаление не записывается: устаревшие отсчёты отбрасываются при сжатии журнала.
+func (r *Repo) PruneHistory(before time.Time) error {
+	return r.index.PruneHistory(before)
+}
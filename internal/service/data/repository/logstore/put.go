@@ -0,0 +1,53 @@
+package logstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Put Записывает значение метрики в хранилище для заданного ID.
+func (r *Repo) Put(id string, metric interface{}) error {
+	prm := metrics.NewProxyMetrics()
+	switch m := metric.(type) {
+	case metrics.Gauge:
+		prm.Gauges[id] = m
+	case metrics.Counter:
+		prm.Counters[id] = m
+	case metrics.Histogram:
+		prm.Histograms[id] = m
+	case metrics.Summary:
+		prm.Summaries[id] = m
+	case metrics.Set:
+		prm.Sets[id] = m
+	default:
+		return fmt.Errorf("metrics not implemented")
+	}
+
+	return r.PutMetrics(prm)
+}
+
+// PutMetrics Массово записывает значения метрик в хранилище.
+func (r *Repo) PutMetrics(m *metrics.ProxyMetrics) error {
+	return r.PutSamples(metrics.NewSamples(m, time.Now()))
+}
+
+// PutSamples Записывает отсчёты в историю с их метками времени и обновляет текущие значения метрик.
+func (r *Repo) PutSamples(samples []metrics.Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	return r.commit(record{Samples: samples})
+}
+
+// Restore Массово загружает переданные значения метрик в хранилище.
+func (r *Repo) Restore(prm *metrics.ProxyMetrics) error {
+	return r.commit(record{Restore: prm})
+}
+
+// ResetSets Удаляет метрики типа `set`, начиная новое окно подсчёта уникальных значений.
+func (r *Repo) ResetSets() error {
+	return r.commit(record{ResetSets: true})
+}
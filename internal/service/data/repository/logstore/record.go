@@ -0,0 +1,598 @@
+package logstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"math"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	headerSize    = 8       // длина данных и их контрольная сумма, по 4 байта
+	maxRecordSize = 1 << 30 // защита от разбора повреждённого заголовка
+)
+
+// Флаги записи журнала.
+const (
+	flagRestore byte = 1 << iota
+	flagResetSets
+)
+
+// Коды типов метрик в записи журнала.
+const (
+	codeGauge byte = iota + 1
+	codeCounter
+	codeHistogram
+	codeSummary
+	codeSet
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errTornRecord    = errors.New("torn log record")
+	errCorruptRecord = errors.New("corrupted log record")
+)
+
+// record Запись журнала. Изменения метрик хранятся отсчётами с метками времени,
+// поэтому при восстановлении история получает исходное время приёма значений.
+type record struct {
+	Samples   []metrics.Sample      // Принятые значения метрик
+	Restore   *metrics.ProxyMetrics // Значения метрик, заменяющие текущие
+	History   []metrics.Sample      // Отсчёты истории без изменения текущих значений
+	ResetSets bool                  // Сброс метрик типа `set`
+}
+
+// writeRecord Дописывает запись в журнал: заголовок с длиной и контрольной суммой, затем данные.
+// Запись выполняется одним вызовом Write, чтобы запись не перемежалась с другими.
+func writeRecord(w io.Writer, rec record) (int, error) {
+	e := &encoder{buf: make([]byte, headerSize, 64)}
+	err := e.record(rec)
+	if err != nil {
+		return 0, err
+	}
+
+	data := e.buf
+	payload := data[headerSize:]
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(data[4:8], crc32.Checksum(payload, crcTable))
+
+	return w.Write(data)
+}
+
+// replay Последовательно читает записи журнала и передаёт их в apply. Возвращает смещение конца
+// последней целой записи: данные после него — запись, оборванная при сбое во время дозаписи.
+// Повреждённая запись в середине журнала не отбрасывается: возвращается ошибка ErrCorrupted.
+// Записи, которые не удалось применить, пропускаются.
+func replay(r io.Reader, apply func(record) error) (int64, error) {
+	br := bufio.NewReader(r)
+
+	var offset int64
+	for {
+		rec, n, err := readRecord(br)
+		if err == io.EOF || errors.Is(err, errTornRecord) {
+			return offset, nil
+		}
+		if errors.Is(err, errCorruptRecord) {
+			return offset, fmt.Errorf("%w at offset %d - %s", ErrCorrupted, offset, err)
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		if errApply := apply(rec); errApply != nil {
+			log.Printf("[WARNING] Skipping log record at offset %d - %s\n", offset, errApply)
+		}
+		offset += int64(n)
+	}
+}
+
+// readRecord Читает одну запись журнала; возвращает io.EOF в конце журнала, errTornRecord,
+// если журнал закончился посреди записи, и errCorruptRecord, если запись повреждена.
+// Последняя запись журнала с неверной контрольной суммой считается оборванной: при сбое размер файла
+// мог дойти до диска раньше данных.
+func readRecord(br *bufio.Reader) (record, int, error) {
+	rec := record{}
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(br, header)
+	if err == io.EOF {
+		return rec, 0, io.EOF
+	}
+	if err == io.ErrUnexpectedEOF {
+		return rec, n, errTornRecord
+	}
+	if err != nil {
+		return rec, n, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return rec, n, fmt.Errorf("%w: record size %d", errCorruptRecord, size)
+	}
+	payload := make([]byte, size)
+	m, err := io.ReadFull(br, payload)
+	n += m
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return rec, n, errTornRecord
+	}
+	if err != nil {
+		return rec, n, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		if _, errPeek := br.Peek(1); errPeek == io.EOF {
+			return rec, n, errTornRecord
+		}
+		return rec, n, fmt.Errorf("%w: checksum mismatch", errCorruptRecord)
+	}
+
+	d := &decoder{data: payload}
+	rec = d.record()
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.data))
+	}
+	if d.err != nil {
+		return rec, n, fmt.Errorf("%w: %s", errCorruptRecord, d.err)
+	}
+
+	return rec, n, nil
+}
+
+// encoder Кодирует запись журнала. Числа без знака и длины записываются как uvarint, целые — как varint,
+// числа с плавающей точкой — 8 байтами IEEE 754, строки и срезы байтов — длиной и данными.
+// Метка времени записывается в наносекундах Unix, нулевая метка — нулём.
+type encoder struct {
+	buf []byte
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) byte(v byte) {
+	e.buf = append(e.buf, v)
+}
+
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.tmp[:], v)
+	e.buf = append(e.buf, e.tmp[:n]...)
+}
+
+func (e *encoder) varint(v int64) {
+	n := binary.PutVarint(e.tmp[:], v)
+	e.buf = append(e.buf, e.tmp[:n]...)
+}
+
+func (e *encoder) float(v float64) {
+	binary.LittleEndian.PutUint64(e.tmp[:8], math.Float64bits(v))
+	e.buf = append(e.buf, e.tmp[:8]...)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) time(t time.Time) {
+	if t.IsZero() {
+		e.varint(0)
+		return
+	}
+	e.varint(t.UnixNano())
+}
+
+// record Кодирует флаги записи, отсчёты Samples и History и снимок Restore, если он есть.
+func (e *encoder) record(rec record) error {
+	var flags byte
+	if rec.Restore != nil {
+		flags |= flagRestore
+	}
+	if rec.ResetSets {
+		flags |= flagResetSets
+	}
+	e.byte(flags)
+
+	for _, samples := range [][]metrics.Sample{rec.Samples, rec.History} {
+		e.uvarint(uint64(len(samples)))
+		for _, s := range samples {
+			err := e.sample(s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if rec.Restore != nil {
+		return e.metrics(rec.Restore)
+	}
+
+	return nil
+}
+
+func (e *encoder) sample(s metrics.Sample) error {
+	switch s.MType {
+	case metrics.TypeGauge:
+		e.byte(codeGauge)
+	case metrics.TypeCounter:
+		e.byte(codeCounter)
+	case metrics.TypeHistogram:
+		e.byte(codeHistogram)
+	case metrics.TypeSummary:
+		e.byte(codeSummary)
+	case metrics.TypeSet:
+		e.byte(codeSet)
+	default:
+		return fmt.Errorf("unknown metric type %q", s.MType)
+	}
+	e.string(s.ID)
+	e.time(s.Timestamp)
+
+	switch s.MType {
+	case metrics.TypeGauge:
+		e.float(s.Value)
+	case metrics.TypeCounter:
+		e.varint(s.Delta)
+	case metrics.TypeHistogram:
+		e.present(s.Histogram != nil)
+		if s.Histogram != nil {
+			e.histogram(*s.Histogram)
+		}
+	case metrics.TypeSummary:
+		e.present(s.Summary != nil)
+		if s.Summary != nil {
+			e.summary(*s.Summary)
+		}
+	case metrics.TypeSet:
+		e.present(s.Set != nil)
+		if s.Set != nil {
+			return e.set(*s.Set)
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) present(ok bool) {
+	if ok {
+		e.byte(1)
+		return
+	}
+	e.byte(0)
+}
+
+func (e *encoder) histogram(h metrics.Histogram) {
+	e.uvarint(uint64(len(h.Bounds)))
+	for _, b := range h.Bounds {
+		e.float(b)
+	}
+	e.uvarint(uint64(len(h.Counts)))
+	for _, c := range h.Counts {
+		e.uvarint(c)
+	}
+	e.uvarint(h.Count)
+	e.float(h.Sum)
+}
+
+func (e *encoder) summary(s metrics.Summary) {
+	e.float(s.Alpha)
+	for _, bins := range []map[int32]uint64{s.Positive, s.Negative} {
+		e.uvarint(uint64(len(bins)))
+		for i, c := range bins {
+			e.varint(int64(i))
+			e.uvarint(c)
+		}
+	}
+	e.uvarint(s.Zero)
+	e.uvarint(s.Count)
+	e.float(s.Sum)
+	e.float(s.Min)
+	e.float(s.Max)
+}
+
+func (e *encoder) set(s metrics.Set) error {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	e.bytes(data)
+
+	return nil
+}
+
+func (e *encoder) metrics(prm *metrics.ProxyMetrics) error {
+	e.uvarint(uint64(len(prm.Gauges)))
+	for id, v := range prm.Gauges {
+		e.string(id)
+		e.float(float64(v))
+	}
+	e.uvarint(uint64(len(prm.Counters)))
+	for id, v := range prm.Counters {
+		e.string(id)
+		e.varint(int64(v))
+	}
+	e.uvarint(uint64(len(prm.Histograms)))
+	for id, h := range prm.Histograms {
+		e.string(id)
+		e.histogram(h)
+	}
+	e.uvarint(uint64(len(prm.Summaries)))
+	for id, s := range prm.Summaries {
+		e.string(id)
+		e.summary(s)
+	}
+	e.uvarint(uint64(len(prm.Sets)))
+	for id, s := range prm.Sets {
+		e.string(id)
+		err := e.set(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decoder Разбирает запись журнала, закодированную encoder. После первой ошибки разбора
+// методы возвращают нулевые значения, а ошибка сохраняется в err.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) == 0 {
+		d.fail("unexpected end of record")
+		return 0
+	}
+	v := d.data[0]
+	d.data = d.data[1:]
+
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("bad uvarint")
+		return 0
+	}
+	d.data = d.data[n:]
+
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail("bad varint")
+		return 0
+	}
+	d.data = d.data[n:]
+
+	return v
+}
+
+func (d *decoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) < 8 {
+		d.fail("unexpected end of record")
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.data))
+	d.data = d.data[8:]
+
+	return v
+}
+
+// count Читает число элементов; каждый элемент занимает хотя бы байт, поэтому число элементов
+// не превышает длину оставшихся данных.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("bad element count %d", n)
+		return 0
+	}
+
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) time() time.Time {
+	ns := d.varint()
+	if ns == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ns)
+}
+
+func (d *decoder) record() record {
+	rec := record{}
+
+	flags := d.byte()
+	rec.ResetSets = flags&flagResetSets != 0
+
+	for _, samples := range []*[]metrics.Sample{&rec.Samples, &rec.History} {
+		n := d.count()
+		for i := 0; i < n && d.err == nil; i++ {
+			*samples = append(*samples, d.sample())
+		}
+	}
+
+	if flags&flagRestore != 0 {
+		rec.Restore = d.metrics()
+	}
+
+	return rec
+}
+
+func (d *decoder) sample() metrics.Sample {
+	s := metrics.Sample{}
+
+	code := d.byte()
+	s.ID = d.string()
+	s.Timestamp = d.time()
+
+	switch code {
+	case codeGauge:
+		s.MType = metrics.TypeGauge
+		s.Value = d.float()
+	case codeCounter:
+		s.MType = metrics.TypeCounter
+		s.Delta = d.varint()
+	case codeHistogram:
+		s.MType = metrics.TypeHistogram
+		if d.byte() == 1 {
+			h := d.histogram()
+			s.Histogram = &h
+		}
+	case codeSummary:
+		s.MType = metrics.TypeSummary
+		if d.byte() == 1 {
+			sm := d.summary()
+			s.Summary = &sm
+		}
+	case codeSet:
+		s.MType = metrics.TypeSet
+		if d.byte() == 1 {
+			set := d.set()
+			s.Set = &set
+		}
+	default:
+		d.fail("unknown metric type code %d", code)
+	}
+
+	return s
+}
+
+func (d *decoder) histogram() metrics.Histogram {
+	h := metrics.Histogram{}
+
+	// пустые срезы остаются nil, как после Histogram.Clone
+	if n := d.count(); n > 0 {
+		h.Bounds = make([]float64, n)
+		for i := range h.Bounds {
+			h.Bounds[i] = d.float()
+		}
+	}
+	if n := d.count(); n > 0 {
+		h.Counts = make([]uint64, n)
+		for i := range h.Counts {
+			h.Counts[i] = d.uvarint()
+		}
+	}
+	h.Count = d.uvarint()
+	h.Sum = d.float()
+
+	return h
+}
+
+func (d *decoder) summary() metrics.Summary {
+	s := metrics.Summary{}
+
+	s.Alpha = d.float()
+	for _, bins := range []*map[int32]uint64{&s.Positive, &s.Negative} {
+		n := d.count()
+		*bins = make(map[int32]uint64, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			idx := d.varint()
+			if idx < math.MinInt32 || idx > math.MaxInt32 {
+				d.fail("bad summary bin index %d", idx)
+			}
+			(*bins)[int32(idx)] = d.uvarint()
+		}
+	}
+	s.Zero = d.uvarint()
+	s.Count = d.uvarint()
+	s.Sum = d.float()
+	s.Min = d.float()
+	s.Max = d.float()
+
+	return s
+}
+
+func (d *decoder) set() metrics.Set {
+	s := metrics.Set{}
+
+	data := d.bytes()
+	if d.err != nil {
+		return s
+	}
+	if err := s.UnmarshalBinary(data); err != nil {
+		d.fail("bad set - %s", err)
+	}
+
+	return s
+}
+
+func (d *decoder) metrics() *metrics.ProxyMetrics {
+	prm := metrics.NewProxyMetrics()
+
+	n := d.count()
+	for i := 0; i < n && d.err == nil; i++ {
+		id := d.string()
+		prm.Gauges[id] = metrics.Gauge(d.float())
+	}
+	n = d.count()
+	for i := 0; i < n && d.err == nil; i++ {
+		id := d.string()
+		prm.Counters[id] = metrics.Counter(d.varint())
+	}
+	n = d.count()
+	for i := 0; i < n && d.err == nil; i++ {
+		id := d.string()
+		prm.Histograms[id] = d.histogram()
+	}
+	n = d.count()
+	for i := 0; i < n && d.err == nil; i++ {
+		id := d.string()
+		prm.Summaries[id] = d.summary()
+	}
+	n = d.count()
+	for i := 0; i < n && d.err == nil; i++ {
+		id := d.string()
+		prm.Sets[id] = d.set()
+	}
+
+	return prm
+}
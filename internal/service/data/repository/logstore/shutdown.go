@@ -0,0 +1,22 @@
+package logstore
+
+// Shutdown Сбрасывает журнал на диск и закрывает его.
+func (r *Repo) Shutdown() error {
+	r.cancel()
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Sync()
+	if errClose := r.file.Close(); err == nil {
+		err = errClose
+	}
+	r.file = nil
+
+	return err
+}
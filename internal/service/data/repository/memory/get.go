@@ -20,5 +20,26 @@ func (r *Repo) Get(id string) (interface{}, error) {
 		return value, nil
 	}
 
+	r.histogramsMu.RLock()
+	defer r.histogramsMu.RUnlock()
+	h, ok := r.histograms[id]
+	if ok {
+		return h.Clone(), nil
+	}
+
+	r.summariesMu.RLock()
+	defer r.summariesMu.RUnlock()
+	s, ok := r.summaries[id]
+	if ok {
+		return s.Clone(), nil
+	}
+
+	r.setsMu.RLock()
+	defer r.setsMu.RUnlock()
+	set, ok := r.sets[id]
+	if ok {
+		return set.Clone(), nil
+	}
+
 	return nil, fmt.Errorf("metrics not found")
 }
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// GetHistory Извлекает отсчёты метрики с заданным ID за промежуток времени [from, to].
+func (r *Repo) GetHistory(id string, from, to time.Time) ([]metrics.Sample, error) {
+	r.historyMu.RLock()
+	defer r.historyMu.RUnlock()
+
+	samples := make([]metrics.Sample, 0)
+	for _, sample := range r.history[id] {
+		if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// History Возвращает все хранимые отсчёты истории, упорядоченные по времени внутри каждой метрики.
+func (r *Repo) History() []metrics.Sample {
+	r.historyMu.RLock()
+	defer r.historyMu.RUnlock()
+
+	samples := make([]metrics.Sample, 0)
+	for _, series := range r.history {
+		samples = append(samples, series...)
+	}
+
+	return samples
+}
+
+// RestoreHistory Загружает отсчёты в историю, не изменяя текущие значения метрик.
+func (r *Repo) RestoreHistory(samples []metrics.Sample) {
+	r.appendHistory(samples...)
+}
@@ -20,5 +20,23 @@ func (r *Repo) GetMetrics() (*metrics.ProxyMetrics, error) {
 	}
 	r.countersMu.RUnlock()
 
+	r.histogramsMu.RLock()
+	for k, v := range r.histograms {
+		prm.Histograms[k] = v.Clone()
+	}
+	r.histogramsMu.RUnlock()
+
+	r.summariesMu.RLock()
+	for k, v := range r.summaries {
+		prm.Summaries[k] = v.Clone()
+	}
+	r.summariesMu.RUnlock()
+
+	r.setsMu.RLock()
+	for k, v := range r.sets {
+		prm.Sets[k] = v.Clone()
+	}
+	r.setsMu.RUnlock()
+
 	return prm, nil
 }
@@ -1,21 +1,287 @@
 package memory
 
 import (
+	"fmt"
+	metricserErrors "github.com/sergeysynergy/metricser/internal/service/errors"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"sort"
 	"sync"
+	"time"
 )
 
 type Repo struct {
-	gaugesMu sync.RWMutex
-	gauges   map[string]metrics.Gauge
+	gaugesMu   sync.RWMutex
+	gauges     map[string]metrics.Gauge
+	gaugeTimes map[string]time.Time // Время, к которому относится значение gauge; отсутствует, если неизвестно.
 
 	countersMu sync.RWMutex
 	counters   map[string]metrics.Counter
+
+	histogramsMu sync.RWMutex
+	histograms   map[string]metrics.Histogram
+
+	summariesMu sync.RWMutex
+	summaries   map[string]metrics.Summary
+
+	setsMu sync.RWMutex
+	sets   map[string]metrics.Set
+
+	typesMu sync.Mutex
+	types   map[string]string // Типы хранимых метрик по ключам серий
+
+	historyMu        sync.RWMutex
+	history          map[string][]metrics.Sample
+	historyEnabled   bool
+	historyRetention time.Duration // Время хранения отсчётов, 0 — отсчёты хранятся бессрочно.
+}
+
+type Option func(r *Repo)
+
+func New(opts ...Option) *Repo {
+	r := &Repo{
+		gauges:     make(map[string]metrics.Gauge, metrics.TypeGaugeLen),
+		gaugeTimes: make(map[string]time.Time, metrics.TypeGaugeLen),
+		counters:   make(map[string]metrics.Counter, metrics.TypeCounterLen),
+		histograms: make(map[string]metrics.Histogram),
+		summaries:  make(map[string]metrics.Summary),
+		sets:       make(map[string]metrics.Set),
+		types:      make(map[string]string),
+		history:    make(map[string][]metrics.Sample),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithHistory Включает хранение истории принятых значений метрик с заданным сроком хранения.
+func WithHistory(retention time.Duration) Option {
+	return func(r *Repo) {
+		r.historyEnabled = true
+		if retention > 0 {
+			r.historyRetention = retention
+		}
+	}
+}
+
+// reserveTypes Проверяет, что метрики не хранятся под теми же ключами с другим типом и не повторяются
+// в пакете с разными типами, и закрепляет типы новых ключей. Возвращает закреплённые ключи,
+// чтобы снять закрепление, если запись не удалась.
+func (r *Repo) reserveTypes(prm *metrics.ProxyMetrics) ([]string, error) {
+	series := make(map[string]string)
+	add := func(key, mType string) error {
+		if stored, ok := series[key]; ok && stored != mType {
+			return typeConflict(key, mType, stored)
+		}
+		series[key] = mType
+		return nil
+	}
+
+	for key := range prm.Gauges {
+		series[key] = metrics.TypeGauge
+	}
+	for key := range prm.Counters {
+		if err := add(key, metrics.TypeCounter); err != nil {
+			return nil, err
+		}
+	}
+	for key := range prm.Histograms {
+		if err := add(key, metrics.TypeHistogram); err != nil {
+			return nil, err
+		}
+	}
+	for key := range prm.Summaries {
+		if err := add(key, metrics.TypeSummary); err != nil {
+			return nil, err
+		}
+	}
+	for key := range prm.Sets {
+		if err := add(key, metrics.TypeSet); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.reserve(series)
+}
+
+// reserveType Закрепляет тип метрики id; см. reserveTypes.
+func (r *Repo) reserveType(id, mType string) ([]string, error) {
+	return r.reserve(map[string]string{id: mType})
+}
+
+func (r *Repo) reserve(series map[string]string) ([]string, error) {
+	r.typesMu.Lock()
+	defer r.typesMu.Unlock()
+
+	for key, mType := range series {
+		if stored, ok := r.types[key]; ok && stored != mType {
+			return nil, typeConflict(key, mType, stored)
+		}
+	}
+
+	added := make([]string, 0)
+	for key, mType := range series {
+		if _, ok := r.types[key]; !ok {
+			r.types[key] = mType
+			added = append(added, key)
+		}
+	}
+
+	return added, nil
+}
+
+// releaseTypes Снимает закрепление типов ключей, значения которых не были записаны.
+func (r *Repo) releaseTypes(keys []string) {
+	r.typesMu.Lock()
+	defer r.typesMu.Unlock()
+
+	for _, key := range keys {
+		delete(r.types, key)
+	}
+}
+
+// resetTypes Заново определяет типы ключей по хранимым значениям метрик.
+func (r *Repo) resetTypes() {
+	types := make(map[string]string)
+
+	r.gaugesMu.RLock()
+	for key := range r.gauges {
+		types[key] = metrics.TypeGauge
+	}
+	r.gaugesMu.RUnlock()
+	r.countersMu.RLock()
+	for key := range r.counters {
+		types[key] = metrics.TypeCounter
+	}
+	r.countersMu.RUnlock()
+	r.histogramsMu.RLock()
+	for key := range r.histograms {
+		types[key] = metrics.TypeHistogram
+	}
+	r.histogramsMu.RUnlock()
+	r.summariesMu.RLock()
+	for key := range r.summaries {
+		types[key] = metrics.TypeSummary
+	}
+	r.summariesMu.RUnlock()
+	r.setsMu.RLock()
+	for key := range r.sets {
+		types[key] = metrics.TypeSet
+	}
+	r.setsMu.RUnlock()
+
+	r.typesMu.Lock()
+	r.types = types
+	r.typesMu.Unlock()
+}
+
+func typeConflict(id, mType, stored string) error {
+	return fmt.Errorf("%s %s stored as %s: %w", mType, id, stored, metricserErrors.ErrTypeConflict)
+}
+
+// mergeSketches Объединяет принятые приращения гистограмм, скетчей `summary` и множеств `set`
+// с хранимыми значениями. При несовпадении корзин или точности хотя бы одного значения хранилище не изменяется.
+func (r *Repo) mergeSketches(prm *metrics.ProxyMetrics) error {
+	hs, ss, sets := prm.Histograms, prm.Summaries, prm.Sets
+	if len(hs) == 0 && len(ss) == 0 && len(sets) == 0 {
+		return nil
+	}
+
+	r.histogramsMu.Lock()
+	defer r.histogramsMu.Unlock()
+	r.summariesMu.Lock()
+	defer r.summariesMu.Unlock()
+	r.setsMu.Lock()
+	defer r.setsMu.Unlock()
+
+	mergedHistograms := make(map[string]metrics.Histogram, len(hs))
+	for key, h := range hs {
+		prev, ok := r.histograms[key]
+		if !ok {
+			mergedHistograms[key] = h.Clone()
+			continue
+		}
+
+		m, err := prev.Merge(h)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, key)
+		}
+		mergedHistograms[key] = m
+	}
+
+	mergedSummaries := make(map[string]metrics.Summary, len(ss))
+	for key, s := range ss {
+		prev, ok := r.summaries[key]
+		if !ok {
+			mergedSummaries[key] = s.Clone()
+			continue
+		}
+
+		m, err := prev.Merge(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, key)
+		}
+		mergedSummaries[key] = m
+	}
+
+	mergedSets := make(map[string]metrics.Set, len(sets))
+	for key, s := range sets {
+		prev, ok := r.sets[key]
+		if !ok {
+			mergedSets[key] = s.Clone()
+			continue
+		}
+
+		m, err := prev.Merge(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, key)
+		}
+		mergedSets[key] = m
+	}
+
+	for key, h := range mergedHistograms {
+		r.histograms[key] = h
+	}
+	for key, s := range mergedSummaries {
+		r.summaries[key] = s
+	}
+	for key, s := range mergedSets {
+		r.sets[key] = s
+	}
+
+	return nil
 }
 
-func New() *Repo {
-	return &Repo{
-		gauges:   make(map[string]metrics.Gauge, metrics.TypeGaugeLen),
-		counters: make(map[string]metrics.Counter, metrics.TypeCounterLen),
+// appendHistory Добавляет отсчёты в историю с сохранением порядка по времени и удаляет устаревшие значения.
+func (r *Repo) appendHistory(samples ...metrics.Sample) {
+	if !r.historyEnabled {
+		return
+	}
+
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	for _, sample := range samples {
+		series := r.history[sample.ID]
+
+		// отсчёты с метками времени источника могут приходить не по порядку
+		i := sort.Search(len(series), func(i int) bool {
+			return series[i].Timestamp.After(sample.Timestamp)
+		})
+		series = append(series, metrics.Sample{})
+		copy(series[i+1:], series[i:])
+		series[i] = sample
+
+		if r.historyRetention > 0 {
+			border := series[len(series)-1].Timestamp.Add(-r.historyRetention)
+			i = 0
+			for i < len(series) && series[i].Timestamp.Before(border) {
+				i++
+			}
+			series = series[i:]
+		}
+
+		r.history[sample.ID] = series
 	}
 }
@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"time"
+)
+
+// PruneHistory Удаляет отсчёты истории с метками времени раньше before.
+func (r *Repo) PruneHistory(before time.Time) error {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	for id, series := range r.history {
+		i := 0
+		for i < len(series) && series[i].Timestamp.Before(before) {
+			i++
+		}
+		if i == len(series) {
+			delete(r.history, id)
+			continue
+		}
+		r.history[id] = series[i:]
+	}
+
+	return nil
+}
@@ -3,15 +3,29 @@ package memory
 import (
 	"fmt"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"time"
 )
 
 // Put Записывает значение метрики в хранилище Storage для заданного ID.
+// Метрика, уже хранимая с другим типом, не записывается.
 func (r *Repo) Put(id string, metric interface{}) error {
+	mType, ok := metricType(metric)
+	if !ok {
+		return fmt.Errorf("metrics not implemented")
+	}
+	reserved, err := r.reserveType(id, mType)
+	if err != nil {
+		return err
+	}
+
 	switch m := metric.(type) {
 	case metrics.Gauge:
+		now := time.Now()
 		r.gaugesMu.Lock()
 		r.gauges[id] = m
+		r.gaugeTimes[id] = now
 		r.gaugesMu.Unlock()
+		r.appendHistory(metrics.Sample{ID: id, MType: metrics.TypeGauge, Value: float64(m), Timestamp: now})
 	case metrics.Counter:
 		r.countersMu.Lock()
 		_, ok := r.counters[id]
@@ -21,9 +35,50 @@ func (r *Repo) Put(id string, metric interface{}) error {
 			r.counters[id] += m
 		}
 		r.countersMu.Unlock()
-	default:
-		return fmt.Errorf("metrics not implemented")
+		r.appendHistory(metrics.Sample{ID: id, MType: metrics.TypeCounter, Delta: int64(m), Timestamp: time.Now()})
+	case metrics.Histogram:
+		err = r.mergeSketches(&metrics.ProxyMetrics{Histograms: map[string]metrics.Histogram{id: m}})
+		if err != nil {
+			r.releaseTypes(reserved)
+			return err
+		}
+		h := m.Clone()
+		r.appendHistory(metrics.Sample{ID: id, MType: metrics.TypeHistogram, Histogram: &h, Timestamp: time.Now()})
+	case metrics.Summary:
+		err = r.mergeSketches(&metrics.ProxyMetrics{Summaries: map[string]metrics.Summary{id: m}})
+		if err != nil {
+			r.releaseTypes(reserved)
+			return err
+		}
+		s := m.Clone()
+		r.appendHistory(metrics.Sample{ID: id, MType: metrics.TypeSummary, Summary: &s, Timestamp: time.Now()})
+	case metrics.Set:
+		err = r.mergeSketches(&metrics.ProxyMetrics{Sets: map[string]metrics.Set{id: m}})
+		if err != nil {
+			r.releaseTypes(reserved)
+			return err
+		}
+		s := m.Clone()
+		r.appendHistory(metrics.Sample{ID: id, MType: metrics.TypeSet, Set: &s, Timestamp: time.Now()})
 	}
 
 	return nil
 }
+
+// metricType Возвращает тип значения метрики; false, если тип не поддерживается.
+func metricType(metric interface{}) (string, bool) {
+	switch metric.(type) {
+	case metrics.Gauge:
+		return metrics.TypeGauge, true
+	case metrics.Counter:
+		return metrics.TypeCounter, true
+	case metrics.Histogram:
+		return metrics.TypeHistogram, true
+	case metrics.Summary:
+		return metrics.TypeSummary, true
+	case metrics.Set:
+		return metrics.TypeSet, true
+	}
+
+	return "", false
+}
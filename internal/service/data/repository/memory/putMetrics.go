@@ -1,8 +1,12 @@
 package memory
 
-import "github.com/sergeysynergy/metricser/pkg/metrics"
+import (
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"time"
+)
 
 // PutMetrics Массово записывает значение метрик в хранилище Storage.
+// Если хотя бы одна метрика уже хранится с другим типом, пакет не записывается.
 func (r *Repo) PutMetrics(m *metrics.ProxyMetrics) error {
 	// для удобства вызова PutMetrics проинициализируем нулевой хэш Gauges
 	if m.Gauges == nil {
@@ -14,9 +18,24 @@ func (r *Repo) PutMetrics(m *metrics.ProxyMetrics) error {
 		m.Counters = make(map[string]metrics.Counter)
 	}
 
+	// метрики, уже хранимые с другим типом, не записываются: пакет отклоняется целиком
+	reserved, err := r.reserveTypes(m)
+	if err != nil {
+		return err
+	}
+
+	// гистограммы и скетчи объединяем первыми: при несовпадении корзин пакет не записывается
+	err = r.mergeSketches(m)
+	if err != nil {
+		r.releaseTypes(reserved)
+		return err
+	}
+
+	now := time.Now()
 	r.gaugesMu.Lock()
 	for key, value := range m.Gauges {
 		r.gauges[key] = value
+		r.gaugeTimes[key] = now
 	}
 	r.gaugesMu.Unlock()
 
@@ -31,5 +50,7 @@ func (r *Repo) PutMetrics(m *metrics.ProxyMetrics) error {
 	}
 	r.countersMu.Unlock()
 
+	r.appendHistory(metrics.NewSamples(m, now)...)
+
 	return nil
 }
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// PutSamples Записывает отсчёты в историю с их метками времени и обновляет текущие значения метрик.
+// Значение `gauge` заменяется, только если отсчёт не старше хранимого значения: запоздавшие
+// отсчёты попадают в историю, но не перезаписывают более новое значение.
+func (r *Repo) PutSamples(samples []metrics.Sample) error {
+	prm := metrics.NewProxyMetricsFromSamples(samples)
+
+	reserved, err := r.reserveTypes(prm)
+	if err != nil {
+		return err
+	}
+
+	err = r.mergeSketches(prm)
+	if err != nil {
+		r.releaseTypes(reserved)
+		return err
+	}
+
+	times := metrics.GaugeTimes(samples)
+	r.gaugesMu.Lock()
+	for key, value := range prm.Gauges {
+		if cur, ok := r.gaugeTimes[key]; ok && times[key].Before(cur) {
+			continue
+		}
+		r.gauges[key] = value
+		r.gaugeTimes[key] = times[key]
+	}
+	r.gaugesMu.Unlock()
+
+	r.countersMu.Lock()
+	for key, delta := range prm.Counters {
+		r.counters[key] += delta
+	}
+	r.countersMu.Unlock()
+
+	r.appendHistory(samples...)
+
+	return nil
+}
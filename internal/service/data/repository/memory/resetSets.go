@@ -0,0 +1,12 @@
+package memory
+
+import "github.com/sergeysynergy/metricser/pkg/metrics"
+
+// ResetSets Удаляет значения всех метрик типа `set`.
+func (r *Repo) ResetSets() error {
+	r.setsMu.Lock()
+	r.sets = make(map[string]metrics.Set)
+	r.setsMu.Unlock()
+
+	return nil
+}
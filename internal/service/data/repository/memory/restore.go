@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"time"
+
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 )
 
@@ -9,6 +11,8 @@ func (r *Repo) Restore(prm *metrics.ProxyMetrics) error {
 	if prm.Gauges != nil {
 		r.gaugesMu.Lock()
 		r.gauges = prm.Gauges
+		// время восстановленных значений неизвестно
+		r.gaugeTimes = make(map[string]time.Time, len(prm.Gauges))
 		r.gaugesMu.Unlock()
 	}
 
@@ -18,5 +22,25 @@ func (r *Repo) Restore(prm *metrics.ProxyMetrics) error {
 		r.countersMu.Unlock()
 	}
 
+	if prm.Histograms != nil {
+		r.histogramsMu.Lock()
+		r.histograms = prm.Histograms
+		r.histogramsMu.Unlock()
+	}
+
+	if prm.Summaries != nil {
+		r.summariesMu.Lock()
+		r.summaries = prm.Summaries
+		r.summariesMu.Unlock()
+	}
+
+	if prm.Sets != nil {
+		r.setsMu.Lock()
+		r.sets = prm.Sets
+		r.setsMu.Unlock()
+	}
+
+	r.resetTypes()
+
 	return nil
 }
@@ -12,11 +12,11 @@ func (s *Storage) Get(id string) (interface{}, error) {
 	m := model.Metrics{}
 	row := s.db.QueryRowContext(
 		s.ctx,
-		`SELECT id, type, value, delta FROM metrics WHERE id=$1`,
+		`SELECT id, type, value, delta, histogram, summary, hll FROM metrics WHERE id=$1`,
 		id,
 	)
 	// разбираем результат
-	err := row.Scan(&m.ID, &m.MType, &m.Value, &m.Delta)
+	err := row.Scan(&m.ID, &m.MType, &m.Value, &m.Delta, &m.Histogram, &m.Summary, &m.Set)
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +32,12 @@ func (s *Storage) Get(id string) (interface{}, error) {
 			return nil, fmt.Errorf("NULL counter value")
 		}
 		return metrics.Counter(m.Delta.Int64), nil
+	case metrics.TypeHistogram:
+		return model.ScanHistogram(m.Histogram)
+	case metrics.TypeSummary:
+		return model.ScanSummary(m.Summary)
+	case metrics.TypeSet:
+		return model.ScanSet(m.Set)
 	default:
 	}
 
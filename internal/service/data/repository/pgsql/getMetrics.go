@@ -13,7 +13,7 @@ func (s *Storage) GetMetrics() (*metrics.ProxyMetrics, error) {
 	//ctx, cancel := context.WithTimeout(parentCtx, queryTimeOut)
 	//defer cancel()
 
-	rows, err := s.db.QueryContext(s.ctx, `SELECT id, type, value, delta FROM metrics`)
+	rows, err := s.db.QueryContext(s.ctx, `SELECT id, type, value, delta, histogram, summary, hll FROM metrics`)
 	if err != nil {
 		return nil, err
 	}
@@ -23,7 +23,7 @@ func (s *Storage) GetMetrics() (*metrics.ProxyMetrics, error) {
 	// пробегаем по всем записям
 	for rows.Next() {
 		m := model.Metrics{}
-		err = rows.Scan(&m.ID, &m.MType, &m.Value, &m.Delta)
+		err = rows.Scan(&m.ID, &m.MType, &m.Value, &m.Delta, &m.Histogram, &m.Summary, &m.Set)
 		if err != nil {
 			return nil, err
 		}
@@ -39,6 +39,27 @@ func (s *Storage) GetMetrics() (*metrics.ProxyMetrics, error) {
 				log.Println("[WARNING] NULL counter value")
 			}
 			prm.Counters[m.ID] = metrics.Counter(m.Delta.Int64)
+		case metrics.TypeHistogram:
+			h, errHist := model.ScanHistogram(m.Histogram)
+			if errHist != nil {
+				log.Println("[WARNING] failed to read histogram value - ", errHist)
+				continue
+			}
+			prm.Histograms[m.ID] = h
+		case metrics.TypeSummary:
+			sm, errSummary := model.ScanSummary(m.Summary)
+			if errSummary != nil {
+				log.Println("[WARNING] failed to read summary value - ", errSummary)
+				continue
+			}
+			prm.Summaries[m.ID] = sm
+		case metrics.TypeSet:
+			set, errSet := model.ScanSet(m.Set)
+			if errSet != nil {
+				log.Println("[WARNING] failed to read set value - ", errSet)
+				continue
+			}
+			prm.Sets[m.ID] = set
 		default:
 			log.Println("[WARNING] not implemented metrics type")
 		}
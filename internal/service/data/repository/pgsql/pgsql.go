@@ -4,7 +4,9 @@ package pgsql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"github.com/sergeysynergy/metricser/internal/service/data/model"
+	metricserErrors "github.com/sergeysynergy/metricser/internal/service/errors"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 	"log"
 	"sync"
@@ -23,11 +25,30 @@ type Storage struct {
 	stmtGaugeInsert   *sql.Stmt
 	stmtCounterInsert *sql.Stmt
 	stmtGaugeUpdate   *sql.Stmt
+	stmtGaugeUpsert   *sql.Stmt
 	stmtCounterUpdate *sql.Stmt
 	stmtGaugeGet      *sql.Stmt
 	stmtCounterGet    *sql.Stmt
 	stmtAllUpdate     *sql.Stmt
 	stmtAllSelect     *sql.Stmt
+	stmtGaugeSample   *sql.Stmt
+	stmtCounterSample *sql.Stmt
+	stmtTypeGet       *sql.Stmt
+
+	stmtHistogramGet    *sql.Stmt
+	stmtHistogramInsert *sql.Stmt
+	stmtHistogramUpdate *sql.Stmt
+	stmtHistogramSample *sql.Stmt
+
+	stmtSummaryGet    *sql.Stmt
+	stmtSummaryInsert *sql.Stmt
+	stmtSummaryUpdate *sql.Stmt
+	stmtSummarySample *sql.Stmt
+
+	stmtSetGet    *sql.Stmt
+	stmtSetInsert *sql.Stmt
+	stmtSetUpdate *sql.Stmt
+	stmtSetSample *sql.Stmt
 }
 
 // New создаёт и инициализирует новую структуру типа Storage.
@@ -85,6 +106,12 @@ func (s *Storage) initTable() error {
 				type text NOT NULL, 
 				value double precision,
 				delta bigint,
+				histogram jsonb,
+				summary jsonb,
+				hll bytea,
+				name text,
+				labels jsonb,
+				updated_at timestamp with time zone,
 				PRIMARY KEY (id)
 			);
 		`)
@@ -95,6 +122,54 @@ func (s *Storage) initTable() error {
 		log.Println("table `metrics` created")
 	}
 
+	// id хранит ключ серии: имя метрики и отсортированный набор меток, см. metrics.SeriesKey;
+	// для таблиц, созданных предыдущими версиями, добавим колонки имени и меток серии
+	_, err = s.db.ExecContext(s.ctx, `
+		ALTER TABLE public.metrics ADD COLUMN IF NOT EXISTS name text;
+		ALTER TABLE public.metrics ADD COLUMN IF NOT EXISTS labels jsonb;
+		ALTER TABLE public.metrics ADD COLUMN IF NOT EXISTS histogram jsonb;
+		ALTER TABLE public.metrics ADD COLUMN IF NOT EXISTS summary jsonb;
+		ALTER TABLE public.metrics ADD COLUMN IF NOT EXISTS hll bytea;
+		ALTER TABLE public.metrics ADD COLUMN IF NOT EXISTS updated_at timestamp with time zone;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(s.ctx, "select * from samples;")
+	if err != nil {
+		_, err = s.db.ExecContext(s.ctx, `
+			CREATE TABLE public.samples (
+				id text NOT NULL,
+				type text NOT NULL,
+				value double precision,
+				delta bigint,
+				histogram jsonb,
+				summary jsonb,
+				hll bytea,
+				created_at timestamp with time zone NOT NULL
+			);
+			CREATE INDEX samples_id_created_at_idx ON public.samples (id, created_at);
+		`)
+		if err != nil {
+			return err
+		}
+
+		log.Println("table `samples` created")
+	}
+
+	// гистограммы и скетчи хранятся в формате JSON, множества — в двоичном виде;
+	// добавим колонки для таблиц, созданных предыдущими версиями
+	_, err = s.db.ExecContext(s.ctx, `
+		ALTER TABLE public.samples ADD COLUMN IF NOT EXISTS histogram jsonb;
+		ALTER TABLE public.samples ADD COLUMN IF NOT EXISTS summary jsonb;
+		ALTER TABLE public.samples ADD COLUMN IF NOT EXISTS hll bytea;
+		CREATE INDEX IF NOT EXISTS samples_created_at_idx ON public.samples (created_at);
+	`)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -102,17 +177,26 @@ func (s *Storage) initTable() error {
 func (s *Storage) initStatements() error {
 	var err error
 
-	s.stmtGaugeInsert, err = s.db.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, value) VALUES ($1, 'gauge', $2)")
+	s.stmtGaugeInsert, err = s.db.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, value, name, labels) VALUES ($1, 'gauge', $2, $3, $4)")
 	if err != nil {
 		return err
 	}
 
-	s.stmtCounterInsert, err = s.db.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, delta) VALUES ($1, 'counter', $2)")
+	s.stmtCounterInsert, err = s.db.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, delta, name, labels) VALUES ($1, 'counter', $2, $3, $4)")
 	if err != nil {
 		return err
 	}
 
-	s.stmtGaugeUpdate, err = s.db.PrepareContext(s.ctx, "UPDATE metrics SET value = $2 WHERE id = $1")
+	// время восстановленного значения неизвестно
+	s.stmtGaugeUpdate, err = s.db.PrepareContext(s.ctx, "UPDATE metrics SET value = $2, updated_at = NULL WHERE id = $1")
+	if err != nil {
+		return err
+	}
+
+	// значение gauge заменяется, только если принятое значение не старше хранимого
+	s.stmtGaugeUpsert, err = s.db.PrepareContext(s.ctx, `INSERT INTO metrics (id, type, value, name, labels, updated_at) VALUES ($1, 'gauge', $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+		WHERE metrics.updated_at IS NULL OR metrics.updated_at <= excluded.updated_at`)
 	if err != nil {
 		return err
 	}
@@ -142,6 +226,81 @@ func (s *Storage) initStatements() error {
 		return err
 	}
 
+	s.stmtGaugeSample, err = s.db.PrepareContext(s.ctx, "INSERT INTO samples (id, type, value, created_at) VALUES ($1, 'gauge', $2, $3)")
+	if err != nil {
+		return err
+	}
+
+	s.stmtCounterSample, err = s.db.PrepareContext(s.ctx, "INSERT INTO samples (id, type, delta, created_at) VALUES ($1, 'counter', $2, $3)")
+	if err != nil {
+		return err
+	}
+
+	s.stmtTypeGet, err = s.db.PrepareContext(s.ctx, "SELECT type FROM metrics WHERE id=$1")
+	if err != nil {
+		return err
+	}
+
+	s.stmtHistogramGet, err = s.db.PrepareContext(s.ctx, "SELECT type, histogram FROM metrics WHERE id=$1 FOR UPDATE")
+	if err != nil {
+		return err
+	}
+
+	s.stmtHistogramInsert, err = s.db.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, histogram, name, labels) VALUES ($1, 'histogram', $2, $3, $4)")
+	if err != nil {
+		return err
+	}
+
+	s.stmtHistogramUpdate, err = s.db.PrepareContext(s.ctx, "UPDATE metrics SET histogram = $2 WHERE id = $1")
+	if err != nil {
+		return err
+	}
+
+	s.stmtHistogramSample, err = s.db.PrepareContext(s.ctx, "INSERT INTO samples (id, type, histogram, created_at) VALUES ($1, 'histogram', $2, $3)")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSummaryGet, err = s.db.PrepareContext(s.ctx, "SELECT type, summary FROM metrics WHERE id=$1 FOR UPDATE")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSummaryInsert, err = s.db.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, summary, name, labels) VALUES ($1, 'summary', $2, $3, $4)")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSummaryUpdate, err = s.db.PrepareContext(s.ctx, "UPDATE metrics SET summary = $2 WHERE id = $1")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSummarySample, err = s.db.PrepareContext(s.ctx, "INSERT INTO samples (id, type, summary, created_at) VALUES ($1, 'summary', $2, $3)")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSetGet, err = s.db.PrepareContext(s.ctx, "SELECT type, hll FROM metrics WHERE id=$1 FOR UPDATE")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSetInsert, err = s.db.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, hll, name, labels) VALUES ($1, 'set', $2, $3, $4)")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSetUpdate, err = s.db.PrepareContext(s.ctx, "UPDATE metrics SET hll = $2 WHERE id = $1")
+	if err != nil {
+		return err
+	}
+
+	s.stmtSetSample, err = s.db.PrepareContext(s.ctx, "INSERT INTO samples (id, type, hll, created_at) VALUES ($1, 'set', $2, $3)")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -162,6 +321,11 @@ func (s *Storage) closeStatements() error {
 		return err
 	}
 
+	err = s.stmtGaugeUpsert.Close()
+	if err != nil {
+		return err
+	}
+
 	err = s.stmtCounterUpdate.Close()
 	if err != nil {
 		return err
@@ -187,6 +351,28 @@ func (s *Storage) closeStatements() error {
 		return err
 	}
 
+	err = s.stmtGaugeSample.Close()
+	if err != nil {
+		return err
+	}
+
+	err = s.stmtCounterSample.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range []*sql.Stmt{
+		s.stmtTypeGet,
+		s.stmtHistogramGet, s.stmtHistogramInsert, s.stmtHistogramUpdate, s.stmtHistogramSample,
+		s.stmtSummaryGet, s.stmtSummaryInsert, s.stmtSummaryUpdate, s.stmtSummarySample,
+		s.stmtSetGet, s.stmtSetInsert, s.stmtSetUpdate, s.stmtSetSample,
+	} {
+		err = stmt.Close()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -229,7 +415,8 @@ func (s *Storage) checkCounter(id string) (metrics.Counter, error) {
 
 	err := row.Scan(&m.ID, &m.MType, &m.Value, &m.Delta)
 	if err == sql.ErrNoRows {
-		_, err = s.stmtCounterInsert.ExecContext(s.ctx, id, 0)
+		name, labels := model.SeriesColumns(id)
+		_, err = s.stmtCounterInsert.ExecContext(s.ctx, id, 0, name, labels)
 		if err != nil {
 			return 0, err
 		}
@@ -238,6 +425,32 @@ func (s *Storage) checkCounter(id string) (metrics.Counter, error) {
 	if err != nil {
 		return 0, err
 	}
+	if err = checkType(id, metrics.TypeCounter, m.MType); err != nil {
+		return 0, err
+	}
 
 	return metrics.Counter(m.Delta.Int64), nil
 }
+
+// checkGauge Проверяет, что метрика id отсутствует в БД или хранится как `gauge`.
+func (s *Storage) checkGauge(stmt *sql.Stmt, id string) error {
+	var mType string
+	err := stmt.QueryRowContext(s.ctx, id).Scan(&mType)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return checkType(id, metrics.TypeGauge, mType)
+}
+
+// checkType Возвращает ошибку ErrTypeConflict, если метрика id хранится с типом stored, отличным от mType:
+// тип записи в БД не изменяется, а значения других типов в ней не хранятся.
+func checkType(id, mType, stored string) error {
+	if stored != mType {
+		return fmt.Errorf("%s %s stored as %s: %w", mType, id, stored, metricserErrors.ErrTypeConflict)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package pgsql
+
+import "time"
+
+// PruneHistory Удаляет из БД отсчёты истории с метками времени раньше before.
+func (s *Storage) PruneHistory(before time.Time) error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM samples WHERE created_at < $1`, before)
+	return err
+}
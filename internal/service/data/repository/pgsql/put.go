@@ -1,6 +1,9 @@
 package pgsql
 
 import (
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/service/data/model"
 	metricserErrors "github.com/sergeysynergy/metricser/internal/service/errors"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 )
@@ -9,25 +12,17 @@ import (
 func (s *Storage) Put(id string, val interface{}) error {
 	switch m := val.(type) {
 	case metrics.Gauge:
-		result, err := s.db.ExecContext(s.ctx, `UPDATE metrics SET value = $2 WHERE id = $1`, id, m)
-		if err != nil {
+		if err := s.checkGauge(s.stmtTypeGet, id); err != nil {
 			return err
 		}
-
-		rows, err := result.RowsAffected()
-		if err != nil {
+		now := time.Now()
+		name, labels := model.SeriesColumns(id)
+		if _, err := s.stmtGaugeUpsert.ExecContext(s.ctx, id, m, name, labels, now); err != nil {
 			return err
 		}
-		if rows == 0 {
-			_, err = s.db.ExecContext(
-				s.ctx,
-				`INSERT INTO metrics (id, type, value) VALUES ($1, 'gauge', $2)`,
-				id,
-				m,
-			)
-			if err != nil {
-				return err
-			}
+
+		if _, err := s.stmtGaugeSample.ExecContext(s.ctx, id, m, now); err != nil {
+			return err
 		}
 	case metrics.Counter:
 		// получим текущее значение счётчика
@@ -39,6 +34,23 @@ func (s *Storage) Put(id string, val interface{}) error {
 		if _, err = s.stmtCounterUpdate.ExecContext(s.ctx, id, m+v); err != nil {
 			return err
 		}
+
+		if _, err = s.stmtCounterSample.ExecContext(s.ctx, id, m, time.Now()); err != nil {
+			return err
+		}
+	case metrics.Histogram:
+		// гистограммы объединяются с сохранённым значением в транзакции
+		prm := metrics.NewProxyMetrics()
+		prm.Histograms[id] = m
+		return s.PutMetrics(prm)
+	case metrics.Summary:
+		prm := metrics.NewProxyMetrics()
+		prm.Summaries[id] = m
+		return s.PutMetrics(prm)
+	case metrics.Set:
+		prm := metrics.NewProxyMetrics()
+		prm.Sets[id] = m
+		return s.PutMetrics(prm)
 	default:
 		return metricserErrors.MetricNotImplemented
 	}
@@ -2,41 +2,60 @@ package pgsql
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/sergeysynergy/metricser/internal/service/data/model"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 	"log"
+	"time"
 )
 
 // PutMetrics Массово записывает значение метрик в БД.
 func (s *Storage) PutMetrics(m *metrics.ProxyMetrics) error {
+	return s.putMetrics(m, metrics.NewSamples(m, time.Now()))
+}
+
+// PutSamples Записывает отсчёты в БД с их метками времени и обновляет текущие значения метрик.
+func (s *Storage) PutSamples(samples []metrics.Sample) error {
+	return s.putMetrics(metrics.NewProxyMetricsFromSamples(samples), samples)
+}
+
+// putMetrics Записывает текущие значения метрик и отсчёты истории в одной транзакции.
+// Время значений `gauge` берётся из отсчётов.
+func (s *Storage) putMetrics(m *metrics.ProxyMetrics, samples []metrics.Sample) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	txGaugeUpdate := tx.StmtContext(s.ctx, s.stmtGaugeUpdate)
+	txGaugeUpsert := tx.StmtContext(s.ctx, s.stmtGaugeUpsert)
 	txCounterUpdate := tx.StmtContext(s.ctx, s.stmtCounterUpdate)
-	txGaugeInsert := tx.StmtContext(s.ctx, s.stmtGaugeInsert)
 	txCounterInsert := tx.StmtContext(s.ctx, s.stmtCounterInsert)
 	txCounterGet := tx.StmtContext(s.ctx, s.stmtCounterGet)
+	txGaugeSample := tx.StmtContext(s.ctx, s.stmtGaugeSample)
+	txCounterSample := tx.StmtContext(s.ctx, s.stmtCounterSample)
+	txHistogramGet := tx.StmtContext(s.ctx, s.stmtHistogramGet)
+	txHistogramInsert := tx.StmtContext(s.ctx, s.stmtHistogramInsert)
+	txHistogramUpdate := tx.StmtContext(s.ctx, s.stmtHistogramUpdate)
+	txHistogramSample := tx.StmtContext(s.ctx, s.stmtHistogramSample)
+	txSummaryGet := tx.StmtContext(s.ctx, s.stmtSummaryGet)
+	txSummaryInsert := tx.StmtContext(s.ctx, s.stmtSummaryInsert)
+	txSummaryUpdate := tx.StmtContext(s.ctx, s.stmtSummaryUpdate)
+	txSummarySample := tx.StmtContext(s.ctx, s.stmtSummarySample)
+	txSetGet := tx.StmtContext(s.ctx, s.stmtSetGet)
+	txSetInsert := tx.StmtContext(s.ctx, s.stmtSetInsert)
+	txSetUpdate := tx.StmtContext(s.ctx, s.stmtSetUpdate)
+	txSetSample := tx.StmtContext(s.ctx, s.stmtSetSample)
+	txTypeGet := tx.StmtContext(s.ctx, s.stmtTypeGet)
 
-	if m.Gauges != nil {
-		for id, value := range m.Gauges {
-			result, errGauge := txGaugeUpdate.Exec(id, value)
-			if errGauge != nil {
-				return errGauge
-			}
-			count, errGauge := result.RowsAffected()
-			if errGauge != nil {
-				return errGauge
-			}
-			if count == 0 {
-				_, errGaugeInsert := txGaugeInsert.ExecContext(s.ctx, id, value)
-				if errGaugeInsert != nil {
-					return errGaugeInsert
-				}
-			}
+	times := metrics.GaugeTimes(samples)
+	for id, value := range m.Gauges {
+		if err = s.checkGauge(txTypeGet, id); err != nil {
+			return err
+		}
+		name, labels := model.SeriesColumns(id)
+		if _, err = txGaugeUpsert.ExecContext(s.ctx, id, value, name, labels, times[id]); err != nil {
+			return err
 		}
 	}
 
@@ -49,8 +68,9 @@ func (s *Storage) PutMetrics(m *metrics.ProxyMetrics) error {
 			err = row.Scan(&mtx.ID, &mtx.MType, &mtx.Value, &mtx.Delta)
 			if err == sql.ErrNoRows {
 				// добавим новую запись в случае отсутствия результата
-				// s.pgsql.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, delta) VALUES ($1, 'counter', $2)")
-				_, err = txCounterInsert.ExecContext(s.ctx, id, delta)
+				// s.pgsql.PrepareContext(s.ctx, "INSERT INTO metrics (id, type, delta, name, labels) VALUES ($1, 'counter', $2, $3, $4)")
+				name, labels := model.SeriesColumns(id)
+				_, err = txCounterInsert.ExecContext(s.ctx, id, delta, name, labels)
 				if err != nil {
 					return err
 				}
@@ -59,6 +79,9 @@ func (s *Storage) PutMetrics(m *metrics.ProxyMetrics) error {
 			if err != nil {
 				return err
 			}
+			if err = checkType(id, metrics.TypeCounter, mtx.MType); err != nil {
+				return err
+			}
 
 			// запишем увеличенное значение
 			v := metrics.Counter(0)
@@ -73,6 +96,169 @@ func (s *Storage) PutMetrics(m *metrics.ProxyMetrics) error {
 		}
 	}
 
+	for id, h := range m.Histograms {
+		// получим текущее значение гистограммы и объединим его с принятым
+		mtx := model.Metrics{}
+		err = txHistogramGet.QueryRowContext(s.ctx, id).Scan(&mtx.MType, &mtx.Histogram)
+		if err == sql.ErrNoRows {
+			col, errCol := model.JSONColumn(h)
+			if errCol != nil {
+				return errCol
+			}
+			name, labels := model.SeriesColumns(id)
+			_, err = txHistogramInsert.ExecContext(s.ctx, id, col, name, labels)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = checkType(id, metrics.TypeHistogram, mtx.MType); err != nil {
+			return err
+		}
+
+		current, err := model.ScanHistogram(mtx.Histogram)
+		if err != nil {
+			return err
+		}
+		merged, err := current.Merge(h)
+		if err != nil {
+			return fmt.Errorf("histogram %s: %w", id, err)
+		}
+		col, err := model.JSONColumn(merged)
+		if err != nil {
+			return err
+		}
+		if _, err = txHistogramUpdate.ExecContext(s.ctx, id, col); err != nil {
+			return err
+		}
+	}
+
+	for id, sm := range m.Summaries {
+		// получим текущее значение скетча и объединим его с принятым
+		mtx := model.Metrics{}
+		err = txSummaryGet.QueryRowContext(s.ctx, id).Scan(&mtx.MType, &mtx.Summary)
+		if err == sql.ErrNoRows {
+			col, errCol := model.JSONColumn(sm)
+			if errCol != nil {
+				return errCol
+			}
+			name, labels := model.SeriesColumns(id)
+			_, err = txSummaryInsert.ExecContext(s.ctx, id, col, name, labels)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = checkType(id, metrics.TypeSummary, mtx.MType); err != nil {
+			return err
+		}
+
+		current, err := model.ScanSummary(mtx.Summary)
+		if err != nil {
+			return err
+		}
+		merged, err := current.Merge(sm)
+		if err != nil {
+			return fmt.Errorf("summary %s: %w", id, err)
+		}
+		col, err := model.JSONColumn(merged)
+		if err != nil {
+			return err
+		}
+		if _, err = txSummaryUpdate.ExecContext(s.ctx, id, col); err != nil {
+			return err
+		}
+	}
+
+	for id, set := range m.Sets {
+		// получим текущее значение множества и объединим его с принятым
+		mtx := model.Metrics{}
+		err = txSetGet.QueryRowContext(s.ctx, id).Scan(&mtx.MType, &mtx.Set)
+		if err == sql.ErrNoRows {
+			col, errCol := set.MarshalBinary()
+			if errCol != nil {
+				return errCol
+			}
+			name, labels := model.SeriesColumns(id)
+			_, err = txSetInsert.ExecContext(s.ctx, id, col, name, labels)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = checkType(id, metrics.TypeSet, mtx.MType); err != nil {
+			return err
+		}
+
+		current, err := model.ScanSet(mtx.Set)
+		if err != nil {
+			return err
+		}
+		merged, err := current.Merge(set)
+		if err != nil {
+			return fmt.Errorf("set %s: %w", id, err)
+		}
+		col, err := merged.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if _, err = txSetUpdate.ExecContext(s.ctx, id, col); err != nil {
+			return err
+		}
+	}
+
+	// сохраним принятые значения и приращения в истории
+	for _, sample := range samples {
+		switch sample.MType {
+		case metrics.TypeGauge:
+			_, err = txGaugeSample.ExecContext(s.ctx, sample.ID, sample.Value, sample.Timestamp)
+		case metrics.TypeCounter:
+			_, err = txCounterSample.ExecContext(s.ctx, sample.ID, sample.Delta, sample.Timestamp)
+		case metrics.TypeHistogram:
+			if sample.Histogram == nil {
+				continue
+			}
+			col, errCol := model.JSONColumn(*sample.Histogram)
+			if errCol != nil {
+				return errCol
+			}
+			_, err = txHistogramSample.ExecContext(s.ctx, sample.ID, col, sample.Timestamp)
+		case metrics.TypeSummary:
+			if sample.Summary == nil {
+				continue
+			}
+			col, errCol := model.JSONColumn(*sample.Summary)
+			if errCol != nil {
+				return errCol
+			}
+			_, err = txSummarySample.ExecContext(s.ctx, sample.ID, col, sample.Timestamp)
+		case metrics.TypeSet:
+			if sample.Set == nil {
+				continue
+			}
+			col, errCol := sample.Set.MarshalBinary()
+			if errCol != nil {
+				return errCol
+			}
+			_, err = txSetSample.ExecContext(s.ctx, sample.ID, col, sample.Timestamp)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		log.Println("[ERROR] put metrics transaction failed - ", err)
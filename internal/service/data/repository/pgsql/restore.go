@@ -1,6 +1,7 @@
 package pgsql
 
 import (
+	"github.com/sergeysynergy/metricser/internal/service/data/model"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 	"log"
 )
@@ -30,7 +31,8 @@ func (s *Storage) Restore(m *metrics.ProxyMetrics) error {
 				return err
 			}
 			if count == 0 {
-				_, errGaugeInsert := txGaugeInsert.ExecContext(s.ctx, id, value)
+				name, labels := model.SeriesColumns(id)
+				_, errGaugeInsert := txGaugeInsert.ExecContext(s.ctx, id, value, name, labels)
 				if errGaugeInsert != nil {
 					return err
 				}
@@ -51,7 +53,8 @@ func (s *Storage) Restore(m *metrics.ProxyMetrics) error {
 				return err
 			}
 			if count == 0 {
-				_, errCounterInsert := txCounterInsert.ExecContext(s.ctx, id, delta)
+				name, labels := model.SeriesColumns(id)
+				_, errCounterInsert := txCounterInsert.ExecContext(s.ctx, id, delta, name, labels)
 				if errCounterInsert != nil {
 					return err
 				}
@@ -59,6 +62,84 @@ func (s *Storage) Restore(m *metrics.ProxyMetrics) error {
 		}
 	}
 
+	// запишем значения гистограмм, заменяя сохранённые
+	if len(m.Histograms) > 0 {
+		txHistogramUpdate := tx.StmtContext(s.ctx, s.stmtHistogramUpdate)
+		txHistogramInsert := tx.StmtContext(s.ctx, s.stmtHistogramInsert)
+		for id, h := range m.Histograms {
+			col, errHist := model.JSONColumn(h)
+			if errHist != nil {
+				return errHist
+			}
+			result, errHist := txHistogramUpdate.Exec(id, col)
+			if errHist != nil {
+				return errHist
+			}
+			count, errHist := result.RowsAffected()
+			if errHist != nil {
+				return errHist
+			}
+			if count == 0 {
+				name, labels := model.SeriesColumns(id)
+				if _, errHist = txHistogramInsert.ExecContext(s.ctx, id, col, name, labels); errHist != nil {
+					return errHist
+				}
+			}
+		}
+	}
+
+	// запишем значения скетчей, заменяя сохранённые
+	if len(m.Summaries) > 0 {
+		txSummaryUpdate := tx.StmtContext(s.ctx, s.stmtSummaryUpdate)
+		txSummaryInsert := tx.StmtContext(s.ctx, s.stmtSummaryInsert)
+		for id, sm := range m.Summaries {
+			col, errSummary := model.JSONColumn(sm)
+			if errSummary != nil {
+				return errSummary
+			}
+			result, errSummary := txSummaryUpdate.Exec(id, col)
+			if errSummary != nil {
+				return errSummary
+			}
+			count, errSummary := result.RowsAffected()
+			if errSummary != nil {
+				return errSummary
+			}
+			if count == 0 {
+				name, labels := model.SeriesColumns(id)
+				if _, errSummary = txSummaryInsert.ExecContext(s.ctx, id, col, name, labels); errSummary != nil {
+					return errSummary
+				}
+			}
+		}
+	}
+
+	// запишем значения множеств, заменяя сохранённые
+	if len(m.Sets) > 0 {
+		txSetUpdate := tx.StmtContext(s.ctx, s.stmtSetUpdate)
+		txSetInsert := tx.StmtContext(s.ctx, s.stmtSetInsert)
+		for id, set := range m.Sets {
+			col, errSet := set.MarshalBinary()
+			if errSet != nil {
+				return errSet
+			}
+			result, errSet := txSetUpdate.Exec(id, col)
+			if errSet != nil {
+				return errSet
+			}
+			count, errSet := result.RowsAffected()
+			if errSet != nil {
+				return errSet
+			}
+			if count == 0 {
+				name, labels := model.SeriesColumns(id)
+				if _, errSet = txSetInsert.ExecContext(s.ctx, id, col, name, labels); errSet != nil {
+					return errSet
+				}
+			}
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		log.Println("[ERROR] put metrics transaction failed - ", err)
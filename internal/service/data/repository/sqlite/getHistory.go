@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"math"
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/service/data/model"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// GetHistory извлекает из БД отсчёты метрики с заданным ID за промежуток времени [from, to].
+func (s *Storage) GetHistory(id string, from, to time.Time) ([]metrics.Sample, error) {
+	rows, err := s.db.QueryContext(
+		s.ctx,
+		`SELECT id, type, value, delta, histogram, summary, hll, created_at FROM samples
+			WHERE id = ?1 AND created_at BETWEEN ?2 AND ?3 ORDER BY created_at`,
+		id, unixNano(from), unixNano(to),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// обязательно закрываем перед возвратом функции
+	defer rows.Close()
+
+	samples := make([]metrics.Sample, 0)
+	for rows.Next() {
+		m := model.Sample{}
+		var createdAt int64
+		err = rows.Scan(&m.ID, &m.MType, &m.Value, &m.Delta, &m.Histogram, &m.Summary, &m.Set, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		sample := metrics.Sample{
+			ID:        m.ID,
+			MType:     m.MType,
+			Value:     m.Value.Float64,
+			Delta:     m.Delta.Int64,
+			Timestamp: time.Unix(0, createdAt),
+		}
+		switch m.MType {
+		case metrics.TypeHistogram:
+			h, errHist := model.ScanHistogram(m.Histogram)
+			if errHist != nil {
+				return nil, errHist
+			}
+			sample.Histogram = &h
+		case metrics.TypeSummary:
+			sm, errSummary := model.ScanSummary(m.Summary)
+			if errSummary != nil {
+				return nil, errSummary
+			}
+			sample.Summary = &sm
+		case metrics.TypeSet:
+			set, errSet := model.ScanSet(m.Set)
+			if errSet != nil {
+				return nil, errSet
+			}
+			sample.Set = &set
+		}
+		samples = append(samples, sample)
+	}
+
+	// проверяем на ошибки
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// unixNano Переводит время в наносекунды Unix, ограничивая значения за пределами диапазона int64.
+func unixNano(t time.Time) int64 {
+	switch {
+	case t.Before(time.Unix(0, math.MinInt64)):
+		return math.MinInt64
+	case t.After(time.Unix(0, math.MaxInt64)):
+		return math.MaxInt64
+	}
+
+	return t.UnixNano()
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"log"
+
+	"github.com/sergeysynergy/metricser/internal/service/data/model"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// GetMetrics извлекает из БД значение всех метрик.
+func (s *Storage) GetMetrics() (*metrics.ProxyMetrics, error) {
+	prm := metrics.NewProxyMetrics()
+
+	rows, err := s.stmtMetricsSelect.QueryContext(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	// обязательно закрываем перед возвратом функции
+	defer rows.Close()
+
+	for rows.Next() {
+		m := model.Metrics{}
+		err = rows.Scan(&m.ID, &m.MType, &m.Value, &m.Delta, &m.Histogram, &m.Summary, &m.Set)
+		if err != nil {
+			return nil, err
+		}
+
+		switch m.MType {
+		case metrics.TypeGauge:
+			if !m.Value.Valid {
+				log.Println("[WARNING] NULL gauge value")
+			}
+			prm.Gauges[m.ID] = metrics.Gauge(m.Value.Float64)
+		case metrics.TypeCounter:
+			if !m.Delta.Valid {
+				log.Println("[WARNING] NULL counter value")
+			}
+			prm.Counters[m.ID] = metrics.Counter(m.Delta.Int64)
+		case metrics.TypeHistogram:
+			h, errHist := model.ScanHistogram(m.Histogram)
+			if errHist != nil {
+				log.Println("[WARNING] failed to read histogram value - ", errHist)
+				continue
+			}
+			prm.Histograms[m.ID] = h
+		case metrics.TypeSummary:
+			sm, errSummary := model.ScanSummary(m.Summary)
+			if errSummary != nil {
+				log.Println("[WARNING] failed to read summary value - ", errSummary)
+				continue
+			}
+			prm.Summaries[m.ID] = sm
+		case metrics.TypeSet:
+			set, errSet := model.ScanSet(m.Set)
+			if errSet != nil {
+				log.Println("[WARNING] failed to read set value - ", errSet)
+				continue
+			}
+			prm.Sets[m.ID] = set
+		default:
+			log.Println("[WARNING] not implemented metrics type")
+		}
+	}
+
+	// проверяем на ошибки
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return prm, nil
+}
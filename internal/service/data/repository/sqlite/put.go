@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"time"
+
+	metricserErrors "github.com/sergeysynergy/metricser/internal/service/errors"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Put записывает значение метрики в БД для заданного ID.
+func (s *Storage) Put(id string, val interface{}) error {
+	prm := metrics.NewProxyMetrics()
+	switch m := val.(type) {
+	case metrics.Gauge:
+		prm.Gauges[id] = m
+	case metrics.Counter:
+		prm.Counters[id] = m
+	case metrics.Histogram:
+		prm.Histograms[id] = m
+	case metrics.Summary:
+		prm.Summaries[id] = m
+	case metrics.Set:
+		prm.Sets[id] = m
+	default:
+		return metricserErrors.MetricNotImplemented
+	}
+
+	return s.PutMetrics(prm)
+}
+
+// PutMetrics Массово записывает значение метрик в БД.
+func (s *Storage) PutMetrics(m *metrics.ProxyMetrics) error {
+	return s.putMetrics(m, metrics.NewSamples(m, time.Now()))
+}
+
+// PutSamples Записывает отсчёты в БД с их метками времени и обновляет текущие значения метрик.
+func (s *Storage) PutSamples(samples []metrics.Sample) error {
+	return s.putMetrics(metrics.NewProxyMetricsFromSamples(samples), samples)
+}
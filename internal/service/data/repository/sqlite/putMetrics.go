@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/sergeysynergy/metricser/internal/service/data/model"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// putMetrics Записывает текущие значения метрик и отсчёты истории в одной транзакции.
+// Время значений `gauge` берётся из отсчётов. Значение метрики, хранимой с другим типом,
+// отменяет всю транзакцию с ошибкой ErrTypeConflict.
+func (s *Storage) putMetrics(m *metrics.ProxyMetrics, samples []metrics.Sample) error {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txGaugePut := tx.StmtContext(s.ctx, s.stmtGaugePut)
+	txCounterAdd := tx.StmtContext(s.ctx, s.stmtCounterAdd)
+	txHistogramGet := tx.StmtContext(s.ctx, s.stmtHistogramGet)
+	txHistogramSet := tx.StmtContext(s.ctx, s.stmtHistogramSet)
+	txSummaryGet := tx.StmtContext(s.ctx, s.stmtSummaryGet)
+	txSummarySet := tx.StmtContext(s.ctx, s.stmtSummarySet)
+	txSetGet := tx.StmtContext(s.ctx, s.stmtSetGet)
+	txSetSet := tx.StmtContext(s.ctx, s.stmtSetSet)
+	txSampleInsert := tx.StmtContext(s.ctx, s.stmtSampleInsert)
+	txTypeGet := tx.StmtContext(s.ctx, s.stmtTypeGet)
+
+	// проверим типы до записи значений
+	for id := range m.Gauges {
+		if err = s.checkType(txTypeGet, id, metrics.TypeGauge); err != nil {
+			return err
+		}
+	}
+	for id := range m.Counters {
+		if err = s.checkType(txTypeGet, id, metrics.TypeCounter); err != nil {
+			return err
+		}
+	}
+	for id := range m.Histograms {
+		if err = s.checkType(txTypeGet, id, metrics.TypeHistogram); err != nil {
+			return err
+		}
+	}
+	for id := range m.Summaries {
+		if err = s.checkType(txTypeGet, id, metrics.TypeSummary); err != nil {
+			return err
+		}
+	}
+	for id := range m.Sets {
+		if err = s.checkType(txTypeGet, id, metrics.TypeSet); err != nil {
+			return err
+		}
+	}
+
+	times := metrics.GaugeTimes(samples)
+	for id, value := range m.Gauges {
+		name, labels := model.SeriesColumns(id)
+		if _, err = txGaugePut.ExecContext(s.ctx, id, float64(value), name, labels, times[id].UnixNano()); err != nil {
+			return err
+		}
+	}
+
+	for id, delta := range m.Counters {
+		name, labels := model.SeriesColumns(id)
+		if _, err = txCounterAdd.ExecContext(s.ctx, id, int64(delta), name, labels); err != nil {
+			return err
+		}
+	}
+
+	for id, h := range m.Histograms {
+		// получим текущее значение гистограммы и объединим его с принятым
+		mtx := model.Metrics{}
+		err = txHistogramGet.QueryRowContext(s.ctx, id).Scan(&mtx.Histogram)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			current, errHist := model.ScanHistogram(mtx.Histogram)
+			if errHist != nil {
+				return errHist
+			}
+			h, err = current.Merge(h)
+			if err != nil {
+				return fmt.Errorf("histogram %s: %w", id, err)
+			}
+		}
+
+		col, err := model.JSONColumn(h)
+		if err != nil {
+			return err
+		}
+		name, labels := model.SeriesColumns(id)
+		if _, err = txHistogramSet.ExecContext(s.ctx, id, col, name, labels); err != nil {
+			return err
+		}
+	}
+
+	for id, sm := range m.Summaries {
+		// получим текущее значение скетча и объединим его с принятым
+		mtx := model.Metrics{}
+		err = txSummaryGet.QueryRowContext(s.ctx, id).Scan(&mtx.Summary)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			current, errSummary := model.ScanSummary(mtx.Summary)
+			if errSummary != nil {
+				return errSummary
+			}
+			sm, err = current.Merge(sm)
+			if err != nil {
+				return fmt.Errorf("summary %s: %w", id, err)
+			}
+		}
+
+		col, err := model.JSONColumn(sm)
+		if err != nil {
+			return err
+		}
+		name, labels := model.SeriesColumns(id)
+		if _, err = txSummarySet.ExecContext(s.ctx, id, col, name, labels); err != nil {
+			return err
+		}
+	}
+
+	for id, set := range m.Sets {
+		// получим текущее значение множества и объединим его с принятым
+		mtx := model.Metrics{}
+		err = txSetGet.QueryRowContext(s.ctx, id).Scan(&mtx.Set)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			current, errSet := model.ScanSet(mtx.Set)
+			if errSet != nil {
+				return errSet
+			}
+			set, err = current.Merge(set)
+			if err != nil {
+				return fmt.Errorf("set %s: %w", id, err)
+			}
+		}
+
+		col, err := set.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		name, labels := model.SeriesColumns(id)
+		if _, err = txSetSet.ExecContext(s.ctx, id, col, name, labels); err != nil {
+			return err
+		}
+	}
+
+	// сохраним принятые значения и приращения в истории
+	for _, sample := range samples {
+		row, errSample := sampleColumns(sample)
+		if errSample != nil {
+			return errSample
+		}
+		_, err = txSampleInsert.ExecContext(s.ctx,
+			row.ID, row.MType, row.Value, row.Delta, row.Histogram, row.Summary, row.Set, unixNano(row.CreatedAt),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Println("[ERROR] put metrics transaction failed - ", err)
+		return err
+	}
+
+	return nil
+}
+
+// sampleColumns Представляет отсчёт метрики в формате БД.
+func sampleColumns(sample metrics.Sample) (model.Sample, error) {
+	m := model.Sample{
+		ID:        sample.ID,
+		MType:     sample.MType,
+		CreatedAt: sample.Timestamp,
+	}
+
+	var err error
+	switch sample.MType {
+	case metrics.TypeGauge:
+		m.Value = sql.NullFloat64{Float64: sample.Value, Valid: true}
+	case metrics.TypeCounter:
+		m.Delta = sql.NullInt64{Int64: sample.Delta, Valid: true}
+	case metrics.TypeHistogram:
+		if sample.Histogram != nil {
+			m.Histogram.String, err = model.JSONColumn(*sample.Histogram)
+			m.Histogram.Valid = err == nil
+		}
+	case metrics.TypeSummary:
+		if sample.Summary != nil {
+			m.Summary.String, err = model.JSONColumn(*sample.Summary)
+			m.Summary.Valid = err == nil
+		}
+	case metrics.TypeSet:
+		if sample.Set != nil {
+			m.Set, err = sample.Set.MarshalBinary()
+		}
+	}
+
+	return m, err
+}
@@ -0,0 +1,7 @@
+package sqlite
+
+// ResetSets Удаляет из БД текущие значения всех метрик типа `set`; отсчёты истории сохраняются.
+func (s *Storage) ResetSets() error {
+	_, err := s.db.ExecContext(s.ctx, `DELETE FROM metrics WHERE type = 'set'`)
+	return err
+}
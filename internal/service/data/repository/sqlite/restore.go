@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"log"
+
+	"github.com/sergeysynergy/metricser/internal/service/data/model"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Restore Массово загружает переданные значения метрик в БД, заменяя сохранённые.
+func (s *Storage) Restore(m *metrics.ProxyMetrics) error {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txGaugeSet := tx.StmtContext(s.ctx, s.stmtGaugeSet)
+	txCounterSet := tx.StmtContext(s.ctx, s.stmtCounterSet)
+	txHistogramSet := tx.StmtContext(s.ctx, s.stmtHistogramSet)
+	txSummarySet := tx.StmtContext(s.ctx, s.stmtSummarySet)
+	txSetSet := tx.StmtContext(s.ctx, s.stmtSetSet)
+
+	for id, value := range m.Gauges {
+		name, labels := model.SeriesColumns(id)
+		if _, err = txGaugeSet.ExecContext(s.ctx, id, float64(value), name, labels); err != nil {
+			return err
+		}
+	}
+
+	for id, delta := range m.Counters {
+		name, labels := model.SeriesColumns(id)
+		if _, err = txCounterSet.ExecContext(s.ctx, id, int64(delta), name, labels); err != nil {
+			return err
+		}
+	}
+
+	for id, h := range m.Histograms {
+		col, errHist := model.JSONColumn(h)
+		if errHist != nil {
+			return errHist
+		}
+		name, labels := model.SeriesColumns(id)
+		if _, err = txHistogramSet.ExecContext(s.ctx, id, col, name, labels); err != nil {
+			return err
+		}
+	}
+
+	for id, sm := range m.Summaries {
+		col, errSummary := model.JSONColumn(sm)
+		if errSummary != nil {
+			return errSummary
+		}
+		name, labels := model.SeriesColumns(id)
+		if _, err = txSummarySet.ExecContext(s.ctx, id, col, name, labels); err != nil {
+			return err
+		}
+	}
+
+	for id, set := range m.Sets {
+		col, errSet := set.MarshalBinary()
+		if errSet != nil {
+			return errSet
+		}
+		name, labels := model.SeriesColumns(id)
+		if _, err = txSetSet.ExecContext(s.ctx, id, col, name, labels); err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Println("[ERROR] restore metrics transaction failed - ", err)
+		return err
+	}
+
+	return nil
+}
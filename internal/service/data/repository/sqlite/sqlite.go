@@ -0,0 +1,248 @@
+// Package sqlite Пакет предназначен для записи/извлечения значений метрик в файл базы данных SQLite.
+//
+// Таблицы повторяют схему pgsql, поэтому строки читаются в те же модели model.Metrics и model.Sample.
+// Используется драйвер на чистом Go, сборка не требует cgo.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	metricserErrors "github.com/sergeysynergy/metricser/internal/service/errors"
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+// Проверка на выполнение контракта интерфейса.
+var _ storage.Repo = new(Storage)
+
+// Storage хранит подключение к БД, контекст выполнения и список SQL-утверждений.
+type Storage struct {
+	db     *sql.DB
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stmtGaugeSet      *sql.Stmt
+	stmtGaugePut      *sql.Stmt
+	stmtCounterAdd    *sql.Stmt
+	stmtCounterSet    *sql.Stmt
+	stmtHistogramGet  *sql.Stmt
+	stmtHistogramSet  *sql.Stmt
+	stmtSummaryGet    *sql.Stmt
+	stmtSummarySet    *sql.Stmt
+	stmtSetGet        *sql.Stmt
+	stmtSetSet        *sql.Stmt
+	stmtSampleInsert  *sql.Stmt
+	stmtMetricsSelect *sql.Stmt
+	stmtTypeGet       *sql.Stmt
+}
+
+// New Открывает файл БД по заданному пути, создавая его и таблицы с метриками при необходимости.
+func New(path string) (*Storage, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty SQLite database path")
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite допускает одного писателя: ожидаем освобождения блокировки вместо ошибки SQLITE_BUSY,
+	// а журнал WAL позволяет читать во время записи
+	q := url.Values{}
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "synchronous(NORMAL)")
+	dsn := url.URL{Scheme: "file", Path: path, RawQuery: q.Encode()}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	// транзакции выполняются последовательно через одно подключение
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxIdleTime(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Storage{
+		db:     db,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	err = s.initTable()
+	if err != nil {
+		cancel()
+		db.Close()
+		return nil, fmt.Errorf("database initialization failed - %w", err)
+	}
+
+	err = s.initStatements()
+	if err != nil {
+		cancel()
+		db.Close()
+		return nil, fmt.Errorf("database statements initialization failed - %w", err)
+	}
+
+	return s, nil
+}
+
+// initTable Создаёт в БД таблицы с метриками и отсчётами, если они отсутствуют.
+// Гистограммы и скетчи хранятся в формате JSON, множества — в двоичном виде,
+// время отсчёта и значения gauge — в наносекундах Unix, чтобы сравнение по времени не зависело от часового пояса.
+func (s *Storage) initTable() error {
+	_, err := s.db.ExecContext(s.ctx, `
+		CREATE TABLE IF NOT EXISTS metrics (
+			id text NOT NULL,
+			type text NOT NULL,
+			value double precision,
+			delta bigint,
+			histogram text,
+			summary text,
+			hll blob,
+			name text,
+			labels text,
+			updated_at bigint,
+			PRIMARY KEY (id)
+		);
+		CREATE TABLE IF NOT EXISTS samples (
+			id text NOT NULL,
+			type text NOT NULL,
+			value double precision,
+			delta bigint,
+			histogram text,
+			summary text,
+			hll blob,
+			created_at bigint NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS samples_id_created_at_idx ON samples (id, created_at);
+		CREATE INDEX IF NOT EXISTS samples_created_at_idx ON samples (created_at);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// добавим колонку времени значения gauge для таблиц, созданных предыдущими версиями
+	_, err = s.db.ExecContext(s.ctx, `SELECT updated_at FROM metrics LIMIT 0`)
+	if err != nil {
+		_, err = s.db.ExecContext(s.ctx, `ALTER TABLE metrics ADD COLUMN updated_at bigint`)
+	}
+
+	return err
+}
+
+// replaceColumns Сбрасывает тип и значения записи метрики перед записью значения нового типа.
+const replaceColumns = `type = excluded.type, value = NULL, delta = NULL, histogram = NULL, summary = NULL, hll = NULL, updated_at = NULL`
+
+// initStatements Инициализирует SQL-утверждения при запуске.
+func (s *Storage) initStatements() error {
+	var err error
+
+	prepare := func(query string) *sql.Stmt {
+		if err != nil {
+			return nil
+		}
+		var stmt *sql.Stmt
+		stmt, err = s.db.PrepareContext(s.ctx, query)
+		return stmt
+	}
+
+	// утверждения *Set заменяют запись целиком, включая тип: ими восстанавливаются значения метрик,
+	// а при записи принятых значений тип проверяется заранее, см. checkType;
+	// время восстановленного значения gauge неизвестно
+	s.stmtGaugeSet = prepare(`INSERT INTO metrics (id, type, value, name, labels) VALUES (?1, 'gauge', ?2, ?3, ?4)
+		ON CONFLICT (id) DO UPDATE SET ` + replaceColumns + `, value = excluded.value`)
+	// значение gauge заменяется, только если принятое значение не старше хранимого
+	s.stmtGaugePut = prepare(`INSERT INTO metrics (id, type, value, name, labels, updated_at) VALUES (?1, 'gauge', ?2, ?3, ?4, ?5)
+		ON CONFLICT (id) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+		WHERE metrics.updated_at IS NULL OR metrics.updated_at <= excluded.updated_at`)
+	s.stmtCounterAdd = prepare(`INSERT INTO metrics (id, type, delta, name, labels) VALUES (?1, 'counter', ?2, ?3, ?4)
+		ON CONFLICT (id) DO UPDATE SET delta = coalesce(delta, 0) + excluded.delta`)
+	s.stmtCounterSet = prepare(`INSERT INTO metrics (id, type, delta, name, labels) VALUES (?1, 'counter', ?2, ?3, ?4)
+		ON CONFLICT (id) DO UPDATE SET ` + replaceColumns + `, delta = excluded.delta`)
+	s.stmtHistogramGet = prepare(`SELECT histogram FROM metrics WHERE id = ?1`)
+	s.stmtHistogramSet = prepare(`INSERT INTO metrics (id, type, histogram, name, labels) VALUES (?1, 'histogram', ?2, ?3, ?4)
+		ON CONFLICT (id) DO UPDATE SET ` + replaceColumns + `, histogram = excluded.histogram`)
+	s.stmtSummaryGet = prepare(`SELECT summary FROM metrics WHERE id = ?1`)
+	s.stmtSummarySet = prepare(`INSERT INTO metrics (id, type, summary, name, labels) VALUES (?1, 'summary', ?2, ?3, ?4)
+		ON CONFLICT (id) DO UPDATE SET ` + replaceColumns + `, summary = excluded.summary`)
+	s.stmtSetGet = prepare(`SELECT hll FROM metrics WHERE id = ?1`)
+	s.stmtSetSet = prepare(`INSERT INTO metrics (id, type, hll, name, labels) VALUES (?1, 'set', ?2, ?3, ?4)
+		ON CONFLICT (id) DO UPDATE SET ` + replaceColumns + `, hll = excluded.hll`)
+	s.stmtSampleInsert = prepare(`INSERT INTO samples (id, type, value, delta, histogram, summary, hll, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`)
+	s.stmtMetricsSelect = prepare(`SELECT id, type, value, delta, histogram, summary, hll FROM metrics`)
+	s.stmtTypeGet = prepare(`SELECT type FROM metrics WHERE id = ?1`)
+
+	return err
+}
+
+// closeStatements Закрывает SQL-утверждения при завершении работы.
+func (s *Storage) closeStatements() error {
+	for _, stmt := range []*sql.Stmt{
+		s.stmtGaugeSet, s.stmtGaugePut, s.stmtCounterAdd, s.stmtCounterSet,
+		s.stmtHistogramGet, s.stmtHistogramSet,
+		s.stmtSummaryGet, s.stmtSummarySet,
+		s.stmtSetGet, s.stmtSetSet,
+		s.stmtSampleInsert, s.stmtMetricsSelect, s.stmtTypeGet,
+	} {
+		err := stmt.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ping позволяет проверить доступность БД.
+func (s *Storage) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	return s.db.PingContext(ctx)
+}
+
+// Shutdown штатно завершает работу с БД.
+func (s *Storage) Shutdown() error {
+	s.cancel()
+
+	err := s.closeStatements()
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Close()
+	if err != nil {
+		return err
+	}
+
+	log.Println("[DEBUG] Gracefully close SQLite database")
+	return nil
+}
+
+// checkType Возвращает ошибку ErrTypeConflict, если метрика id уже хранится в БД с типом, отличным от mType:
+// тип записи при приёме значений не изменяется.
+func (s *Storage) checkType(stmt *sql.Stmt, id, mType string) error {
+	var stored string
+	err := stmt.QueryRowContext(s.ctx, id).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stored != mType {
+		return fmt.Errorf("%s %s stored as %s: %w", mType, id, stored, metricserErrors.ErrTypeConflict)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	metricserErrors "github.com/sergeysynergy/metricser/internal/service/errors"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLitePutGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metricser.db")
+
+	h := metrics.NewHistogram(metrics.DefaultBuckets)
+	h.Observe(0.3)
+	sm := metrics.NewSummary(metrics.DefaultSummaryAlpha)
+	sm.Observe(42)
+	set := metrics.NewSet(metrics.DefaultSetPrecision)
+	set.Add("user-1")
+
+	s, err := New(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Ping())
+
+	require.NoError(t, s.Put("Alloc", metrics.Gauge(1.5)))
+	require.NoError(t, s.Put("Alloc", metrics.Gauge(2.5)))
+	require.NoError(t, s.Put("PollCount", metrics.Counter(2)))
+	require.NoError(t, s.Put(metrics.SeriesKey("requests", map[string]string{"code": "200"}), metrics.Counter(1)))
+	require.NoError(t, s.PutMetrics(&metrics.ProxyMetrics{
+		Counters:   map[string]metrics.Counter{"PollCount": 3},
+		Histograms: map[string]metrics.Histogram{"latency": h},
+		Summaries:  map[string]metrics.Summary{"size": sm},
+		Sets:       map[string]metrics.Set{"users": set},
+	}))
+	require.NoError(t, s.Put("latency", h))
+	// скетч с другими границами отклоняется целиком
+	assert.ErrorIs(t, s.PutMetrics(&metrics.ProxyMetrics{
+		Gauges:     map[string]metrics.Gauge{"Alloc": 100},
+		Histograms: map[string]metrics.Histogram{"latency": metrics.NewHistogram([]float64{1})},
+	}), metrics.ErrBucketsMismatch)
+
+	value, err := s.Get("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(2.5), value)
+	value, err = s.Get("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Counter(5), value)
+	value, err = s.Get("latency")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), value.(metrics.Histogram).Count)
+	_, err = s.Get("unknown")
+	assert.Error(t, err)
+
+	want, err := s.GetMetrics()
+	require.NoError(t, err)
+	assert.Len(t, want.Counters, 2)
+	assert.Equal(t, set, want.Sets["users"])
+	require.NoError(t, s.Shutdown())
+
+	// значения сохраняются после повторного открытия БД
+	s, err = New(path)
+	require.NoError(t, err)
+	defer s.Shutdown()
+
+	got, err := s.GetMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	history, err := s.GetHistory("PollCount", time.Time{}, time.Now())
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, int64(2), history[0].Delta)
+	assert.Equal(t, int64(3), history[1].Delta)
+
+	history, err = s.GetHistory("users", time.Now().Add(-time.Minute), time.Now())
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, set, *history[0].Set)
+
+	// запоздавший отсчёт gauge не заменяет более новое значение
+	now := time.Now()
+	require.NoError(t, s.PutSamples([]metrics.Sample{{ID: "Alloc", MType: metrics.TypeGauge, Value: 7, Timestamp: now.Add(time.Minute)}}))
+	require.NoError(t, s.PutSamples([]metrics.Sample{{ID: "Alloc", MType: metrics.TypeGauge, Value: 3, Timestamp: now}}))
+	value, err = s.Get("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(7), value)
+}
+
+func TestSQLiteRestoreAndResetSets(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "metricser.db"))
+	require.NoError(t, err)
+	defer s.Shutdown()
+
+	ts := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.PutSamples([]metrics.Sample{
+		{ID: "PollCount", MType: metrics.TypeCounter, Delta: 4, Timestamp: ts},
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: 1, Timestamp: ts},
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: 2, Timestamp: ts.Add(time.Second)},
+	}))
+	require.NoError(t, s.Put("users", metrics.NewSet(metrics.DefaultSetPrecision)))
+
+	prm, err := s.GetMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(2), prm.Gauges["Alloc"])
+
+	history, err := s.GetHistory("Alloc", ts, ts)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.True(t, ts.Equal(history[0].Timestamp))
+
+	// Restore заменяет значения счётчиков, а не прибавляет к ним
+	require.NoError(t, s.Restore(&metrics.ProxyMetrics{Counters: map[string]metrics.Counter{"PollCount": 10}}))
+	value, err := s.Get("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Counter(10), value)
+
+	require.NoError(t, s.ResetSets())
+	prm, err = s.GetMetrics()
+	require.NoError(t, err)
+	assert.Empty(t, prm.Sets)
+	assert.Len(t, prm.Gauges, 1)
+}
+
+func TestSQLiteTypeConflict(t *testing.T) {
+	// путь с символами, которые в DSN нужно экранировать
+	path := filepath.Join(t.TempDir(), "metrics #1?.db")
+	s, err := New(path)
+	require.NoError(t, err)
+	defer s.Shutdown()
+
+	require.NoError(t, s.Put("Alloc", metrics.Gauge(1)))
+	assert.FileExists(t, path)
+	require.NoError(t, s.Put("PollCount", metrics.Counter(1)))
+
+	// значение другого типа отменяет весь пакет
+	err = s.PutMetrics(&metrics.ProxyMetrics{
+		Gauges:     map[string]metrics.Gauge{"Free": 5},
+		Histograms: map[string]metrics.Histogram{"Alloc": metrics.NewHistogram(nil)},
+	})
+	assert.ErrorIs(t, err, metricserErrors.ErrTypeConflict)
+	assert.ErrorIs(t, s.Put("PollCount", metrics.Gauge(2)), metricserErrors.ErrTypeConflict)
+	assert.ErrorIs(t, s.Put("Alloc", metrics.NewSet(metrics.DefaultSetPrecision)), metricserErrors.ErrTypeConflict)
+
+	prm, err := s.GetMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]metrics.Gauge{"Alloc": 1}, prm.Gauges)
+	assert.Equal(t, map[string]metrics.Counter{"PollCount": 1}, prm.Counters)
+	assert.Empty(t, prm.Histograms)
+
+	// Restore заменяет значение вместе с типом
+	require.NoError(t, s.Restore(&metrics.ProxyMetrics{Counters: map[string]metrics.Counter{"Alloc": 3}}))
+	value, err := s.Get("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Counter(3), value)
+}
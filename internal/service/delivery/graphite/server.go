@@ -0,0 +1,186 @@
+// Package graphite Пакет реализует приём метрик по строковому протоколу Graphite через TCP и UDP.
+// Принятые значения сохраняются как метрики типа gauge через заданный интервал.
+package graphite
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/service/delivery/listener"
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	gr "github.com/sergeysynergy/metricser/pkg/graphite"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Server Хранит данные и объекты для приёма метрик Graphite.
+type Server struct {
+	uc            storage.UseCase
+	mapper        *gr.Mapper
+	tcpAddr       string
+	udpAddr       string
+	flushInterval time.Duration
+	lines         *listener.Listener
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+
+	mu     sync.Mutex
+	points map[string]gr.Point
+}
+
+type Option func(server *Server)
+
+// New Создаёт новый объект типа Server.
+func New(uc storage.UseCase, opts ...Option) *Server {
+	const (
+		defaultFlushInterval = 10 * time.Second
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		uc:            uc,
+		mapper:        &gr.Mapper{},
+		flushInterval: defaultFlushInterval,
+		ctx:           ctx,
+		cancel:        cancel,
+		points:        make(map[string]gr.Point),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.lines = listener.New("Graphite", s.handle,
+		listener.WithTCPAddress(s.tcpAddr),
+		listener.WithUDPAddress(s.udpAddr),
+	)
+
+	return s
+}
+
+// WithTCPAddress Использует переданный адрес для приёма метрик по TCP.
+func WithTCPAddress(addr string) Option {
+	return func(s *Server) {
+		s.tcpAddr = addr
+	}
+}
+
+// WithUDPAddress Использует переданный адрес для приёма метрик по UDP.
+func WithUDPAddress(addr string) Option {
+	return func(s *Server) {
+		s.udpAddr = addr
+	}
+}
+
+// WithFlushInterval Использует переданный интервал записи принятых метрик в хранилище.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		if interval > 0 {
+			s.flushInterval = interval
+		}
+	}
+}
+
+// WithMapper Использует переданные правила преобразования путей в ID и метки.
+func WithMapper(mapper *gr.Mapper) Option {
+	return func(s *Server) {
+		if mapper != nil {
+			s.mapper = mapper
+		}
+	}
+}
+
+// Enabled Сообщает, задан ли хотя бы один адрес приёма метрик.
+func (s *Server) Enabled() bool {
+	return s.lines.Enabled()
+}
+
+// Serve Открывает заданные адреса и запускает приём метрик.
+func (s *Server) Serve() error {
+	err := s.lines.Serve()
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.flushTicker()
+
+	return nil
+}
+
+// Shutdown Останавливает приём метрик и записывает принятые значения в хранилище.
+func (s *Server) Shutdown() error {
+	s.cancel()
+	s.lines.Shutdown()
+	s.wg.Wait()
+
+	s.flush()
+	log.Println("[DEBUG] Gracefully shutdown Graphite listener")
+
+	return nil
+}
+
+// handle Разбирает строку и запоминает значение до следующей записи в хранилище.
+// Из нескольких значений одной метрики за интервал остаётся значение с наибольшей отметкой времени.
+func (s *Server) handle(line []byte) {
+	p, err := gr.ParseLine(string(line))
+	if err != nil {
+		log.Println("[WARNING] Bad Graphite metric -", err)
+		return
+	}
+
+	id, labels := s.mapper.Map(p.Path)
+	if err = metrics.CheckLabels(labels); err != nil {
+		log.Println("[WARNING] Bad Graphite metric -", err)
+		return
+	}
+
+	key := metrics.SeriesKey(id, labels)
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.points[key]; ok && p.Time.Before(prev.Time) {
+		return
+	}
+	s.points[key] = p
+}
+
+func (s *Server) flushTicker() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// flush Записывает принятые метрики в хранилище.
+func (s *Server) flush() {
+	s.mu.Lock()
+	points := s.points
+	s.points = make(map[string]gr.Point)
+	s.mu.Unlock()
+
+	if len(points) == 0 {
+		return
+	}
+
+	prm := metrics.NewProxyMetrics()
+	for key, p := range points {
+		prm.Gauges[key] = metrics.Gauge(p.Value)
+	}
+	err := s.uc.PutMetrics(prm)
+	if err != nil {
+		log.Println("[ERROR] Failed to put Graphite metrics -", err)
+	}
+}
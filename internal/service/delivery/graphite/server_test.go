@@ -0,0 +1,62 @@
+package graphite
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	gr "github.com/sergeysynergy/metricser/pkg/graphite"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestServer(t *testing.T) {
+	mapper, err := gr.NewMapper([]gr.Rule{{Filter: "collectd.*", Template: "_.host.measurement*"}})
+	assert.NoError(t, err)
+
+	uc := storage.New()
+	s := New(uc,
+		WithTCPAddress("127.0.0.1:0"),
+		WithUDPAddress("127.0.0.1:0"),
+		WithFlushInterval(time.Hour),
+		WithMapper(mapper),
+	)
+	assert.True(t, s.Enabled())
+
+	err = s.Serve()
+	assert.NoError(t, err)
+
+	tcp, err := net.Dial("tcp", s.lines.TCPAddr().String())
+	assert.NoError(t, err)
+	_, err = tcp.Write([]byte("collectd.web01.load.shortterm 0.5 1600000000\nbad line here now\nservers.db.load 1 -1\n"))
+	assert.NoError(t, err)
+	tcp.Close()
+
+	udp, err := net.Dial("udp", s.lines.UDPAddr().String())
+	assert.NoError(t, err)
+	defer udp.Close()
+	_, err = udp.Write([]byte("collectd.web01.load.shortterm 0.75 1600000010\n"))
+	assert.NoError(t, err)
+
+	loadKey := metrics.SeriesKey("load.shortterm", metrics.Labels{"host": "web01"})
+
+	// дождёмся приёма обоих пакетов
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.points[loadKey].Value == 0.75 && s.points["servers.db.load"].Value == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// при остановке принятые метрики записываются в хранилище
+	err = s.Shutdown()
+	assert.NoError(t, err)
+
+	value, err := uc.Get(loadKey)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(0.75), value)
+	value, err = uc.Get("servers.db.load")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(1), value)
+}
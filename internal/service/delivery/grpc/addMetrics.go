@@ -6,6 +6,8 @@ import (
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 	pb "github.com/sergeysynergy/metricser/proto"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"log"
 )
@@ -15,17 +17,136 @@ func (s *MetricsServer) AddMetrics(ctx context.Context, in *pb.AddMetricsRequest
 	prm := metrics.NewProxyMetrics()
 	// Преобразуем формат метрик proto-файла к внутреннему формату.
 	for _, v := range in.Gauges {
-		prm.Gauges[v.Id] = metrics.Gauge(v.Value)
+		if err := metrics.CheckSeriesKey(metrics.SeriesKey(v.Id, v.Labels)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+		prm.Gauges[metrics.SeriesKey(v.Id, v.Labels)] = metrics.Gauge(v.Value)
 	}
 	for _, v := range in.Counters {
-		prm.Counters[v.Id] = metrics.Counter(v.Delta)
+		if err := metrics.CheckSeriesKey(metrics.SeriesKey(v.Id, v.Labels)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+		prm.Counters[metrics.SeriesKey(v.Id, v.Labels)] += metrics.Counter(v.Delta)
 	}
+	for _, v := range in.Histograms {
+		if err := metrics.CheckSeriesKey(metrics.SeriesKey(v.Id, v.Labels)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+		h := metrics.Histogram{Bounds: v.Bounds, Counts: v.Counts, Count: v.Count, Sum: v.Sum}
+		if err := h.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
 
-	err := s.uc.PutMetrics(prm)
+		// объединяем дублирующие гистограммы по корзинам
+		key := metrics.SeriesKey(v.Id, v.Labels)
+		if prev, ok := prm.Histograms[key]; ok {
+			merged, err := prev.Merge(h)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "%s: %s", key, err)
+			}
+			h = merged
+		}
+		prm.Histograms[key] = h
+	}
+	for _, v := range in.Summaries {
+		if err := metrics.CheckSeriesKey(metrics.SeriesKey(v.Id, v.Labels)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+		sm := metrics.Summary{
+			Alpha:    v.Alpha,
+			Positive: v.Positive,
+			Negative: v.Negative,
+			Zero:     v.Zero,
+			Count:    v.Count,
+			Sum:      v.Sum,
+			Min:      v.Min,
+			Max:      v.Max,
+		}
+		if err := sm.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+
+		// объединяем дублирующие скетчи
+		key := metrics.SeriesKey(v.Id, v.Labels)
+		if prev, ok := prm.Summaries[key]; ok {
+			merged, err := prev.Merge(sm)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "%s: %s", key, err)
+			}
+			sm = merged
+		}
+		prm.Summaries[key] = sm
+	}
+	for _, v := range in.Sets {
+		if err := metrics.CheckSeriesKey(metrics.SeriesKey(v.Id, v.Labels)); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+		set := metrics.Set{}
+		if err := set.UnmarshalBinary(v.Data); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+
+		// объединяем дублирующие множества
+		key := metrics.SeriesKey(v.Id, v.Labels)
+		if prev, ok := prm.Sets[key]; ok {
+			merged, err := prev.Merge(set)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "%s: %s", key, err)
+			}
+			set = merged
+		}
+		prm.Sets[key] = set
+	}
+
+	// повторно доставленный пакет уже учтён, второй раз его не применяем
+	batchID := firstMD(ctx, metrics.HeaderBatchID)
+	applied, err := s.uc.PutBatch(batchID, prm)
 	if err != nil {
 		return nil, status.Errorf(codes.Unknown, err.Error())
 	}
-	log.Println("[DEBUG] Metrics has been updated using gRPC")
+	if applied {
+		log.Println("[DEBUG] Metrics has been updated using gRPC")
+	} else {
+		log.Printf("[DEBUG] Batch %s already accepted, skipping\n", batchID)
+	}
+
+	s.putAgent(ctx)
 
 	return &empty.Empty{}, nil
 }
+
+// putAgent Запоминает сведения об агенте, если метаданные запроса содержат его идентификатор.
+func (s *MetricsServer) putAgent(ctx context.Context) {
+	info := metrics.AgentInfo{
+		ID:       firstMD(ctx, metrics.HeaderAgentID),
+		Hostname: firstMD(ctx, metrics.HeaderAgentHostname),
+		OS:       firstMD(ctx, metrics.HeaderAgentOS),
+		Version:  firstMD(ctx, metrics.HeaderAgentVersion),
+	}
+	if info.ID == "" {
+		return
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		info.Address = p.Addr.String()
+	}
+
+	err := s.uc.PutAgent(info)
+	if err != nil {
+		log.Println("[WARNING] Failed to register agent -", err)
+	}
+}
+
+// firstMD Возвращает первое значение метаданных запроса по ключу.
+func firstMD(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
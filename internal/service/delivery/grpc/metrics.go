@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
 	pb "github.com/sergeysynergy/metricser/proto"
 )
 
@@ -29,23 +30,89 @@ func (s *MetricsServer) ListMetrics(_ context.Context, _ *empty.Empty) (*pb.List
 
 	gauges := make([]*pb.Gauge, 0, len(prm.Gauges))
 	for k, v := range prm.Gauges {
+		id, labels, err := metrics.ParseSeriesKey(k)
+		if err != nil {
+			continue
+		}
 		gauges = append(gauges, &pb.Gauge{
-			Id:    k,
-			Value: float64(v),
+			Id:     id,
+			Value:  float64(v),
+			Labels: labels,
 		})
 	}
 
 	counters := make([]*pb.Counter, 0, len(prm.Counters))
 	for k, v := range prm.Counters {
+		id, labels, err := metrics.ParseSeriesKey(k)
+		if err != nil {
+			continue
+		}
 		counters = append(counters, &pb.Counter{
-			Id:    k,
-			Delta: int64(v),
+			Id:     id,
+			Delta:  int64(v),
+			Labels: labels,
+		})
+	}
+
+	histograms := make([]*pb.Histogram, 0, len(prm.Histograms))
+	for k, v := range prm.Histograms {
+		id, labels, err := metrics.ParseSeriesKey(k)
+		if err != nil {
+			continue
+		}
+		histograms = append(histograms, &pb.Histogram{
+			Id:     id,
+			Bounds: v.Bounds,
+			Counts: v.Counts,
+			Count:  v.Count,
+			Sum:    v.Sum,
+			Labels: labels,
+		})
+	}
+
+	summaries := make([]*pb.Summary, 0, len(prm.Summaries))
+	for k, v := range prm.Summaries {
+		id, labels, err := metrics.ParseSeriesKey(k)
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, &pb.Summary{
+			Id:       id,
+			Alpha:    v.Alpha,
+			Positive: v.Positive,
+			Negative: v.Negative,
+			Zero:     v.Zero,
+			Count:    v.Count,
+			Sum:      v.Sum,
+			Min:      v.Min,
+			Max:      v.Max,
+			Labels:   labels,
+		})
+	}
+
+	sets := make([]*pb.Set, 0, len(prm.Sets))
+	for k, v := range prm.Sets {
+		id, labels, err := metrics.ParseSeriesKey(k)
+		if err != nil {
+			continue
+		}
+		data, err := v.MarshalBinary()
+		if err != nil {
+			continue
+		}
+		sets = append(sets, &pb.Set{
+			Id:     id,
+			Data:   data,
+			Labels: labels,
 		})
 	}
 
 	response := pb.ListMetricsResponse{
-		Gauges:   gauges,
-		Counters: counters,
+		Gauges:     gauges,
+		Counters:   counters,
+		Histograms: histograms,
+		Summaries:  summaries,
+		Sets:       sets,
 	}
 	return &response, nil
 }
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Agents Возвращает сведения обо всех агентах, присылавших отчёты.
+func (h *Handler) Agents(w http.ResponseWriter, r *http.Request) {
+	agents, err := h.uc.GetAgents()
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(agents)
+	if err != nil {
+		h.errorJSONMarshalFailed(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", applicationJSON)
+	w.Write(body)
+}
+
+// putAgent Запоминает сведения об агенте, если запрос содержит его идентификатор.
+func (h *Handler) putAgent(r *http.Request) {
+	id := r.Header.Get(metrics.HeaderAgentID)
+	if id == "" {
+		return
+	}
+
+	// при подключенном middleware.RealIP в RemoteAddr содержится IP клиента из X-Real-IP
+	err := h.uc.PutAgent(metrics.AgentInfo{
+		ID:       id,
+		Hostname: r.Header.Get(metrics.HeaderAgentHostname),
+		OS:       r.Header.Get(metrics.HeaderAgentOS),
+		Version:  r.Header.Get(metrics.HeaderAgentVersion),
+		Address:  r.RemoteAddr,
+	})
+	if err != nil {
+		log.Println("[WARNING] Failed to register agent -", err)
+	}
+}
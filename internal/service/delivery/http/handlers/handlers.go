@@ -6,6 +6,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/influx"
+	"github.com/sergeysynergy/metricser/pkg/otlp"
 	"log"
 	"net"
 )
@@ -20,6 +22,8 @@ type Handler struct {
 	key           string
 	privateKey    *rsa.PrivateKey
 	trustedSubnet *net.IPNet
+	influx        *influx.Mapper // Правила преобразования точек InfluxDB в метрики
+	otlp          *otlp.Mapper   // Преобразование метрик OpenTelemetry
 
 	router chi.Router
 	uc     storage.UseCase
@@ -29,9 +33,14 @@ type Option func(handler *Handler)
 
 // New Создаёт новый объект JSON API Handler.
 func New(uc storage.UseCase, opts ...Option) *Handler {
+	// правила по умолчанию заведомо корректны
+	defaultInflux, _ := influx.NewMapper(influx.Mapping{})
+
 	h := &Handler{
 		router:        chi.NewRouter(), // создадим новый роутер
 		trustedSubnet: &net.IPNet{},    // создадим объект доверенной сети
+		influx:        defaultInflux,
+		otlp:          otlp.NewMapper(),
 		uc:            uc,
 	}
 
@@ -82,6 +91,18 @@ func WithKey(key string) Option {
 	}
 }
 
+// WithInfluxMapping Использует переданные правила преобразования точек InfluxDB в метрики.
+func WithInfluxMapping(m influx.Mapping) Option {
+	return func(h *Handler) {
+		mp, err := influx.NewMapper(m)
+		if err != nil {
+			log.Println("[WARNING] Failed to parse InfluxDB mapping! Default mapping will be used -", err)
+			return
+		}
+		h.influx = mp
+	}
+}
+
 // GetRouter Возвращает объект роутер.
 func (h *Handler) GetRouter() chi.Router {
 	return h.router
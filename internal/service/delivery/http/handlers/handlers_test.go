@@ -127,6 +127,84 @@ func TestGet(t *testing.T) {
 				value:      "metrics not found\n",
 			},
 		},
+		{
+			name: "Summary ok",
+			handler: func() *Handler {
+				uc := storage.New()
+				sm := metrics.NewSummary(metrics.DefaultSummaryAlpha)
+				sm.Observe(2)
+				require.NoError(t, uc.Put("Latency", sm))
+				return New(uc)
+			}(),
+			request: "/value/summary/Latency",
+			want: want{
+				statusCode: http.StatusOK,
+				value:      "0.5 2\n0.95 2\n0.99 2\n",
+			},
+		},
+		{
+			name:    "Summary not found",
+			handler: New(storage.New()),
+			request: "/value/summary/NotFound",
+			want: want{
+				statusCode: http.StatusNotFound,
+				value:      "metrics not found\n",
+			},
+		},
+		{
+			name: "Gauge type mismatch",
+			handler: New(storage.New(storage.WithCounters(
+				map[string]metrics.Counter{"PollCount": 42},
+			))),
+			request: "/value/gauge/PollCount",
+			want: want{
+				statusCode: http.StatusNotFound,
+				value:      "metric is not a gauge\n",
+			},
+		},
+		{
+			name: "Counter type mismatch",
+			handler: New(storage.New(storage.WithGauges(
+				map[string]metrics.Gauge{"Alloc": 1221.23},
+			))),
+			request: "/value/counter/Alloc",
+			want: want{
+				statusCode: http.StatusNotFound,
+				value:      "metric is not a counter\n",
+			},
+		},
+		{
+			name: "Histogram ok",
+			handler: func() *Handler {
+				uc := storage.New()
+				hist := metrics.NewHistogram([]float64{1, 10})
+				hist.Observe(0.5)
+				hist.Observe(5)
+				require.NoError(t, uc.Put("Latency", hist))
+				return New(uc)
+			}(),
+			request: "/value/histogram/Latency",
+			want: want{
+				statusCode: http.StatusOK,
+				value:      "1 1\n10 2\n+Inf 2\n",
+			},
+		},
+		{
+			name: "Set ok",
+			handler: func() *Handler {
+				uc := storage.New()
+				set := metrics.NewSet(metrics.DefaultSetPrecision)
+				set.Add("alice")
+				set.Add("bob")
+				require.NoError(t, uc.Put("Users", set))
+				return New(uc)
+			}(),
+			request: "/value/set/Users",
+			want: want{
+				statusCode: http.StatusOK,
+				value:      "2",
+			},
+		},
 		{
 			name:    "Not implemented",
 			handler: New(storage.New()),
@@ -187,3 +265,24 @@ func TestList(t *testing.T) {
 		})
 	}
 }
+
+func TestPrometheus(t *testing.T) {
+	st := storage.New()
+	err := st.PutMetrics(&metrics.ProxyMetrics{
+		Gauges:   map[string]metrics.Gauge{metrics.Alloc: 42.5},
+		Counters: map[string]metrics.Counter{metrics.PollCount: 3},
+	})
+	require.NoError(t, err)
+
+	handler := New(st)
+	ts := httptest.NewServer(handler.GetRouter())
+	defer ts.Close()
+
+	resp, body := testRequest(t, ts, http.MethodGet, "/metrics")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, "# TYPE Alloc gauge\nAlloc 42.5\n")
+	assert.Contains(t, body, "# TYPE PollCount_total counter\nPollCount_total 3\n")
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -73,7 +74,11 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		gauge := value.(metrics.Gauge)
+		gauge, ok := value.(metrics.Gauge)
+		if !ok {
+			http.Error(w, "metric is not a gauge", http.StatusNotFound)
+			return
+		}
 		val = strconv.FormatFloat(float64(gauge), 'f', -1, 64)
 	case "counter":
 		counter, err := h.uc.Get(name)
@@ -81,7 +86,59 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		val = fmt.Sprintf("%d", counter)
+		delta, ok := counter.(metrics.Counter)
+		if !ok {
+			http.Error(w, "metric is not a counter", http.StatusNotFound)
+			return
+		}
+		val = strconv.FormatInt(int64(delta), 10)
+	case metrics.TypeHistogram:
+		value, err := h.uc.Get(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		hist, ok := value.(metrics.Histogram)
+		if !ok {
+			http.Error(w, "metric is not a histogram", http.StatusNotFound)
+			return
+		}
+		// корзины выводятся построчно с накопительными счётчиками: `<граница> <количество>`
+		for i, c := range hist.Cumulative() {
+			le := math.Inf(1)
+			if i < len(hist.Bounds) {
+				le = hist.Bounds[i]
+			}
+			val += fmt.Sprintf("%s %d\n", strconv.FormatFloat(le, 'f', -1, 64), c)
+		}
+	case metrics.TypeSet:
+		value, err := h.uc.Get(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		set, ok := value.(metrics.Set)
+		if !ok {
+			http.Error(w, "metric is not a set", http.StatusNotFound)
+			return
+		}
+		// выводится оценка числа уникальных значений
+		val = strconv.FormatUint(set.Estimate(), 10)
+	case metrics.TypeSummary:
+		value, err := h.uc.Get(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		sm, ok := value.(metrics.Summary)
+		if !ok {
+			http.Error(w, "metric is not a summary", http.StatusNotFound)
+			return
+		}
+		// квантили по умолчанию выводятся построчно: `<квантиль> <оценка>`
+		for _, q := range metrics.DefaultQuantiles {
+			val += fmt.Sprintf("%s %s\n", strconv.FormatFloat(q, 'f', -1, 64), strconv.FormatFloat(sm.Quantile(q), 'f', -1, 64))
+		}
 	default:
 		err := fmt.Errorf("not implemented")
 		http.Error(w, err.Error(), http.StatusNotImplemented)
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// History Возвращает отсчёты метрики за промежуток времени, заданный параметрами `from` и `to` в формате RFC3339.
+// По умолчанию возвращаются отсчёты за последний час. Остальные параметры запроса задают метки серии.
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	const defaultPeriod = time.Hour
+
+	name := chi.URLParam(r, "name")
+
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			h.errorJSON(w, r, fmt.Sprintf("bad `to` parameter - %s", err), http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultPeriod)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			h.errorJSON(w, r, fmt.Sprintf("bad `from` parameter - %s", err), http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+
+	labels := make(metrics.Labels)
+	for k, v := range r.URL.Query() {
+		if k == "from" || k == "to" || len(v) == 0 {
+			continue
+		}
+		labels[k] = v[0]
+	}
+
+	samples, err := h.uc.GetHistory(metrics.SeriesKey(name, labels), from, to)
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(samples)
+	if err != nil {
+		h.errorJSONMarshalFailed(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", applicationJSON)
+	w.Write(body)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/influx"
+)
+
+// maxInfluxBody Максимальный размер тела запроса записи точек InfluxDB после распаковки.
+const maxInfluxBody = 32 << 20
+
+// InfluxWrite Принимает метрики в строковом протоколе InfluxDB, совместимо с `POST /api/v2/write`.
+// Параметры org и bucket не используются. Значения сохраняются с временем точек.
+func (h *Handler) InfluxWrite(w http.ResponseWriter, r *http.Request) {
+	precision, err := influx.Precision(r.URL.Query().Get("precision"))
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInfluxBody))
+	if err != nil {
+		// MaxBytesReader отдаёт тело целиком до превышения предела
+		if len(body) >= maxInfluxBody {
+			h.errorJSON(w, r, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.errorJSONReadBodyFailed(w, r, err)
+		return
+	}
+
+	points, err := influx.Parse(body, precision)
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	samples := h.influx.Map(points, time.Now())
+	if len(samples) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = h.uc.PutSamples(samples)
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/influx"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestInfluxWrite(t *testing.T) {
+	st := storage.New()
+	handler := New(st, WithInfluxMapping(influx.Mapping{
+		Template: "{measurement}_{field}",
+		Counters: []string{"net_bytes_.*"},
+	}))
+	ts := httptest.NewServer(handler.GetRouter())
+	defer ts.Close()
+
+	gzipped := func(s string) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		_, err := gz.Write([]byte(s))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		return buf
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       *bytes.Buffer
+		gzip       bool
+		statusCode int
+	}{
+		{
+			name:       "Plain body",
+			path:       "/api/v2/write?org=o&bucket=b&precision=s",
+			body:       bytes.NewBufferString("cpu,host=a usage=12.5 1600000000\nnet,host=a bytes_recv=100i\n"),
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "Gzipped body",
+			path:       "/api/v2/write",
+			body:       gzipped("net,host=a bytes_recv=250i\n"),
+			gzip:       true,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "Bad line",
+			path:       "/api/v2/write",
+			body:       bytes.NewBufferString("cpu,host=a\n"),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Bad precision",
+			path:       "/api/v2/write?precision=h",
+			body:       bytes.NewBufferString("cpu value=1\n"),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Body too large",
+			path:       "/api/v2/write",
+			body:       bytes.NewBufferString("cpu usage=1\n" + strings.Repeat("#", maxInfluxBody)),
+			statusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "Bad gzip",
+			path:       "/api/v2/write",
+			body:       bytes.NewBufferString("not gzip"),
+			gzip:       true,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+tt.path, tt.body)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.statusCode, resp.StatusCode)
+		})
+	}
+
+	host := metrics.Labels{"host": "a"}
+	value, err := st.Get(metrics.SeriesKey("cpu_usage", host))
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(12.5), value)
+
+	// первое накопительное значение источника только запоминается, дальше учитываются приращения
+	value, err = st.Get(metrics.SeriesKey("net_bytes_recv", host))
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(150), value)
+
+	resp, body := testRequest(t, ts, http.MethodGet, "/metrics")
+	defer resp.Body.Close()
+	assert.Contains(t, body, `net_bytes_recv_total{host="a"} 150`)
+}
@@ -14,7 +14,13 @@ const listTemplate = `<h1>Current metrics data</h1>
 {{if .Counters}}
 <h2>Counters:</h1>{{range .Counters}}<div>{{.Key}} - {{.Delta}}</div>{{end}}
 {{end}}
-`
+{{if .Histograms}}
+<h2>Histograms:</h1>{{range .Histograms}}<div>{{.Key}} - count {{.Count}}, sum {{.Sum}}</div>{{end}}
+{{end}}{{if .Summaries}}
+<h2>Summaries:</h1>{{range .Summaries}}<div>{{.Key}} - count {{.Count}}, p50 {{.P50}}, p95 {{.P95}}, p99 {{.P99}}</div>{{end}}
+{{end}}{{if .Sets}}
+<h2>Sets:</h1>{{range .Sets}}<div>{{.Key}} - {{.Cardinality}} unique</div>{{end}}
+{{end}}`
 
 // List Возвращает список со значением всех метрик.
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
@@ -27,9 +33,26 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 			Key   string
 			Delta int64
 		}
+		histogram struct {
+			Key   string
+			Count uint64
+			Sum   float64
+		}
+		summary struct {
+			Key           string
+			Count         uint64
+			P50, P95, P99 float64
+		}
+		set struct {
+			Key         string
+			Cardinality uint64
+		}
 		metrics struct {
-			Gauges   []gauge
-			Counters []counter
+			Gauges     []gauge
+			Counters   []counter
+			Histograms []histogram
+			Summaries  []summary
+			Sets       []set
 		}
 	)
 
@@ -54,9 +77,36 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	}
 	sort.Slice(counters, func(i, j int) bool { return counters[i].Key < counters[j].Key })
 
+	histograms := make([]histogram, 0, len(prm.Histograms))
+	for k, val := range prm.Histograms {
+		histograms = append(histograms, histogram{Key: k, Count: val.Count, Sum: val.Sum})
+	}
+	sort.Slice(histograms, func(i, j int) bool { return histograms[i].Key < histograms[j].Key })
+
+	summaries := make([]summary, 0, len(prm.Summaries))
+	for k, val := range prm.Summaries {
+		summaries = append(summaries, summary{
+			Key:   k,
+			Count: val.Count,
+			P50:   val.Quantile(0.5),
+			P95:   val.Quantile(0.95),
+			P99:   val.Quantile(0.99),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Key < summaries[j].Key })
+
+	sets := make([]set, 0, len(prm.Sets))
+	for k, val := range prm.Sets {
+		sets = append(sets, set{Key: k, Cardinality: val.Estimate()})
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Key < sets[j].Key })
+
 	mcs := metrics{
-		Gauges:   gauges,
-		Counters: counters,
+		Gauges:     gauges,
+		Counters:   counters,
+		Histograms: histograms,
+		Summaries:  summaries,
+		Sets:       sets,
 	}
 
 	t, err := template.New("list").Parse(listTemplate)
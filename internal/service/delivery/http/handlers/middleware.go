@@ -29,10 +29,10 @@ func gzipDecompressor(next http.Handler) http.Handler {
 		if r.Header.Get("Content-Encoding") == "gzip" {
 			gz, err := gzip.NewReader(r.Body)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			} else {
-				r.Body = gz
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
+			r.Body = gz
 			defer gz.Close()
 		}
 
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	otlppb "github.com/sergeysynergy/metricser/proto/otlp"
+)
+
+const applicationProtobuf = "application/x-protobuf"
+
+// OTLPMetrics Принимает метрики OpenTelemetry по протоколу OTLP/HTTP, совместимо с `POST /v1/metrics`.
+// Тело запроса передаётся в формате protobuf или JSON, ответ возвращается в том же формате.
+func (h *Handler) OTLPMetrics(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != applicationProtobuf && contentType != applicationJSON {
+		h.errorJSON(w, r, fmt.Sprintf("unsupported content type '%s'", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.errorJSONReadBodyFailed(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+
+	req := &otlppb.ExportMetricsServiceRequest{}
+	if contentType == applicationJSON {
+		// поля OTLP, которые сервис не использует, пропускаются
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		h.errorJSON(w, r, "failed to unmarshal OTLP request - "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	prm, rejected := h.otlp.Map(req)
+	if !prm.Empty() {
+		err = h.uc.PutMetrics(prm)
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := &otlppb.ExportMetricsServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &otlppb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       "unsupported data point types or temporality, or inconsistent histogram buckets",
+		}
+	}
+
+	var data []byte
+	if contentType == applicationJSON {
+		data, err = protojson.Marshal(resp)
+	} else {
+		data, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	otlppb "github.com/sergeysynergy/metricser/proto/otlp"
+)
+
+func TestOTLPMetrics(t *testing.T) {
+	st := storage.New()
+	handler := New(st)
+	ts := httptest.NewServer(handler.GetRouter())
+	defer ts.Close()
+
+	requests := func(value int64) *otlppb.ExportMetricsServiceRequest {
+		return &otlppb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*otlppb.ResourceMetrics{{
+				ScopeMetrics: []*otlppb.ScopeMetrics{{Metrics: []*otlppb.Metric{
+					{Name: "requests", Data: &otlppb.Metric_Sum{Sum: &otlppb.Sum{
+						AggregationTemporality: otlppb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+						IsMonotonic:            true,
+						DataPoints: []*otlppb.NumberDataPoint{
+							{Value: &otlppb.NumberDataPoint_AsInt{AsInt: value}},
+						},
+					}}},
+					{Name: "rpc", Data: &otlppb.Metric_Summary{Summary: &otlppb.Summary{
+						DataPoints: []*otlppb.SummaryDataPoint{{Count: 1}},
+					}}},
+				}}},
+			}},
+		}
+	}
+	marshal := func(m proto.Message) []byte {
+		b, err := proto.Marshal(m)
+		require.NoError(t, err)
+		return b
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		statusCode  int
+		rejected    int64
+	}{
+		{
+			name:        "Protobuf",
+			contentType: applicationProtobuf,
+			body:        marshal(requests(10)),
+			statusCode:  http.StatusOK,
+			rejected:    1,
+		},
+		{
+			name:        "JSON",
+			contentType: applicationJSON,
+			// поля, которые сервис не использует, пропускаются
+			body: []byte(`{"resourceMetrics":[{"scopeMetrics":[{"scope":{"name":"lib"},"metrics":[{"name":"requests","sum":{` +
+				`"aggregationTemporality":2,"isMonotonic":true,"dataPoints":[{"asInt":"25"}]}}]}]}]}`),
+			statusCode: http.StatusOK,
+		},
+		{
+			name:        "Bad body",
+			contentType: applicationProtobuf,
+			body:        []byte("not protobuf"),
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:        "Bad content type",
+			contentType: "text/plain",
+			body:        marshal(requests(30)),
+			statusCode:  http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/metrics", bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.statusCode, resp.StatusCode)
+			if resp.StatusCode != http.StatusOK || tt.contentType != applicationProtobuf {
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			exportResp := &otlppb.ExportMetricsServiceResponse{}
+			require.NoError(t, proto.Unmarshal(body, exportResp))
+			assert.Equal(t, tt.rejected, exportResp.GetPartialSuccess().GetRejectedDataPoints())
+		})
+	}
+
+	// первое накопительное значение источника только запоминается, дальше учитываются приращения
+	value, err := st.Get("requests")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(15), value)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/sergeysynergy/metricser/pkg/prometheus"
+)
+
+// Prometheus Возвращает значения всех метрик в текстовом формате Prometheus.
+func (h *Handler) Prometheus(w http.ResponseWriter, r *http.Request) {
+	prm, err := h.uc.GetMetrics()
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	err = prometheus.Encode(buf, prm)
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", prometheus.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
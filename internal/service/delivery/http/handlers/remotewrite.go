@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/sergeysynergy/metricser/pkg/prometheus"
+)
+
+// RemoteWrite Принимает отсчёты Prometheus по протоколу remote_write: сжатый snappy protobuf WriteRequest.
+// Отсчёты сохраняются с метками времени источника. Недопустимые серии пропускаются с предупреждением,
+// остальные серии запроса записываются.
+func (h *Handler) RemoteWrite(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.errorJSONReadBodyFailed(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+
+	samples, skipped, err := prometheus.DecodeWriteRequest(body)
+	if skipped > 0 {
+		log.Printf("[WARNING] Skipped %d bad series in remote_write request - %s\n", skipped, err)
+	} else if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.uc.PutSamples(samples)
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
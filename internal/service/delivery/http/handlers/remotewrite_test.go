@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sergeysynergy/metricser/internal/service/data/repository/memory"
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	pb "github.com/sergeysynergy/metricser/proto"
+)
+
+func TestRemoteWrite(t *testing.T) {
+	st := storage.New(storage.WithDBStorer(memory.New(memory.WithHistory(0))))
+	handler := New(st)
+	ts := httptest.NewServer(handler.GetRouter())
+	defer ts.Close()
+
+	raw, err := proto.Marshal(&pb.WriteRequest{Timeseries: []*pb.TimeSeries{{
+		Labels: []*pb.Label{{Name: "__name__", Value: "node_load1"}, {Name: "instance", Value: "web01"}},
+		Samples: []*pb.Sample{
+			{Value: 0.5, Timestamp: 1600000015000},
+			{Value: 0.25, Timestamp: 1600000000000},
+		},
+	}}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		body       []byte
+		statusCode int
+	}{
+		{
+			name:       "Snappy body",
+			body:       snappy.Encode(nil, raw),
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "Uncompressed body",
+			body:       raw,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/write", bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			req.Header.Set("Content-Encoding", "snappy")
+			req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.statusCode, resp.StatusCode)
+		})
+	}
+
+	// текущее значение соответствует самому позднему отсчёту
+	key := metrics.SeriesKey("node_load1", metrics.Labels{"instance": "web01"})
+	value, err := st.Get(key)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(0.5), value)
+
+	// отсчёты сохраняются с метками времени источника
+	samples, err := st.GetHistory(key, time.UnixMilli(1600000000000), time.UnixMilli(1600000015000))
+	assert.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Equal(t, 0.25, samples[0].Value)
+	assert.Equal(t, time.UnixMilli(1600000000000), samples[0].Timestamp)
+	assert.Equal(t, 0.5, samples[1].Value)
+}
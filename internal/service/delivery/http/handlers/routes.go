@@ -4,6 +4,9 @@ package handlers
 func (h *Handler) setRoutes() {
 	h.router.Get("/", h.List)
 
+	// значения всех метрик в текстовом формате Prometheus
+	h.router.Get("/metrics", h.Prometheus)
+
 	// шаблон роутов POST http://<АДРЕС_СЕРВЕРА>/update/<ТИП_МЕТРИКИ>/<ИМЯ_МЕТРИКИ>/<ЗНАЧЕНИЕ_МЕТРИКИ>
 	h.router.Post("/update/{type}/{name}/{value}", h.Post)
 
@@ -15,6 +18,22 @@ func (h *Handler) setRoutes() {
 	h.router.Post("/updates/", h.Updates)
 	h.router.Post("/value/", h.Value)
 
+	// шаблон роутов GET http://<АДРЕС_СЕРВЕРА>/history/<ИМЯ_МЕТРИКИ>?from=<RFC3339>&to=<RFC3339>
+	h.router.Get("/history/{name}", h.History)
+
+	// сведения об агентах, присылавших отчёты
+	h.router.Get("/agents", h.Agents)
+
+	// приём метрик в строковом протоколе InfluxDB
+	h.router.Post("/api/v2/write", h.InfluxWrite)
+	h.router.Post("/write", h.InfluxWrite)
+
+	// приём метрик OpenTelemetry по протоколу OTLP/HTTP
+	h.router.Post("/v1/metrics", h.OTLPMetrics)
+
+	// приём отсчётов Prometheus по протоколу remote_write
+	h.router.Post("/api/v1/write", h.RemoteWrite)
+
 	// обработчики для работы с базой данных
 	h.router.Get("/ping", h.ping)
 }
@@ -36,6 +36,13 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		log.Println("[WARNING] unknown metric ID", m.ID)
 	}
 
+	key := m.Key()
+	err = metrics.CheckSeriesKey(key)
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	switch m.MType {
 	case "gauge":
 		if m.Value == nil {
@@ -43,14 +50,14 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if h.key != "" && m.Hash != "" {
-			if metrics.GaugeHash(h.key, m.ID, *m.Value) != m.Hash {
+			if metrics.GaugeHash(h.key, key, *m.Value) != m.Hash {
 				err = fmt.Errorf("hash check failed for gauge metric")
 				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
 				return
 			}
 		}
 
-		err = h.uc.Put(m.ID, metrics.Gauge(*m.Value))
+		err = h.uc.Put(key, metrics.Gauge(*m.Value))
 		if err != nil {
 			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
 			return
@@ -61,14 +68,83 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if h.key != "" && m.Hash != "" {
-			if metrics.CounterHash(h.key, m.ID, *m.Delta) != m.Hash {
+			if metrics.CounterHash(h.key, key, *m.Delta) != m.Hash {
 				err = fmt.Errorf("hash check failed for counter metric")
 				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
 				return
 			}
 		}
 
-		err = h.uc.Put(m.ID, metrics.Counter(*m.Delta))
+		err = h.uc.Put(key, metrics.Counter(*m.Delta))
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case metrics.TypeHistogram:
+		if m.Histogram == nil {
+			h.errorJSON(w, r, "nil histogram value", http.StatusBadRequest)
+			return
+		}
+		err = m.Histogram.Validate()
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if h.key != "" && m.Hash != "" {
+			if metrics.HistogramHash(h.key, key, *m.Histogram) != m.Hash {
+				err = fmt.Errorf("hash check failed for histogram metric")
+				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = h.uc.Put(key, *m.Histogram)
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case metrics.TypeSummary:
+		if m.Summary == nil {
+			h.errorJSON(w, r, "nil summary value", http.StatusBadRequest)
+			return
+		}
+		err = m.Summary.Validate()
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if h.key != "" && m.Hash != "" {
+			if metrics.SummaryHash(h.key, key, *m.Summary) != m.Hash {
+				err = fmt.Errorf("hash check failed for summary metric")
+				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = h.uc.Put(key, *m.Summary)
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case metrics.TypeSet:
+		if m.Set == nil {
+			h.errorJSON(w, r, "nil set value", http.StatusBadRequest)
+			return
+		}
+		err = m.Set.Validate()
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if h.key != "" && m.Hash != "" {
+			if metrics.SetHash(h.key, key, *m.Set) != m.Hash {
+				err = fmt.Errorf("hash check failed for set metric")
+				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = h.uc.Put(key, *m.Set)
 		if err != nil {
 			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
 			return
@@ -79,6 +155,8 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.putAgent(r)
+
 	w.WriteHeader(http.StatusOK)
 
 	// запишем метрики в файл, если проинициализировано хранилище на базе файла
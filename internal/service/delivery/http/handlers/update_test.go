@@ -104,6 +104,17 @@ func TestUpdate(t *testing.T) {
 				statusCode: http.StatusNotImplemented,
 			},
 		},
+		{
+			name: "Bad series key",
+			body: metrics.Metrics{
+				ID:    "Alloc{",
+				MType: "gauge",
+				Value: func() *float64 { v := 42.24; return &v }(),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
 		{
 			name: "Gauge ok",
 			body: metrics.Metrics{
@@ -172,3 +183,234 @@ func TestUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdatesLabels(t *testing.T) {
+	st := storage.New()
+	h := New(st)
+	ts := httptest.NewServer(h.router)
+	defer ts.Close()
+
+	alloc1, alloc2 := 1.0, 2.0
+	body := []metrics.Metrics{
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: &alloc1, Labels: metrics.Labels{"agent": "a1", "host": "srv1"}},
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: &alloc2, Labels: metrics.Labels{"host": "srv2", "agent": "a2"}},
+	}
+
+	client := resty.New()
+	resp, err := client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(body).
+		Post(ts.URL + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	prm, err := st.GetMetrics()
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(1), prm.Gauges[`Alloc{agent="a1",host="srv1"}`])
+	assert.Equal(t, metrics.Gauge(2), prm.Gauges[`Alloc{agent="a2",host="srv2"}`])
+
+	m := metrics.Metrics{}
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: metrics.Alloc, MType: metrics.TypeGauge, Labels: metrics.Labels{"host": "srv2", "agent": "a2"}}).
+		SetResult(&m).
+		Post(ts.URL + "/value/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, alloc2, *m.Value)
+
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody([]metrics.Metrics{{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: &alloc1, Labels: metrics.Labels{"bad-name": "x"}}}).
+		Post(ts.URL + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
+
+func TestUpdatesHistogram(t *testing.T) {
+	st := storage.New()
+	h := New(st)
+	ts := httptest.NewServer(h.router)
+	defer ts.Close()
+
+	latency := func(counts []uint64, count uint64, sum float64) *metrics.Histogram {
+		return &metrics.Histogram{Bounds: []float64{0.1, 1}, Counts: counts, Count: count, Sum: sum}
+	}
+	body := []metrics.Metrics{
+		{ID: "latency", MType: metrics.TypeHistogram, Histogram: latency([]uint64{1, 2, 0}, 3, 1.2)},
+		{ID: "latency", MType: metrics.TypeHistogram, Histogram: latency([]uint64{0, 1, 1}, 2, 3.5)},
+	}
+
+	client := resty.New()
+	resp, err := client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(body).
+		Post(ts.URL + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// повторное обновление объединяется с сохранённой гистограммой по корзинам
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeHistogram, Histogram: latency([]uint64{1, 0, 0}, 1, 0.05)}).
+		Post(ts.URL + "/update/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	m := metrics.Metrics{}
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeHistogram}).
+		SetResult(&m).
+		Post(ts.URL + "/value/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	if assert.NotNil(t, m.Histogram) {
+		assert.Equal(t, []uint64{2, 3, 1}, m.Histogram.Counts)
+		assert.Equal(t, uint64(6), m.Histogram.Count)
+		assert.InDelta(t, 4.75, m.Histogram.Sum, 1e-9)
+	}
+
+	// гистограмма с другими границами корзин не принимается
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeHistogram, Histogram: &metrics.Histogram{Bounds: []float64{5}, Counts: []uint64{1, 0}, Count: 1}}).
+		Post(ts.URL + "/update/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	// несогласованная гистограмма не принимается
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody([]metrics.Metrics{{ID: "latency", MType: metrics.TypeHistogram, Histogram: latency([]uint64{1, 0, 0}, 5, 0)}}).
+		Post(ts.URL + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
+
+func TestUpdatesSummary(t *testing.T) {
+	st := storage.New()
+	h := New(st)
+	ts := httptest.NewServer(h.router)
+	defer ts.Close()
+
+	// два агента передают скетчи своих наблюдений
+	first, second := metrics.NewSummary(0), metrics.NewSummary(0)
+	for i := 1; i <= 50; i++ {
+		first.Observe(float64(i))
+	}
+	for i := 51; i <= 100; i++ {
+		second.Observe(float64(i))
+	}
+
+	client := resty.New()
+	resp, err := client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody([]metrics.Metrics{
+			{ID: "latency", MType: metrics.TypeSummary, Summary: &first, Labels: metrics.Labels{"agent": "a1"}},
+			{ID: "latency", MType: metrics.TypeSummary, Summary: &first},
+		}).
+		Post(ts.URL + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeSummary, Summary: &second}).
+		Post(ts.URL + "/update/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	m := metrics.Metrics{}
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeSummary}).
+		SetResult(&m).
+		Post(ts.URL + "/value/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	if assert.NotNil(t, m.Summary) {
+		assert.Equal(t, uint64(100), m.Summary.Count)
+	}
+	assert.Len(t, m.Quantiles, 3)
+	assert.InEpsilon(t, 50, m.Quantiles["0.5"], 0.02)
+	assert.InEpsilon(t, 95, m.Quantiles["0.95"], 0.02)
+	assert.InEpsilon(t, 99, m.Quantiles["0.99"], 0.02)
+
+	// квантили можно запросить явно
+	m = metrics.Metrics{}
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeSummary, Quantiles: map[string]float64{"0.9": 0}}).
+		SetResult(&m).
+		Post(ts.URL + "/value/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Len(t, m.Quantiles, 1)
+	assert.InEpsilon(t, 90, m.Quantiles["0.9"], 0.02)
+
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeSummary, Quantiles: map[string]float64{"p99": 0}}).
+		Post(ts.URL + "/value/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	// скетч с другой точностью не объединяется с сохранённым
+	other := metrics.NewSummary(0.05)
+	other.Observe(1)
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "latency", MType: metrics.TypeSummary, Summary: &other}).
+		Post(ts.URL + "/update/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
+
+func TestUpdatesSet(t *testing.T) {
+	st := storage.New()
+	h := New(st)
+	ts := httptest.NewServer(h.router)
+	defer ts.Close()
+
+	// агенты передают скетчи уникальных пользователей, частично пересекающиеся
+	first, second := metrics.NewSet(metrics.DefaultSetPrecision), metrics.NewSet(metrics.DefaultSetPrecision)
+	for _, u := range []string{"alice", "bob", "carol"} {
+		first.Add(u)
+	}
+	for _, u := range []string{"carol", "dave"} {
+		second.Add(u)
+	}
+
+	client := resty.New()
+	resp, err := client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody([]metrics.Metrics{
+			{ID: "users", MType: metrics.TypeSet, Set: &first},
+			{ID: "users", MType: metrics.TypeSet, Set: &second},
+		}).
+		Post(ts.URL + "/updates/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	m := metrics.Metrics{}
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "users", MType: metrics.TypeSet}).
+		SetResult(&m).
+		Post(ts.URL + "/value/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	if assert.NotNil(t, m.Cardinality) {
+		assert.Equal(t, uint64(4), *m.Cardinality)
+	}
+
+	// множество с другой точностью не объединяется с сохранённым
+	other := metrics.NewSet(8)
+	resp, err = client.R().
+		SetHeader("Content-Type", applicationJSON).
+		SetBody(metrics.Metrics{ID: "users", MType: metrics.TypeSet, Set: &other}).
+		Post(ts.URL + "/update/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
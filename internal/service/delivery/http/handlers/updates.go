@@ -39,6 +39,13 @@ func (h *Handler) Updates(w http.ResponseWriter, r *http.Request) {
 
 	prm := metrics.NewProxyMetrics()
 	for _, m := range mcs {
+		key := m.Key()
+		err = metrics.CheckSeriesKey(key)
+		if err != nil {
+			h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		switch m.MType {
 		case "gauge":
 			if m.Value == nil {
@@ -47,14 +54,14 @@ func (h *Handler) Updates(w http.ResponseWriter, r *http.Request) {
 			}
 
 			if h.key != "" && m.Hash != "" {
-				if metrics.GaugeHash(h.key, m.ID, *m.Value) != m.Hash {
+				if metrics.GaugeHash(h.key, key, *m.Value) != m.Hash {
 					err = fmt.Errorf("hash check failed for gauge metric")
 					h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
 					return
 				}
 			}
 
-			prm.Gauges[m.ID] = metrics.Gauge(*m.Value)
+			prm.Gauges[key] = metrics.Gauge(*m.Value)
 		case "counter":
 			if m.Delta == nil {
 				h.errorJSON(w, r, "nil counter value", http.StatusBadRequest)
@@ -62,7 +69,7 @@ func (h *Handler) Updates(w http.ResponseWriter, r *http.Request) {
 			}
 
 			if h.key != "" && m.Hash != "" {
-				if metrics.CounterHash(h.key, m.ID, *m.Delta) != m.Hash {
+				if metrics.CounterHash(h.key, key, *m.Delta) != m.Hash {
 					err = fmt.Errorf("hash check failed for counter metric")
 					h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
 					return
@@ -70,11 +77,104 @@ func (h *Handler) Updates(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// проверяем и суммируем дублирующие значения
-			v, ok := prm.Counters[m.ID]
+			v, ok := prm.Counters[key]
 			if !ok {
-				prm.Counters[m.ID] = metrics.Counter(*m.Delta)
+				prm.Counters[key] = metrics.Counter(*m.Delta)
 			} else {
-				prm.Counters[m.ID] = v + metrics.Counter(*m.Delta)
+				prm.Counters[key] = v + metrics.Counter(*m.Delta)
+			}
+		case metrics.TypeHistogram:
+			if m.Histogram == nil {
+				h.errorJSON(w, r, "nil histogram value", http.StatusBadRequest)
+				return
+			}
+			err = m.Histogram.Validate()
+			if err != nil {
+				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if h.key != "" && m.Hash != "" {
+				if metrics.HistogramHash(h.key, key, *m.Histogram) != m.Hash {
+					err = fmt.Errorf("hash check failed for histogram metric")
+					h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+
+			// объединяем дублирующие гистограммы по корзинам
+			v, ok := prm.Histograms[key]
+			if !ok {
+				prm.Histograms[key] = m.Histogram.Clone()
+			} else {
+				merged, errMerge := v.Merge(*m.Histogram)
+				if errMerge != nil {
+					h.errorJSON(w, r, fmt.Sprintf("%s: %s", key, errMerge), http.StatusBadRequest)
+					return
+				}
+				prm.Histograms[key] = merged
+			}
+		case metrics.TypeSummary:
+			if m.Summary == nil {
+				h.errorJSON(w, r, "nil summary value", http.StatusBadRequest)
+				return
+			}
+			err = m.Summary.Validate()
+			if err != nil {
+				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if h.key != "" && m.Hash != "" {
+				if metrics.SummaryHash(h.key, key, *m.Summary) != m.Hash {
+					err = fmt.Errorf("hash check failed for summary metric")
+					h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+
+			// объединяем дублирующие скетчи
+			v, ok := prm.Summaries[key]
+			if !ok {
+				prm.Summaries[key] = m.Summary.Clone()
+			} else {
+				merged, errMerge := v.Merge(*m.Summary)
+				if errMerge != nil {
+					h.errorJSON(w, r, fmt.Sprintf("%s: %s", key, errMerge), http.StatusBadRequest)
+					return
+				}
+				prm.Summaries[key] = merged
+			}
+		case metrics.TypeSet:
+			if m.Set == nil {
+				h.errorJSON(w, r, "nil set value", http.StatusBadRequest)
+				return
+			}
+			err = m.Set.Validate()
+			if err != nil {
+				h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if h.key != "" && m.Hash != "" {
+				if metrics.SetHash(h.key, key, *m.Set) != m.Hash {
+					err = fmt.Errorf("hash check failed for set metric")
+					h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+
+			// объединяем дублирующие множества
+			v, ok := prm.Sets[key]
+			if !ok {
+				prm.Sets[key] = m.Set.Clone()
+			} else {
+				merged, errMerge := v.Merge(*m.Set)
+				if errMerge != nil {
+					h.errorJSON(w, r, fmt.Sprintf("%s: %s", key, errMerge), http.StatusBadRequest)
+					return
+				}
+				prm.Sets[key] = merged
 			}
 		default:
 			err = fmt.Errorf("not implemented")
@@ -83,9 +183,16 @@ func (h *Handler) Updates(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = h.uc.PutMetrics(prm)
+	// повторно доставленный пакет уже учтён, второй раз его не применяем
+	batchID := r.Header.Get(metrics.HeaderBatchID)
+	applied, err := h.uc.PutBatch(batchID, prm)
 	if err != nil {
 		h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !applied {
+		log.Printf("%s [DEBUG] %s batch %s already accepted, skipping", prefix, url, batchID)
+	}
+
+	h.putAgent(r)
 }
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 )
@@ -37,37 +38,130 @@ func (h *Handler) Value(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = metrics.CheckLabels(m.Labels)
+	if err != nil {
+		h.errorJSON(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := m.Key()
+
 	switch m.MType {
 	case "":
 		h.errorJSON(w, r, "Metric type needed", http.StatusBadRequest)
 		return
 	case "gauge":
-		gauge, errGet := h.uc.Get(m.ID)
+		gauge, errGet := h.uc.Get(key)
 		if errGet != nil {
-			msg := fmt.Sprintf("%s; type: gauge; id: %s", errGet, m.ID)
+			msg := fmt.Sprintf("%s; type: gauge; id: %s", errGet, key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		g, ok := gauge.(metrics.Gauge)
+		if !ok {
+			msg := fmt.Sprintf("metric is not a gauge; id: %s", key)
 			h.errorJSON(w, r, msg, http.StatusNotFound)
 			return
 		}
-		value := float64(gauge.(metrics.Gauge))
+		value := float64(g)
 		m.Value = &value
 
 		// добавим хэш в ответ при наличии ключа
 		if h.key != "" {
-			m.Hash = metrics.GaugeHash(h.key, m.ID, *m.Value)
+			m.Hash = metrics.GaugeHash(h.key, key, *m.Value)
 		}
 	case "counter":
-		counter, errGet := h.uc.Get(m.ID)
+		counter, errGet := h.uc.Get(key)
 		if errGet != nil {
-			msg := fmt.Sprintf("%s; type: counter; id: %s", errGet, m.ID)
+			msg := fmt.Sprintf("%s; type: counter; id: %s", errGet, key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		c, ok := counter.(metrics.Counter)
+		if !ok {
+			msg := fmt.Sprintf("metric is not a counter; id: %s", key)
 			h.errorJSON(w, r, msg, http.StatusNotFound)
 			return
 		}
-		delta := int64(counter.(metrics.Counter))
+		delta := int64(c)
 		m.Delta = &delta
 
 		// добавим хэш в ответ при наличии ключа
 		if h.key != "" {
-			m.Hash = metrics.CounterHash(h.key, m.ID, *m.Delta)
+			m.Hash = metrics.CounterHash(h.key, key, *m.Delta)
+		}
+	case metrics.TypeHistogram:
+		val, errGet := h.uc.Get(key)
+		if errGet != nil {
+			msg := fmt.Sprintf("%s; type: histogram; id: %s", errGet, key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		hist, ok := val.(metrics.Histogram)
+		if !ok {
+			msg := fmt.Sprintf("metric is not a histogram; id: %s", key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		m.Histogram = &hist
+
+		// добавим хэш в ответ при наличии ключа
+		if h.key != "" {
+			m.Hash = metrics.HistogramHash(h.key, key, hist)
+		}
+	case metrics.TypeSummary:
+		// запрошенные квантили передаются ключами Quantiles, по умолчанию возвращаются p50, p95 и p99
+		qs := metrics.DefaultQuantiles
+		if len(m.Quantiles) > 0 {
+			qs = make([]float64, 0, len(m.Quantiles))
+			for k := range m.Quantiles {
+				q, errParse := strconv.ParseFloat(k, 64)
+				if errParse != nil || q < 0 || q > 1 {
+					h.errorJSON(w, r, fmt.Sprintf("bad quantile %q", k), http.StatusBadRequest)
+					return
+				}
+				qs = append(qs, q)
+			}
+		}
+
+		val, errGet := h.uc.Get(key)
+		if errGet != nil {
+			msg := fmt.Sprintf("%s; type: summary; id: %s", errGet, key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		sm, ok := val.(metrics.Summary)
+		if !ok {
+			msg := fmt.Sprintf("metric is not a summary; id: %s", key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		m.Summary = &sm
+		m.Quantiles = sm.Quantiles(qs)
+
+		// добавим хэш в ответ при наличии ключа
+		if h.key != "" {
+			m.Hash = metrics.SummaryHash(h.key, key, sm)
+		}
+	case metrics.TypeSet:
+		val, errGet := h.uc.Get(key)
+		if errGet != nil {
+			msg := fmt.Sprintf("%s; type: set; id: %s", errGet, key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		set, ok := val.(metrics.Set)
+		if !ok {
+			msg := fmt.Sprintf("metric is not a set; id: %s", key)
+			h.errorJSON(w, r, msg, http.StatusNotFound)
+			return
+		}
+		cardinality := set.Estimate()
+		m.Set = &set
+		m.Cardinality = &cardinality
+
+		// добавим хэш в ответ при наличии ключа
+		if h.key != "" {
+			m.Hash = metrics.SetHash(h.key, key, set)
 		}
 	default:
 		h.errorJSON(w, r, "Given metric type not implemented", http.StatusNotImplemented)
@@ -89,6 +183,8 @@ func (h *Handler) Value(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%s [DEBUG] %s responce body: %s", prefix, url, body)
 	case "counter":
 		log.Printf("%s [DEBUG] %s responce body: %s", prefix, url, body)
+	case metrics.TypeHistogram, metrics.TypeSummary, metrics.TypeSet:
+		log.Printf("%s [DEBUG] %s responce body: %s", prefix, url, body)
 	default:
 		log.Printf("%s [DEBUG] %s unknown metrics type", prefix, url)
 	}
@@ -0,0 +1,202 @@
+// Package listener Пакет реализует приём строковых протоколов метрик через TCP и UDP.
+// Принятые строки передаются обработчику; разбор строк выполняют серверы протоколов.
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"sync"
+)
+
+// MaxLine Максимальный размер UDP-пакета и строки, принятой по TCP.
+const MaxLine = 1 << 16
+
+// Handler Обрабатывает одну принятую строку без завершающего перевода строки.
+// Срез действителен только до возврата из обработчика.
+type Handler func(line []byte)
+
+// Listener Хранит данные и объекты для приёма строк по TCP и UDP.
+type Listener struct {
+	name        string
+	handle      Handler
+	tcpAddr     string
+	udpAddr     string
+	tcpListener net.Listener
+	udpConn     net.PacketConn
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+type Option func(l *Listener)
+
+// New Создаёт новый объект типа Listener; name используется в сообщениях журнала.
+func New(name string, handle Handler, opts ...Option) *Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Listener{
+		name:   name,
+		handle: handle,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// WithTCPAddress Использует переданный адрес для приёма строк по TCP.
+func WithTCPAddress(addr string) Option {
+	return func(l *Listener) {
+		l.tcpAddr = addr
+	}
+}
+
+// WithUDPAddress Использует переданный адрес для приёма строк по UDP.
+func WithUDPAddress(addr string) Option {
+	return func(l *Listener) {
+		l.udpAddr = addr
+	}
+}
+
+// Enabled Сообщает, задан ли хотя бы один адрес приёма.
+func (l *Listener) Enabled() bool {
+	return l.tcpAddr != "" || l.udpAddr != ""
+}
+
+// TCPAddr Возвращает открытый TCP-адрес или nil, если приём по TCP не запущен.
+func (l *Listener) TCPAddr() net.Addr {
+	if l.tcpListener == nil {
+		return nil
+	}
+	return l.tcpListener.Addr()
+}
+
+// UDPAddr Возвращает открытый UDP-адрес или nil, если приём по UDP не запущен.
+func (l *Listener) UDPAddr() net.Addr {
+	if l.udpConn == nil {
+		return nil
+	}
+	return l.udpConn.LocalAddr()
+}
+
+// Serve Открывает заданные адреса и запускает приём строк.
+// Если один из адресов открыть не удалось, уже открытые адреса закрываются.
+func (l *Listener) Serve() error {
+	if l.tcpAddr != "" {
+		ln, err := net.Listen("tcp", l.tcpAddr)
+		if err != nil {
+			return err
+		}
+		l.tcpListener = ln
+		log.Printf("[DEBUG] %s TCP listener started at %s\n", l.name, ln.Addr())
+
+		l.wg.Add(1)
+		go l.serveTCP()
+	}
+
+	if l.udpAddr != "" {
+		conn, err := net.ListenPacket("udp", l.udpAddr)
+		if err != nil {
+			l.Shutdown()
+			return err
+		}
+		l.udpConn = conn
+		log.Printf("[DEBUG] %s UDP listener started at %s\n", l.name, conn.LocalAddr())
+
+		l.wg.Add(1)
+		go l.serveUDP()
+	}
+
+	return nil
+}
+
+// Shutdown Закрывает открытые адреса и соединения и дожидается завершения обработки строк.
+func (l *Listener) Shutdown() error {
+	l.cancel()
+	if l.tcpListener != nil {
+		l.tcpListener.Close()
+	}
+	if l.udpConn != nil {
+		l.udpConn.Close()
+	}
+	l.wg.Wait()
+
+	return nil
+}
+
+func (l *Listener) serveTCP() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.tcpListener.Accept()
+		if err != nil {
+			if l.ctx.Err() == nil {
+				log.Printf("[ERROR] %s TCP accept failed - %s\n", l.name, err)
+			}
+			return
+		}
+
+		l.wg.Add(1)
+		go l.serveConn(conn)
+	}
+}
+
+// serveConn Читает строки из TCP-соединения до его закрытия.
+func (l *Listener) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer conn.Close()
+
+	// соединение закрывается при остановке приёма; горутина завершается вместе с соединением
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-l.ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), MaxLine)
+	for scanner.Scan() {
+		l.handleLine(scanner.Bytes())
+	}
+	if err := scanner.Err(); err != nil && l.ctx.Err() == nil {
+		log.Printf("[WARNING] %s TCP read failed - %s\n", l.name, err)
+	}
+}
+
+func (l *Listener) serveUDP() {
+	defer l.wg.Done()
+
+	buf := make([]byte, MaxLine)
+	for {
+		n, _, err := l.udpConn.ReadFrom(buf)
+		if err != nil {
+			if l.ctx.Err() == nil {
+				log.Printf("[ERROR] %s UDP read failed - %s\n", l.name, err)
+			}
+			return
+		}
+
+		// пакет может содержать несколько строк
+		for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+			l.handleLine(line)
+		}
+	}
+}
+
+// handleLine Передаёт обработчику непустую строку без концевых пробелов и символа возврата каретки.
+func (l *Listener) handleLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	l.handle(line)
+}
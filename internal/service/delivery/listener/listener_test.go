@@ -0,0 +1,65 @@
+package listener
+
+import (
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListener(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	l := New("Test", func(line []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, string(line))
+	}, WithTCPAddress("127.0.0.1:0"), WithUDPAddress("127.0.0.1:0"))
+	assert.True(t, l.Enabled())
+	require.NoError(t, l.Serve())
+	defer l.Shutdown()
+
+	received := func(n int) func() bool {
+		return func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(lines) == n
+		}
+	}
+
+	udp, err := net.Dial("udp", l.UDPAddr().String())
+	require.NoError(t, err)
+	defer udp.Close()
+	_, err = udp.Write([]byte("a 1\n\nb 2"))
+	require.NoError(t, err)
+	assert.Eventually(t, received(2), time.Second, 10*time.Millisecond)
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		tcp, err := net.Dial("tcp", l.TCPAddr().String())
+		require.NoError(t, err)
+		_, err = tcp.Write([]byte("c 3\r\n"))
+		require.NoError(t, err)
+		tcp.Close()
+	}
+	assert.Eventually(t, received(12), time.Second, 10*time.Millisecond)
+
+	// горутины закрытых соединений завершаются, не дожидаясь остановки приёма;
+	// assert.Eventually сам запускает горутины, поэтому ожидаем вручную
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+
+	mu.Lock()
+	assert.Equal(t, []string{"a 1", "b 2"}, lines[:2])
+	assert.Equal(t, "c 3", lines[11])
+	mu.Unlock()
+}
@@ -0,0 +1,162 @@
+package statsd
+
+import (
+	"math"
+	"sync"
+
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	sd "github.com/sergeysynergy/metricser/pkg/statsd"
+)
+
+// timer Накопленные за интервал значения таймера.
+type timer struct {
+	name   string
+	labels metrics.Labels
+	count  float64 // Количество значений с учётом доли отправленных значений
+	sum    float64 // Сумма значений с учётом доли отправленных значений
+	lower  float64
+	upper  float64
+}
+
+// aggregator Накапливает метрики StatsD между сбросами в хранилище.
+type aggregator struct {
+	mu       sync.Mutex
+	uc       storage.UseCase
+	counters map[string]float64
+	gauges   map[string]metrics.Gauge
+	deltas   map[string]metrics.Gauge // Относительные изменения gauge, для которых нет накопленного значения
+	timers   map[string]*timer
+	sets     map[string]metrics.Set
+
+	// remainders Дробные части счётчиков, не переданные в хранилище: значения с долей отправленных
+	// значений меньше единицы дают дробные приращения, которые переносятся в следующий интервал.
+	remainders map[string]float64
+}
+
+func newAggregator(uc storage.UseCase) *aggregator {
+	a := &aggregator{uc: uc, remainders: make(map[string]float64)}
+	a.reset()
+
+	return a
+}
+
+func (a *aggregator) reset() {
+	a.counters = make(map[string]float64)
+	a.gauges = make(map[string]metrics.Gauge)
+	a.deltas = make(map[string]metrics.Gauge)
+	a.timers = make(map[string]*timer)
+	a.sets = make(map[string]metrics.Set)
+}
+
+// add Добавляет значение метрики StatsD к накопленным.
+func (a *aggregator) add(m sd.Metric) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := m.Key()
+
+	switch m.Type {
+	case sd.TypeCounter:
+		a.counters[key] += m.Value / m.SampleRate
+	case sd.TypeGauge:
+		if !m.Relative {
+			a.gauges[key] = metrics.Gauge(m.Value)
+			delete(a.deltas, key)
+			return
+		}
+		// относительное изменение применяется к накопленному значению, а без него — накапливается
+		// и применяется к значению из хранилища при сбросе, чтобы не обращаться к хранилищу под блокировкой
+		if current, ok := a.gauges[key]; ok {
+			a.gauges[key] = current + metrics.Gauge(m.Value)
+			return
+		}
+		a.deltas[key] += metrics.Gauge(m.Value)
+	case sd.TypeTimer, sd.TypeHistogram:
+		t, ok := a.timers[key]
+		if !ok {
+			t = &timer{name: m.Name, labels: m.Labels, lower: m.Value, upper: m.Value}
+			a.timers[key] = t
+		}
+		t.count += 1 / m.SampleRate
+		t.sum += m.Value / m.SampleRate
+		t.lower = math.Min(t.lower, m.Value)
+		t.upper = math.Max(t.upper, m.Value)
+	case sd.TypeSet:
+		s, ok := a.sets[key]
+		if !ok {
+			s = metrics.NewSet(metrics.DefaultSetPrecision)
+		}
+		s.Add(m.Raw)
+		a.sets[key] = s
+	}
+}
+
+// flush Возвращает накопленные метрики и начинает новый интервал.
+// Таймеры передаются как счётчик `.count` и значения `.sum`, `.mean`, `.lower`, `.upper`,
+// множества — как метрики `set`, которые хранилище объединяет с накопленными за окно подсчёта.
+func (a *aggregator) flush() *metrics.ProxyMetrics {
+	prm, deltas := a.take()
+
+	for key, d := range deltas {
+		var current metrics.Gauge
+		if v, err := a.uc.Get(key); err == nil {
+			current, _ = v.(metrics.Gauge)
+		}
+		prm.Gauges[key] = current + d
+	}
+
+	return prm
+}
+
+// take Возвращает накопленные метрики и относительные изменения gauge и начинает новый интервал.
+func (a *aggregator) take() (*metrics.ProxyMetrics, map[string]metrics.Gauge) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	prm := metrics.NewProxyMetrics()
+
+	for key, v := range a.counters {
+		prm.Counters[key] = a.carry(key, v)
+	}
+	for key, v := range a.gauges {
+		prm.Gauges[key] = v
+	}
+	for _, t := range a.timers {
+		countKey := metrics.SeriesKey(t.name+".count", t.labels)
+		prm.Counters[countKey] = a.carry(countKey, t.count)
+		prm.Gauges[metrics.SeriesKey(t.name+".sum", t.labels)] = metrics.Gauge(t.sum)
+		prm.Gauges[metrics.SeriesKey(t.name+".mean", t.labels)] = metrics.Gauge(t.sum / t.count)
+		prm.Gauges[metrics.SeriesKey(t.name+".lower", t.labels)] = metrics.Gauge(t.lower)
+		prm.Gauges[metrics.SeriesKey(t.name+".upper", t.labels)] = metrics.Gauge(t.upper)
+	}
+	for key, s := range a.sets {
+		prm.Sets[key] = s
+	}
+	deltas := a.deltas
+
+	a.reset()
+
+	return prm, deltas
+}
+
+// carry Возвращает целую часть приращения счётчика key вместе с дробной частью, перенесённой
+// из прошлых интервалов, и запоминает новую дробную часть. Значения, отличающиеся от целого
+// на погрешность вычислений, округляются до него.
+func (a *aggregator) carry(key string, v float64) metrics.Counter {
+	const epsilon = 1e-9
+
+	total := v + a.remainders[key]
+	whole := math.Round(total)
+	if math.Abs(total-whole) > epsilon {
+		whole = math.Trunc(total)
+	}
+
+	if rest := total - whole; math.Abs(rest) > epsilon {
+		a.remainders[key] = rest
+	} else {
+		delete(a.remainders, key)
+	}
+
+	return metrics.Counter(whole)
+}
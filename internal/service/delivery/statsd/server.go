@@ -0,0 +1,146 @@
+// Package statsd Пакет реализует приём метрик по протоколу StatsD через UDP и TCP.
+// Принятые метрики накапливаются и записываются в хранилище через заданный интервал.
+package statsd
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/service/delivery/listener"
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	sd "github.com/sergeysynergy/metricser/pkg/statsd"
+)
+
+// Server Хранит данные и объекты для приёма метрик StatsD.
+type Server struct {
+	uc            storage.UseCase
+	agg           *aggregator
+	udpAddr       string
+	tcpAddr       string
+	flushInterval time.Duration
+	lines         *listener.Listener
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+type Option func(server *Server)
+
+// New Создаёт новый объект типа Server.
+func New(uc storage.UseCase, opts ...Option) *Server {
+	const (
+		defaultFlushInterval = 10 * time.Second
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		uc:            uc,
+		agg:           newAggregator(uc),
+		flushInterval: defaultFlushInterval,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.lines = listener.New("StatsD", s.handle,
+		listener.WithUDPAddress(s.udpAddr),
+		listener.WithTCPAddress(s.tcpAddr),
+	)
+
+	return s
+}
+
+// WithUDPAddress Использует переданный адрес для приёма метрик по UDP.
+func WithUDPAddress(addr string) Option {
+	return func(s *Server) {
+		s.udpAddr = addr
+	}
+}
+
+// WithTCPAddress Использует переданный адрес для приёма метрик по TCP.
+func WithTCPAddress(addr string) Option {
+	return func(s *Server) {
+		s.tcpAddr = addr
+	}
+}
+
+// WithFlushInterval Использует переданный интервал записи накопленных метрик в хранилище.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		if interval > 0 {
+			s.flushInterval = interval
+		}
+	}
+}
+
+// Enabled Сообщает, задан ли хотя бы один адрес приёма метрик.
+func (s *Server) Enabled() bool {
+	return s.lines.Enabled()
+}
+
+// Serve Открывает заданные адреса и запускает приём метрик.
+func (s *Server) Serve() error {
+	err := s.lines.Serve()
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.flushTicker()
+
+	return nil
+}
+
+// Shutdown Останавливает приём метрик и записывает накопленные значения в хранилище.
+func (s *Server) Shutdown() error {
+	s.cancel()
+	s.lines.Shutdown()
+	s.wg.Wait()
+
+	s.flush()
+	log.Println("[DEBUG] Gracefully shutdown StatsD listener")
+
+	return nil
+}
+
+// handle Разбирает строку и добавляет метрику к накопленным значениям.
+func (s *Server) handle(line []byte) {
+	m, err := sd.ParseLine(string(line))
+	if err != nil {
+		log.Println("[WARNING] Bad StatsD metric -", err)
+		return
+	}
+	s.agg.add(m)
+}
+
+func (s *Server) flushTicker() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// flush Записывает накопленные метрики в хранилище.
+func (s *Server) flush() {
+	prm := s.agg.flush()
+	if prm.Empty() {
+		return
+	}
+
+	err := s.uc.PutMetrics(prm)
+	if err != nil {
+		log.Println("[ERROR] Failed to put StatsD metrics -", err)
+	}
+}
@@ -0,0 +1,128 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	sd "github.com/sergeysynergy/metricser/pkg/statsd"
+)
+
+func TestAggregatorFlush(t *testing.T) {
+	uc := storage.New()
+	err := uc.Put("queue", metrics.Gauge(10))
+	assert.NoError(t, err)
+
+	agg := newAggregator(uc)
+	lines := "hits:1|c\nhits:1|c|@0.1\nqueue:-3|g\nqueue:+1|g\ntemp:20|g\ntemp:21|g\n" +
+		"db:10|ms\ndb:30|ms|#table:users\ndb:20|ms\nlat:10|ms|@0.5\nusers:alice|s\nusers:bob|s\nusers:alice|s\n"
+	ms, errs := sd.Parse([]byte(lines))
+	assert.Empty(t, errs)
+	for _, m := range ms {
+		agg.add(m)
+	}
+
+	prm := agg.flush()
+	assert.Equal(t, metrics.Counter(11), prm.Counters["hits"])
+	assert.Equal(t, metrics.Gauge(8), prm.Gauges["queue"])
+	assert.Equal(t, metrics.Gauge(21), prm.Gauges["temp"])
+	assert.Equal(t, metrics.Counter(2), prm.Counters["db.count"])
+	assert.Equal(t, metrics.Gauge(15), prm.Gauges["db.mean"])
+	assert.Equal(t, metrics.Gauge(10), prm.Gauges["db.lower"])
+	assert.Equal(t, metrics.Gauge(20), prm.Gauges["db.upper"])
+	assert.Equal(t, metrics.Gauge(30), prm.Gauges["db.sum"])
+	assert.Equal(t, metrics.Gauge(30), prm.Gauges[metrics.SeriesKey("db.mean", metrics.Labels{"table": "users"})])
+	// сумма таймера учитывает долю отправленных значений
+	assert.Equal(t, metrics.Counter(2), prm.Counters["lat.count"])
+	assert.Equal(t, metrics.Gauge(20), prm.Gauges["lat.sum"])
+	assert.Equal(t, metrics.Gauge(10), prm.Gauges["lat.mean"])
+	assert.Equal(t, uint64(2), prm.Sets["users"].Estimate())
+
+	// после сброса начинается новый интервал
+	prm = agg.flush()
+	assert.Empty(t, prm.Gauges)
+	assert.Empty(t, prm.Counters)
+	assert.Empty(t, prm.Sets)
+}
+
+func TestAggregatorCarry(t *testing.T) {
+	agg := newAggregator(storage.New())
+
+	// при доле 0.4 каждое значение даёт 2.5: дробная часть переносится в следующий интервал
+	want := []metrics.Counter{2, 3, 2, 3}
+	for _, w := range want {
+		ms, errs := sd.Parse([]byte("frac:1|c|@0.4\nlat:5|ms|@0.4\n"))
+		assert.Empty(t, errs)
+		for _, m := range ms {
+			agg.add(m)
+		}
+
+		prm := agg.flush()
+		assert.Equal(t, w, prm.Counters["frac"])
+		assert.Equal(t, w, prm.Counters["lat.count"])
+	}
+}
+
+func TestServer(t *testing.T) {
+	uc := storage.New()
+	s := New(uc,
+		WithUDPAddress("127.0.0.1:0"),
+		WithTCPAddress("127.0.0.1:0"),
+		WithFlushInterval(time.Hour),
+	)
+	assert.True(t, s.Enabled())
+
+	err := s.Serve()
+	assert.NoError(t, err)
+
+	udp, err := net.Dial("udp", s.lines.UDPAddr().String())
+	assert.NoError(t, err)
+	defer udp.Close()
+	_, err = udp.Write([]byte("hits:2|c\nbad\n"))
+	assert.NoError(t, err)
+
+	tcp, err := net.Dial("tcp", s.lines.TCPAddr().String())
+	assert.NoError(t, err)
+	_, err = tcp.Write([]byte("hits:3|c\ntemp:5|g\n"))
+	assert.NoError(t, err)
+	tcp.Close()
+
+	// дождёмся приёма обоих пакетов
+	assert.Eventually(t, func() bool {
+		s.agg.mu.Lock()
+		defer s.agg.mu.Unlock()
+		return s.agg.counters["hits"] == 5 && s.agg.gauges["temp"] == 5
+	}, time.Second, 10*time.Millisecond)
+
+	// при остановке накопленные метрики записываются в хранилище
+	err = s.Shutdown()
+	assert.NoError(t, err)
+
+	value, err := uc.Get("hits")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(5), value)
+	value, err = uc.Get("temp")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(5), value)
+}
+
+func TestServerFlushSets(t *testing.T) {
+	uc := storage.New()
+	s := New(uc)
+
+	// интервал только с множествами тоже записывается в хранилище
+	ms, errs := sd.Parse([]byte("users:alice|s\nusers:bob|s\n"))
+	assert.Empty(t, errs)
+	for _, m := range ms {
+		s.agg.add(m)
+	}
+	s.flush()
+
+	value, err := uc.Get("users")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), value.(metrics.Set).Estimate())
+}
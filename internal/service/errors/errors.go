@@ -13,4 +13,5 @@ const (
 	ErrEmptyFilestoreName  AppError = "empty filestore name"
 	ErrFileStoreNotDefined AppError = "filestore not defined"
 	ErrEmptyProxyMetrics   AppError = "empty proxy metrics values"
+	ErrTypeConflict        AppError = "metric already stored with another type"
 )
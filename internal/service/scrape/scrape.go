@@ -0,0 +1,188 @@
+// Package scrape Пакет реализует опрос сервером источников метрик в текстовом формате Prometheus.
+// Принятые значения записываются в хранилище вместе с метриками состояния опроса.
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	"github.com/sergeysynergy/metricser/pkg/prometheus"
+)
+
+// Метрики состояния опроса, записываемые для каждой цели. Имена начинаются с `scrape_`,
+// чтобы не совпадать с сериями, которые отдают сами цели.
+const (
+	MetricUp             = "scrape_up"
+	MetricDuration       = "scrape_duration_seconds"
+	MetricSamplesScraped = "scrape_samples_scraped"
+
+	labelJob      = "job"
+	labelInstance = "instance"
+
+	acceptHeader = "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+
+	// maxBody Максимальный размер ответа цели.
+	maxBody = 32 << 20
+)
+
+// Scheduler Периодически опрашивает заданные цели.
+type Scheduler struct {
+	uc       storage.UseCase
+	targets  []Target
+	interval time.Duration
+	timeout  time.Duration
+	prepared []*target
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+type Option func(s *Scheduler)
+
+// New Создаёт новый объект типа Scheduler.
+func New(uc storage.UseCase, targets []Target, opts ...Option) *Scheduler {
+	const (
+		defaultInterval = 15 * time.Second
+		defaultTimeout  = 10 * time.Second
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		uc:       uc,
+		targets:  targets,
+		interval: defaultInterval,
+		timeout:  defaultTimeout,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// WithInterval Использует переданный интервал опроса для целей, у которых он не задан.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		if interval > 0 {
+			s.interval = interval
+		}
+	}
+}
+
+// WithTimeout Использует переданный таймаут опроса для целей, у которых он не задан.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Scheduler) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+	}
+}
+
+// Enabled Сообщает, задана ли хотя бы одна цель опроса.
+func (s *Scheduler) Enabled() bool {
+	return len(s.targets) > 0
+}
+
+// Serve Проверяет настройки целей и запускает их опрос.
+func (s *Scheduler) Serve() error {
+	for _, t := range s.targets {
+		tg, err := newTarget(t, s.interval, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.prepared = append(s.prepared, tg)
+	}
+
+	for _, tg := range s.prepared {
+		s.wg.Add(1)
+		go s.scrapeTicker(tg)
+		log.Printf("[DEBUG] Scraping %s every %s\n", tg.URL, tg.Interval)
+	}
+
+	return nil
+}
+
+// Shutdown Останавливает опрос целей и дожидается завершения текущих запросов.
+func (s *Scheduler) Shutdown() error {
+	s.cancel()
+	s.wg.Wait()
+	log.Println("[DEBUG] Gracefully shutdown scrape scheduler")
+
+	return nil
+}
+
+func (s *Scheduler) scrapeTicker(tg *target) {
+	defer s.wg.Done()
+
+	mp := prometheus.NewMapper()
+	ticker := time.NewTicker(tg.Interval)
+	defer ticker.Stop()
+	for {
+		s.scrape(tg, mp)
+
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// scrape Опрашивает цель и записывает полученные значения и состояние опроса в хранилище.
+func (s *Scheduler) scrape(tg *target, mp *prometheus.Mapper) {
+	start := time.Now()
+	series, err := s.fetch(tg)
+	duration := time.Since(start)
+
+	prm := metrics.NewProxyMetrics()
+	up := metrics.Gauge(1)
+	if err != nil {
+		if s.ctx.Err() != nil {
+			return
+		}
+		log.Printf("[WARNING] Failed to scrape %s - %s\n", tg.URL, err)
+		up = 0
+	} else {
+		prm = mp.Map(series, tg.labels)
+	}
+
+	prm.Gauges[metrics.SeriesKey(MetricUp, tg.labels)] = up
+	prm.Gauges[metrics.SeriesKey(MetricDuration, tg.labels)] = metrics.Gauge(duration.Seconds())
+	prm.Gauges[metrics.SeriesKey(MetricSamplesScraped, tg.labels)] = metrics.Gauge(len(series))
+
+	err = s.uc.PutMetrics(prm)
+	if err != nil {
+		log.Printf("[ERROR] Failed to put metrics scraped from %s - %s\n", tg.URL, err)
+	}
+}
+
+func (s *Scheduler) fetch(tg *target) ([]prometheus.Series, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, tg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", acceptHeader)
+	if tg.username != "" || tg.password != "" {
+		req.SetBasicAuth(tg.username, tg.password)
+	}
+
+	resp, err := tg.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return prometheus.Parse(http.MaxBytesReader(nil, resp.Body, maxBody))
+}
@@ -0,0 +1,137 @@
+package scrape
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestScheduler(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "prom" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("# TYPE requests_total counter\nrequests_total 7\nqueue 3\nup 5\n"))
+	})
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: secure.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, ca, 0600))
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("secret\n"), 0600))
+
+	uc := storage.New()
+	s := New(uc, []Target{
+		{
+			Job:    "plain",
+			URL:    plain.URL + "/metrics",
+			Labels: metrics.Labels{"env": "test"},
+			Auth:   &BasicAuth{Username: "prom", Password: "secret"},
+		},
+		{
+			Job:  "secure",
+			URL:  secure.URL + "/metrics",
+			Auth: &BasicAuth{Username: "prom", PasswordFile: passwordFile},
+			TLS:  TLSConfig{CAFile: caFile},
+		},
+		{
+			Job: "unauthorized",
+			URL: plain.URL + "/metrics",
+		},
+	}, WithInterval(time.Hour), WithTimeout(time.Second))
+	assert.True(t, s.Enabled())
+	require.NoError(t, s.Serve())
+
+	labels := func(job, rawURL string, extra metrics.Labels) metrics.Labels {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		l := metrics.Labels{labelJob: job, labelInstance: u.Host}
+		for k, v := range extra {
+			l[k] = v
+		}
+		return l
+	}
+	plainLabels := labels("plain", plain.URL, metrics.Labels{"env": "test"})
+	secureLabels := labels("secure", secure.URL, nil)
+	unauthorizedLabels := labels("unauthorized", plain.URL, nil)
+
+	// каждая цель опрашивается сразу после запуска
+	assert.Eventually(t, func() bool {
+		_, err := uc.Get(metrics.SeriesKey(MetricUp, unauthorizedLabels))
+		_, errPlain := uc.Get(metrics.SeriesKey(MetricUp, plainLabels))
+		_, errSecure := uc.Get(metrics.SeriesKey(MetricUp, secureLabels))
+		return err == nil && errPlain == nil && errSecure == nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, s.Shutdown())
+
+	for _, l := range []metrics.Labels{plainLabels, secureLabels} {
+		value, err := uc.Get(metrics.SeriesKey(MetricUp, l))
+		assert.NoError(t, err)
+		assert.Equal(t, metrics.Gauge(1), value)
+		value, err = uc.Get(metrics.SeriesKey(MetricSamplesScraped, l))
+		assert.NoError(t, err)
+		assert.Equal(t, metrics.Gauge(3), value)
+		// серия цели не совпадает с метрикой состояния опроса
+		value, err = uc.Get(metrics.SeriesKey("up", l))
+		assert.NoError(t, err)
+		assert.Equal(t, metrics.Gauge(5), value)
+		// первое накопительное значение цели только запоминается
+		value, err = uc.Get(metrics.SeriesKey("requests_total", l))
+		assert.NoError(t, err)
+		assert.Equal(t, metrics.Counter(0), value)
+		value, err = uc.Get(metrics.SeriesKey("queue", l))
+		assert.NoError(t, err)
+		assert.Equal(t, metrics.Gauge(3), value)
+	}
+
+	value, err := uc.Get(metrics.SeriesKey(MetricUp, unauthorizedLabels))
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(0), value)
+	_, err = uc.Get(metrics.SeriesKey(MetricDuration, unauthorizedLabels))
+	assert.NoError(t, err)
+}
+
+func TestSchedulerBadTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		target Target
+	}{
+		{
+			name:   "Bad scheme",
+			target: Target{URL: "ftp://localhost/metrics"},
+		},
+		{
+			name:   "Missing CA file",
+			target: Target{URL: "https://localhost/metrics", TLS: TLSConfig{CAFile: "/nonexistent/ca.pem"}},
+		},
+		{
+			name:   "Bad label",
+			target: Target{URL: "http://localhost/metrics", Labels: metrics.Labels{"bad-label": "x"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(storage.New(), []Target{tt.target})
+			assert.Error(t, s.Serve())
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package scrape
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Target Источник метрик в текстовом формате Prometheus, опрашиваемый сервером.
+type Target struct {
+	Job      string
+	URL      string
+	Interval time.Duration // 0 — интервал планировщика по умолчанию
+	Timeout  time.Duration // 0 — таймаут планировщика по умолчанию
+	Labels   metrics.Labels
+	Auth     *BasicAuth
+	TLS      TLSConfig
+}
+
+// BasicAuth Учётные данные базовой аутентификации HTTP.
+// Пароль из файла PasswordFile имеет приоритет над Password.
+type BasicAuth struct {
+	Username     string
+	Password     string
+	PasswordFile string
+}
+
+// TLSConfig Настройки TLS-соединения с источником.
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// build Создаёт конфигурацию TLS; возвращает nil, если настройки не заданы.
+func (c TLSConfig) build() (*tls.Config, error) {
+	if c == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		ca, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// target Подготовленная к опросу цель.
+type target struct {
+	Target
+	client   *http.Client
+	username string
+	password string
+	labels   metrics.Labels // метки цели вместе с job и instance
+}
+
+func newTarget(t Target, interval, timeout time.Duration) (*target, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return nil, fmt.Errorf("bad scrape target url %q - %w", t.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("bad scrape target url %q", t.URL)
+	}
+
+	if t.Interval <= 0 {
+		t.Interval = interval
+	}
+	if t.Timeout <= 0 {
+		t.Timeout = timeout
+	}
+	if t.Timeout > t.Interval {
+		t.Timeout = t.Interval
+	}
+
+	tlsConfig, err := t.TLS.build()
+	if err != nil {
+		return nil, fmt.Errorf("bad TLS config of scrape target %q - %w", t.URL, err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	tg := &target{
+		Target: t,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   t.Timeout,
+		},
+		labels: make(metrics.Labels, len(t.Labels)+2),
+	}
+
+	if t.Auth != nil {
+		tg.username = t.Auth.Username
+		tg.password = t.Auth.Password
+		if t.Auth.PasswordFile != "" {
+			b, errFile := os.ReadFile(t.Auth.PasswordFile)
+			if errFile != nil {
+				return nil, fmt.Errorf("failed to read password of scrape target %q - %w", t.URL, errFile)
+			}
+			tg.password = strings.TrimSpace(string(b))
+		}
+	}
+
+	tg.labels[labelInstance] = u.Host
+	if t.Job != "" {
+		tg.labels[labelJob] = t.Job
+	}
+	for k, v := range t.Labels {
+		tg.labels[k] = v
+	}
+	if err = metrics.CheckLabels(tg.labels); err != nil {
+		return nil, fmt.Errorf("bad labels of scrape target %q - %w", t.URL, err)
+	}
+
+	return tg, nil
+}
@@ -12,10 +12,15 @@ import (
 
 	"github.com/sergeysynergy/metricser/config"
 	serviceConst "github.com/sergeysynergy/metricser/internal/service/consts"
+	serviceGraphite "github.com/sergeysynergy/metricser/internal/service/delivery/graphite"
 	serviceGRPC "github.com/sergeysynergy/metricser/internal/service/delivery/grpc"
 	serviceHTTP "github.com/sergeysynergy/metricser/internal/service/delivery/http"
 	"github.com/sergeysynergy/metricser/internal/service/delivery/http/handlers"
+	serviceStatsD "github.com/sergeysynergy/metricser/internal/service/delivery/statsd"
+	"github.com/sergeysynergy/metricser/internal/service/scrape"
 	"github.com/sergeysynergy/metricser/internal/service/storage"
+	"github.com/sergeysynergy/metricser/pkg/graphite"
+	"github.com/sergeysynergy/metricser/pkg/influx"
 	pb "github.com/sergeysynergy/metricser/proto"
 )
 
@@ -25,6 +30,9 @@ type Service struct {
 	uc         storage.UseCase
 	httpServer *serviceHTTP.Server
 	grpcServer *grpc.Server
+	statsd     *serviceStatsD.Server
+	graphite   *serviceGraphite.Server
+	scrape     *scrape.Scheduler
 }
 
 func New(cfg *config.ServerConf, uc storage.UseCase) *Service {
@@ -41,6 +49,72 @@ func New(cfg *config.ServerConf, uc storage.UseCase) *Service {
 func (s *Service) init() {
 	s.initHTTPServer()
 	s.initGRPCServer()
+	s.initStatsD()
+	s.initGraphite()
+	s.initScrape()
+}
+
+func (s *Service) initStatsD() {
+	s.statsd = serviceStatsD.New(s.uc,
+		serviceStatsD.WithUDPAddress(s.cfg.StatsDAddr),
+		serviceStatsD.WithTCPAddress(s.cfg.StatsDTCPAddr),
+		serviceStatsD.WithFlushInterval(s.cfg.StatsDFlush),
+	)
+}
+
+func (s *Service) initGraphite() {
+	rules := make([]graphite.Rule, 0, len(s.cfg.GraphiteRules))
+	for _, r := range s.cfg.GraphiteRules {
+		rule, err := graphite.ParseRule(r)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rules = append(rules, rule)
+	}
+	mapper, err := graphite.NewMapper(rules)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	s.graphite = serviceGraphite.New(s.uc,
+		serviceGraphite.WithTCPAddress(s.cfg.GraphiteAddr),
+		serviceGraphite.WithUDPAddress(s.cfg.GraphiteUDPAddr),
+		serviceGraphite.WithFlushInterval(s.cfg.GraphiteFlush),
+		serviceGraphite.WithMapper(mapper),
+	)
+}
+
+func (s *Service) initScrape() {
+	targets := make([]scrape.Target, 0, len(s.cfg.ScrapeTargets))
+	for _, t := range s.cfg.ScrapeTargets {
+		target := scrape.Target{
+			Job:      t.Job,
+			URL:      t.URL,
+			Interval: t.Interval.Duration,
+			Timeout:  t.Timeout.Duration,
+			Labels:   t.Labels,
+			TLS: scrape.TLSConfig{
+				CAFile:             t.TLS.CAFile,
+				CertFile:           t.TLS.CertFile,
+				KeyFile:            t.TLS.KeyFile,
+				ServerName:         t.TLS.ServerName,
+				InsecureSkipVerify: t.TLS.InsecureSkipVerify,
+			},
+		}
+		if t.BasicAuth != nil {
+			target.Auth = &scrape.BasicAuth{
+				Username:     t.BasicAuth.Username,
+				Password:     t.BasicAuth.Password,
+				PasswordFile: t.BasicAuth.PasswordFile,
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	s.scrape = scrape.New(s.uc, targets,
+		scrape.WithInterval(s.cfg.ScrapeInterval),
+		scrape.WithTimeout(s.cfg.ScrapeTimeout),
+	)
 }
 
 func (s *Service) initGRPCServer() {
@@ -61,6 +135,10 @@ func (s *Service) initHTTPServer() {
 		handlers.WithKey(s.cfg.Key),
 		handlers.WithPrivateKey(s.cfg.PrivateKey),
 		handlers.WithTrustedSubnet(s.cfg.TrustedSubnet),
+		handlers.WithInfluxMapping(influx.Mapping{
+			Template: s.cfg.InfluxTemplate,
+			Counters: s.cfg.InfluxCounters,
+		}),
 	)
 
 	s.httpServer = serviceHTTP.New(s.uc, h.GetRouter(),
@@ -87,6 +165,26 @@ func (s *Service) runGraceDown() {
 		}
 	}()
 
+	// остановим опрос целей и запишем накопленные метрики StatsD и Graphite до завершения работы хранилища
+	if s.scrape.Enabled() {
+		err := s.scrape.Shutdown()
+		if err != nil {
+			log.Fatal("[ERROR] Scrape scheduler shutdown error - ", err)
+		}
+	}
+	if s.statsd.Enabled() {
+		err := s.statsd.Shutdown()
+		if err != nil {
+			log.Fatal("[ERROR] StatsD shutdown error - ", err)
+		}
+	}
+	if s.graphite.Enabled() {
+		err := s.graphite.Shutdown()
+		if err != nil {
+			log.Fatal("[ERROR] Graphite shutdown error - ", err)
+		}
+	}
+
 	// штатно завершим работу файлового хранилища и БД
 	err := s.uc.Shutdown()
 	if err != nil {
@@ -129,5 +227,29 @@ func (s *Service) Run() {
 	go s.httpServer.Serve() // запускаем http-сервер
 	s.startGRPCServer()     // запускаем gRPC-сервер
 
+	// запускаем приём метрик StatsD, если заданы адреса
+	if s.statsd.Enabled() {
+		err := s.statsd.Serve()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// запускаем приём метрик Graphite, если заданы адреса
+	if s.graphite.Enabled() {
+		err := s.graphite.Serve()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// запускаем опрос источников метрик Prometheus, если заданы цели
+	if s.scrape.Enabled() {
+		err := s.scrape.Serve()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	s.runGraceDown()
 }
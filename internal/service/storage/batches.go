@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// maxBatches Количество запоминаемых идентификаторов принятых пакетов метрик.
+const maxBatches = 10000
+
+// PutBatch Записывает в хранилище пакет метрик с заданным идентификатором, если пакет ещё не был принят,
+// и запоминает его идентификатор. Агент повторяет отправку пакета, если не получил ответ сервера,
+// поэтому повторно доставленный пакет не должен изменять значения счётчиков.
+// Проверка и запись выполняются под одной блокировкой: одновременно доставленные копии пакета
+// не могут быть применены обе. Возвращает false, если пакет уже был принят.
+func (s *Storage) PutBatch(id string, prm *metrics.ProxyMetrics) (bool, error) {
+	if id == "" {
+		return true, s.PutMetrics(prm)
+	}
+
+	s.batchesMu.Lock()
+	defer s.batchesMu.Unlock()
+
+	if _, ok := s.batches[id]; ok {
+		return false, nil
+	}
+
+	err := s.PutMetrics(prm)
+	if err != nil {
+		return false, err
+	}
+
+	s.acceptBatch(id)
+	err = s.writeBatch(id)
+	if err != nil {
+		log.Println("[ERROR] Failed to save accepted batch ID -", err)
+	}
+
+	return true, nil
+}
+
+// acceptBatch Запоминает идентификатор принятого пакета; самые старые идентификаторы вытесняются.
+// Вызывается под блокировкой batchesMu.
+func (s *Storage) acceptBatch(id string) {
+	if _, ok := s.batches[id]; ok {
+		return
+	}
+
+	if len(s.batchesOrder) >= maxBatches {
+		delete(s.batches, s.batchesOrder[0])
+		s.batchesOrder = s.batchesOrder[1:]
+	}
+	s.batches[id] = struct{}{}
+	s.batchesOrder = append(s.batchesOrder, id)
+}
+
+// loadBatches Восстанавливает идентификаторы принятых пакетов из файла, чтобы после перезапуска сервера
+// повторно доставленные пакеты по-прежнему отбрасывались.
+func (s *Storage) loadBatches() error {
+	f, err := os.Open(s.batchFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			s.acceptBatch(id)
+			s.batchLines++
+		}
+	}
+
+	return scanner.Err()
+}
+
+// writeBatch Дописывает идентификатор принятого пакета в файл. Когда файл вдвое превышает
+// число запоминаемых идентификаторов, он перезаписывается актуальным списком.
+// Вызывается под блокировкой batchesMu.
+func (s *Storage) writeBatch(id string) error {
+	if s.batchFile == "" {
+		return nil
+	}
+
+	if s.batchLines >= 2*maxBatches {
+		return s.rewriteBatches()
+	}
+
+	f, err := os.OpenFile(s.batchFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, id)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	s.batchLines++
+
+	return nil
+}
+
+// rewriteBatches Атомарно заменяет файл принятых пакетов списком запоминаемых идентификаторов.
+func (s *Storage) rewriteBatches() error {
+	tmp := s.batchFile + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, id := range s.batchesOrder {
+		fmt.Fprintln(w, id)
+	}
+	err = w.Flush()
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.batchFile)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.batchLines = len(s.batchesOrder)
+
+	return nil
+}
+
+// initBatchFile Создаёт каталог файла принятых пакетов и загружает из него идентификаторы.
+func (s *Storage) initBatchFile() {
+	if s.batchFile == "" {
+		return
+	}
+
+	err := os.MkdirAll(filepath.Dir(s.batchFile), 0755)
+	if err == nil {
+		err = s.loadBatches()
+	}
+	if err != nil {
+		log.Printf("[WARNING] Failed to load accepted batch IDs from %s - %s\n", s.batchFile, err)
+	}
+}
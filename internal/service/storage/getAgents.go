@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// GetAgents Возвращает сведения обо всех агентах, присылавших отчёты, упорядоченные по идентификатору.
+func (s *Storage) GetAgents() ([]metrics.AgentInfo, error) {
+	s.agentsMu.RLock()
+	agents := make([]metrics.AgentInfo, 0, len(s.agents))
+	for _, info := range s.agents {
+		agents = append(agents, info)
+	}
+	s.agentsMu.RUnlock()
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+
+	return agents, nil
+}
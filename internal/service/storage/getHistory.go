@@ -0,0 +1,12 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// GetHistory Извлекает отсчёты метрики с заданным ID за промежуток времени [from, to].
+func (s *Storage) GetHistory(id string, from, to time.Time) ([]metrics.Sample, error) {
+	return s.repo.GetHistory(id, from, to)
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 )
 
@@ -10,6 +12,12 @@ type UseCase interface {
 
 	SnapShotCreate() error
 	WriteTicker() error
+
+	PutAgent(metrics.AgentInfo) error
+	GetAgents() ([]metrics.AgentInfo, error)
+
+	// PutBatch Записывает пакет метрик, если пакет с таким идентификатором ещё не был принят.
+	PutBatch(id string, prm *metrics.ProxyMetrics) (bool, error)
 }
 
 type Repo interface {
@@ -22,10 +30,33 @@ type Repo interface {
 	PutMetrics(*metrics.ProxyMetrics) error
 	GetMetrics() (*metrics.ProxyMetrics, error)
 
+	// PutSamples Записывает отсчёты с их метками времени в историю и обновляет текущие значения метрик.
+	PutSamples([]metrics.Sample) error
+
+	// GetHistory Возвращает отсчёты метрики с заданным ID за промежуток времени [from, to].
+	GetHistory(id string, from, to time.Time) ([]metrics.Sample, error)
+	// PruneHistory Удаляет отсчёты истории с метками времени раньше before.
+	PruneHistory(before time.Time) error
+
 	Restore(*metrics.ProxyMetrics) error
+
+	// ResetSets Удаляет значения всех метрик типа `set`, начиная новое окно подсчёта уникальных значений.
+	ResetSets() error
 }
 
 type FileRepo interface {
 	JustWriteMetrics(*metrics.ProxyMetrics) error
 	JustReadMetrics() (*metrics.ProxyMetrics, error)
+
+	// JustWriteHistory Перезаписывает сохранённую историю отсчётов метрик.
+	JustWriteHistory([]metrics.Sample) error
+	// JustReadHistory Извлекает сохранённую историю отсчётов метрик.
+	JustReadHistory() ([]metrics.Sample, error)
+}
+
+// historyRepo Репозиторий, который хранит историю отсчётов только в памяти: при записи снимка
+// на диск история сохраняется в файловое хранилище и восстанавливается из него при запуске.
+type historyRepo interface {
+	History() []metrics.Sample
+	RestoreHistory([]metrics.Sample)
 }
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	serviceErrors "github.com/sergeysynergy/metricser/internal/service/errors"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func (s *Storage) JustReadHistory() ([]metrics.Sample, error) {
+	if s.fileRepo == nil {
+		return nil, serviceErrors.ErrFileStoreNotDefined
+	}
+
+	return s.fileRepo.JustReadHistory()
+}
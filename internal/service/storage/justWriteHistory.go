@@ -0,0 +1,14 @@
+package storage
+
+import "github.com/sergeysynergy/metricser/pkg/metrics"
+
+func (s *Storage) JustWriteHistory(samples []metrics.Sample) (err error) {
+	if s.fileRepo != nil {
+		err = s.fileRepo.JustWriteHistory(samples)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"log"
+	"time"
+)
+
+// PruneHistory Удаляет отсчёты истории с метками времени раньше before.
+func (s *Storage) PruneHistory(before time.Time) error {
+	return s.repo.PruneHistory(before)
+}
+
+// pruneHistoryTicker Периодически удаляет отсчёты старше срока хранения истории.
+func (s *Storage) pruneHistoryTicker() {
+	const maxPruneInterval = time.Minute
+
+	interval := s.historyRetention
+	if interval > maxPruneInterval {
+		interval = maxPruneInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := s.PruneHistory(time.Now().Add(-s.historyRetention))
+			if err != nil {
+				log.Println("[ERROR] Failed to prune metrics history -", err)
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
@@ -1,6 +1,15 @@
 package storage
 
+import (
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
 // Put Записывает значение метрики в хранилище Storage для заданного ID.
 func (s *Storage) Put(id string, metric interface{}) error {
+	err := metrics.CheckSeriesKey(id)
+	if err != nil {
+		return err
+	}
+
 	return s.repo.Put(id, metric)
 }
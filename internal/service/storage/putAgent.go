@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// PutAgent Запоминает сведения об агенте, приславшем отчёт, и время его последнего обращения.
+func (s *Storage) PutAgent(info metrics.AgentInfo) error {
+	if info.ID == "" {
+		return fmt.Errorf("empty agent ID")
+	}
+
+	if info.LastSeen.IsZero() {
+		info.LastSeen = time.Now()
+	}
+
+	s.agentsMu.Lock()
+	s.agents[info.ID] = info
+	s.agentsMu.Unlock()
+
+	return nil
+}
@@ -7,9 +7,9 @@ import (
 
 // PutMetrics Массово записывает значение метрик в хранилище Storage.
 func (s *Storage) PutMetrics(prm *metrics.ProxyMetrics) error {
-	if len(prm.Gauges) == 0 && len(prm.Counters) == 0 {
+	if prm.Empty() {
 		return serviceErrors.ErrEmptyProxyMetrics
 	}
 
-	return s.repo.PutMetrics(prm)
+	return s.repo.PutMetrics(dropBadKeys(prm))
 }
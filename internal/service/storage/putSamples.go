@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// PutSamples Записывает в хранилище отсчёты с метками времени источника.
+func (s *Storage) PutSamples(samples []metrics.Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	return s.repo.PutSamples(dropBadSampleKeys(samples))
+}
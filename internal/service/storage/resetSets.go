@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"log"
+	"time"
+)
+
+// ResetSets Удаляет значения всех метрик типа `set`, начиная новое окно подсчёта уникальных значений.
+// Снимок в файле перезаписывается сразу, иначе при восстановлении вернутся множества прошлого окна.
+func (s *Storage) ResetSets() error {
+	err := s.repo.ResetSets()
+	if err != nil {
+		return err
+	}
+	if s.fileRepo == nil {
+		return nil
+	}
+
+	return s.SnapShotCreate()
+}
+
+// resetSetsTicker Сбрасывает множества на границах окон длительностью setResetInterval,
+// выровненных по времени: при окне в час — в начале каждого часа.
+func (s *Storage) resetSetsTicker() {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(s.setResetInterval).Add(s.setResetInterval).Sub(now))
+
+		select {
+		case <-timer.C:
+			err := s.ResetSets()
+			if err != nil {
+				log.Println("[ERROR] Failed to reset set metrics -", err)
+			}
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -14,5 +14,14 @@ func (s *Storage) SnapShotCreate() (err error) {
 		return err
 	}
 
-	return s.fileRepo.JustWriteMetrics(prm)
+	err = s.fileRepo.JustWriteMetrics(prm)
+	if err != nil {
+		return err
+	}
+
+	if hr, ok := s.repo.(historyRepo); ok {
+		return s.fileRepo.JustWriteHistory(hr.History())
+	}
+
+	return nil
 }
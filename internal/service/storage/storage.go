@@ -7,6 +7,7 @@ import (
 	"github.com/sergeysynergy/metricser/internal/service/data/repository/memory"
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 	"log"
+	"sync"
 	"time"
 )
 
@@ -19,6 +20,18 @@ type Storage struct {
 	cancel        context.CancelFunc
 	restore       bool
 	storeInterval time.Duration // Интервал периодического сохранения метрик на диск, 0 — делает запись синхронной.
+
+	setResetInterval time.Duration // Окно подсчёта уникальных значений метрик `set`, 0 — множества не сбрасываются.
+	historyRetention time.Duration // Срок хранения истории отсчётов, 0 — история не удаляется.
+
+	agentsMu sync.RWMutex
+	agents   map[string]metrics.AgentInfo // Агенты, присылавшие отчёты, по идентификатору
+
+	batchesMu    sync.Mutex
+	batches      map[string]struct{} // Идентификаторы принятых пакетов метрик
+	batchesOrder []string
+	batchFile    string // Файл идентификаторов принятых пакетов, пустое значение — идентификаторы хранятся только в памяти
+	batchLines   int    // Количество строк в файле идентификаторов принятых пакетов
 }
 
 type Option func(storage *Storage)
@@ -35,11 +48,23 @@ func New(opts ...Option) *Storage {
 		repo:          memory.New(),
 		fileRepo:      nil,
 		storeInterval: defaultStoreInterval,
+		agents:        make(map[string]metrics.AgentInfo),
+		batches:       make(map[string]struct{}),
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	s.initBatchFile()
+	s.init()
+
+	if s.setResetInterval > 0 {
+		go s.resetSetsTicker()
+	}
+	if s.historyRetention > 0 {
+		go s.pruneHistoryTicker()
+	}
+
 	return s
 }
 
@@ -88,7 +113,8 @@ func WithCounters(counters map[string]metrics.Counter) Option {
 	}
 }
 
-// WithRestore Определяет флаг, нужно ли восстанавливать при запуске значения метрик из файла.
+// WithRestore Определяет флаг, нужно ли восстанавливать при запуске значения метрик из файла;
+// для репозиториев, сохраняющих метрики между запусками, флаг не задают.
 func WithRestore(restore bool) Option {
 	return func(s *Storage) {
 		s.restore = restore
@@ -102,6 +128,36 @@ func WithStoreInterval(interval time.Duration) Option {
 	}
 }
 
+// WithSetResetInterval Определяет окно подсчёта уникальных значений: в начале каждого окна
+// значения метрик типа `set` сбрасываются.
+func WithSetResetInterval(interval time.Duration) Option {
+	return func(s *Storage) {
+		if interval > 0 {
+			s.setResetInterval = interval
+		}
+	}
+}
+
+// WithHistoryRetention Определяет срок хранения истории отсчётов: более старые отсчёты
+// периодически удаляются из репозитория.
+func WithHistoryRetention(retention time.Duration) Option {
+	return func(s *Storage) {
+		if retention > 0 {
+			s.historyRetention = retention
+		}
+	}
+}
+
+// WithBatchFile Определяет файл, в котором сохраняются идентификаторы принятых пакетов метрик.
+func WithBatchFile(filename string) Option {
+	return func(s *Storage) {
+		s.batchFile = filename
+	}
+}
+
+// init Восстанавливает значения метрик из файла, если задан флаг restore. Флаг задают только для
+// хранения метрик в памяти: базы данных и журнал сохраняют метрики сами, и восстановление
+// повторно добавило бы к ним приращения счётчиков.
 func (s *Storage) init() {
 	if !s.restore {
 		return
@@ -1,9 +1,15 @@
 package storage
 
 import (
+	"github.com/sergeysynergy/metricser/internal/service/data/repository/filestore"
+	"github.com/sergeysynergy/metricser/internal/service/data/repository/memory"
 	serviceErrors "github.com/sergeysynergy/metricser/internal/service/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sergeysynergy/metricser/pkg/metrics"
 )
@@ -161,6 +167,9 @@ func TestStoragePutGetMetrics(t *testing.T) {
 				Counters: map[string]metrics.Counter{
 					"PollCount": 42,
 				},
+				Histograms: map[string]metrics.Histogram{},
+				Summaries:  map[string]metrics.Summary{},
+				Sets:       map[string]metrics.Set{},
 			},
 			want: want{
 				get: &metrics.ProxyMetrics{
@@ -186,6 +195,7 @@ func TestStoragePutGetMetrics(t *testing.T) {
 					Counters: map[string]metrics.Counter{
 						"PollCount": 42,
 					},
+					Histograms: map[string]metrics.Histogram{},
 				},
 			},
 		},
@@ -203,3 +213,273 @@ func TestStoragePutGetMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestStorageGetHistory(t *testing.T) {
+	s := New(WithDBStorer(memory.New(memory.WithHistory(0))))
+	from := time.Now()
+
+	err := s.Put(metrics.Alloc, metrics.Gauge(1))
+	assert.NoError(t, err)
+	err = s.Put(metrics.Alloc, metrics.Gauge(2))
+	assert.NoError(t, err)
+	err = s.PutMetrics(&metrics.ProxyMetrics{
+		Gauges:   map[string]metrics.Gauge{metrics.Alloc: 3},
+		Counters: map[string]metrics.Counter{metrics.PollCount: 5},
+	})
+	assert.NoError(t, err)
+
+	samples, err := s.GetHistory(metrics.Alloc, from, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, samples, 3)
+	for k, v := range []float64{1, 2, 3} {
+		assert.Equal(t, metrics.TypeGauge, samples[k].MType)
+		assert.Equal(t, v, samples[k].Value)
+	}
+
+	samples, err = s.GetHistory(metrics.PollCount, from, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, samples, 1)
+	assert.Equal(t, int64(5), samples[0].Delta)
+
+	samples, err = s.GetHistory(metrics.Alloc, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, samples)
+}
+
+func TestStoragePutSamples(t *testing.T) {
+	s := New(WithDBStorer(memory.New(memory.WithHistory(time.Hour))))
+	now := time.Now()
+
+	err := s.PutSamples([]metrics.Sample{
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: 2, Timestamp: now},
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: 1, Timestamp: now.Add(-time.Minute)},
+		{ID: metrics.PollCount, MType: metrics.TypeCounter, Delta: 3, Timestamp: now.Add(-time.Minute)},
+		{ID: metrics.PollCount, MType: metrics.TypeCounter, Delta: 4, Timestamp: now},
+	})
+	assert.NoError(t, err)
+
+	// отсчёт старше срока хранения истории не сохраняется
+	err = s.PutSamples([]metrics.Sample{
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: 0, Timestamp: now.Add(-2 * time.Hour)},
+	})
+	assert.NoError(t, err)
+
+	// запоздавший отсчёт попадает в историю, но не заменяет более новое значение
+	err = s.PutSamples([]metrics.Sample{
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: 5, Timestamp: now.Add(-30 * time.Second)},
+	})
+	assert.NoError(t, err)
+
+	value, err := s.Get(metrics.Alloc)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Gauge(2), value)
+	value, err = s.Get(metrics.PollCount)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(7), value)
+
+	samples, err := s.GetHistory(metrics.Alloc, now.Add(-3*time.Hour), now)
+	assert.NoError(t, err)
+	assert.Len(t, samples, 3)
+	for k, v := range []float64{1, 5, 2} {
+		assert.Equal(t, v, samples[k].Value)
+	}
+}
+
+func TestStoragePruneHistory(t *testing.T) {
+	s := New(
+		WithDBStorer(memory.New(memory.WithHistory(0))),
+		WithHistoryRetention(50*time.Millisecond),
+	)
+	defer s.Shutdown()
+	now := time.Now()
+
+	err := s.PutSamples([]metrics.Sample{
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: 1, Timestamp: now.Add(-time.Hour)},
+		{ID: metrics.PollCount, MType: metrics.TypeCounter, Delta: 1, Timestamp: now.Add(-time.Hour)},
+		{ID: metrics.Alloc, MType: metrics.TypeGauge, Value: 2, Timestamp: now.Add(time.Hour)},
+	})
+	assert.NoError(t, err)
+
+	// устаревшие отсчёты удаляются, текущие значения метрик сохраняются
+	assert.Eventually(t, func() bool {
+		samples, errHistory := s.GetHistory(metrics.Alloc, time.Time{}, now.Add(2*time.Hour))
+		return errHistory == nil && len(samples) == 1 && samples[0].Value == 2
+	}, time.Second, 10*time.Millisecond)
+
+	samples, err := s.GetHistory(metrics.PollCount, time.Time{}, now)
+	assert.NoError(t, err)
+	assert.Empty(t, samples)
+
+	value, err := s.Get(metrics.PollCount)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Counter(1), value)
+}
+
+func TestStorageSetReset(t *testing.T) {
+	s := New(WithSetResetInterval(50 * time.Millisecond))
+	defer s.Shutdown()
+
+	set := metrics.NewSet(metrics.DefaultSetPrecision)
+	set.Add("alice")
+	err := s.PutMetrics(&metrics.ProxyMetrics{
+		Gauges: map[string]metrics.Gauge{"Alloc": 1},
+		Sets:   map[string]metrics.Set{"users": set},
+	})
+	assert.NoError(t, err)
+
+	v, err := s.Get("users")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), v.(metrics.Set).Estimate())
+
+	// в начале нового окна множества сбрасываются, остальные метрики сохраняются
+	assert.Eventually(t, func() bool {
+		_, errGet := s.Get("users")
+		return errGet != nil
+	}, time.Second, 10*time.Millisecond)
+	_, err = s.Get("Alloc")
+	assert.NoError(t, err)
+}
+
+func TestStorageSetResetSnapshot(t *testing.T) {
+	fr := filestore.New(filestore.WithStoreFile(filepath.Join(t.TempDir(), "metrics.json")))
+	s := New(WithFileStorer(fr))
+	defer s.Shutdown()
+
+	set := metrics.NewSet(metrics.DefaultSetPrecision)
+	set.Add("alice")
+	err := s.PutMetrics(&metrics.ProxyMetrics{
+		Gauges: map[string]metrics.Gauge{"Alloc": 1},
+		Sets:   map[string]metrics.Set{"users": set},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.SnapShotCreate())
+
+	// после сброса снимок не содержит множеств прошлого окна
+	require.NoError(t, s.ResetSets())
+	prm, err := fr.JustReadMetrics()
+	require.NoError(t, err)
+	assert.Empty(t, prm.Sets)
+	assert.Equal(t, metrics.Gauge(1), prm.Gauges["Alloc"])
+}
+
+func TestStorageRestoreFromFile(t *testing.T) {
+	fr := filestore.New(filestore.WithStoreFile(filepath.Join(t.TempDir(), "metrics.json")))
+	err := fr.JustWriteMetrics(&metrics.ProxyMetrics{
+		Gauges:   map[string]metrics.Gauge{"Alloc": 1},
+		Counters: map[string]metrics.Counter{"PollCount": 5},
+	})
+	require.NoError(t, err)
+
+	s := New(WithFileStorer(fr), WithRestore(true))
+	defer s.Shutdown()
+	value, err := s.Get("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Counter(5), value)
+
+	// без флага значения из файла не восстанавливаются
+	s = New(WithFileStorer(fr))
+	defer s.Shutdown()
+	_, err = s.Get("PollCount")
+	assert.Error(t, err)
+}
+
+func TestStorageTypeConflict(t *testing.T) {
+	s := New()
+	defer s.Shutdown()
+
+	require.NoError(t, s.Put("Alloc", metrics.Gauge(1)))
+	require.NoError(t, s.Put("PollCount", metrics.Counter(1)))
+
+	// значение другого типа отменяет весь пакет
+	err := s.PutMetrics(&metrics.ProxyMetrics{
+		Gauges:     map[string]metrics.Gauge{"Free": 5},
+		Histograms: map[string]metrics.Histogram{"Alloc": metrics.NewHistogram(nil)},
+	})
+	assert.ErrorIs(t, err, serviceErrors.ErrTypeConflict)
+	err = s.PutMetrics(&metrics.ProxyMetrics{
+		Gauges:   map[string]metrics.Gauge{"Total": 5},
+		Counters: map[string]metrics.Counter{"Total": 5},
+	})
+	assert.ErrorIs(t, err, serviceErrors.ErrTypeConflict)
+	assert.ErrorIs(t, s.Put("PollCount", metrics.Gauge(2)), serviceErrors.ErrTypeConflict)
+	assert.ErrorIs(t, s.Put("Alloc", metrics.NewSet(metrics.DefaultSetPrecision)), serviceErrors.ErrTypeConflict)
+	err = s.PutSamples([]metrics.Sample{{ID: "Alloc", MType: metrics.TypeCounter, Delta: 1, Timestamp: time.Now()}})
+	assert.ErrorIs(t, err, serviceErrors.ErrTypeConflict)
+
+	prm, err := s.GetMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]metrics.Gauge{"Alloc": 1}, prm.Gauges)
+	assert.Equal(t, map[string]metrics.Counter{"PollCount": 1}, prm.Counters)
+	assert.Empty(t, prm.Histograms)
+	assert.Empty(t, prm.Sets)
+
+	// ключ отклонённого пакета не закрепляется за типом
+	require.NoError(t, s.Put("Free", metrics.Counter(1)))
+}
+
+func TestStorageRestoreHistory(t *testing.T) {
+	fr := filestore.New(filestore.WithStoreFile(filepath.Join(t.TempDir(), "metrics.json")))
+	ts := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	s := New(WithDBStorer(memory.New(memory.WithHistory(0))), WithFileStorer(fr))
+	err := s.PutSamples([]metrics.Sample{
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: 1, Timestamp: ts},
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: 2, Timestamp: ts.Add(time.Second)},
+	})
+	require.NoError(t, err)
+	err = s.SnapShotCreate()
+	require.NoError(t, err)
+	s.Shutdown()
+
+	// после перезапуска история восстанавливается из файла вместе со значениями метрик
+	s = New(WithDBStorer(memory.New(memory.WithHistory(0))), WithFileStorer(fr), WithRestore(true))
+	defer s.Shutdown()
+	samples, err := s.GetHistory("Alloc", ts, ts.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Equal(t, 1.0, samples[0].Value)
+	assert.Equal(t, 2.0, samples[1].Value)
+	assert.True(t, ts.Equal(samples[0].Timestamp))
+}
+
+func TestStoragePutBatch(t *testing.T) {
+	batchFile := filepath.Join(t.TempDir(), "batches")
+	s := New(WithBatchFile(batchFile))
+
+	prm := metrics.NewProxyMetrics()
+	prm.Counters[metrics.PollCount] = 2
+
+	// одновременно доставленные копии пакета применяются один раз
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	applied := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.PutBatch("agent-1", prm)
+			assert.NoError(t, err)
+			if ok {
+				mu.Lock()
+				applied++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, applied)
+
+	value, err := s.Get(metrics.PollCount)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Counter(2), value)
+
+	// принятые идентификаторы сохраняются после перезапуска
+	s = New(WithBatchFile(batchFile))
+	ok, err := s.PutBatch("agent-1", prm)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = s.PutBatch("agent-2", prm)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
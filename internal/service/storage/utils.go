@@ -2,6 +2,7 @@ package storage
 
 import (
 	serviceErrors "github.com/sergeysynergy/metricser/internal/service/errors"
+	"github.com/sergeysynergy/metricser/pkg/metrics"
 	"log"
 )
 
@@ -20,6 +21,88 @@ func (s *Storage) snapShotRestore() (err error) {
 		return err
 	}
 
+	if hr, ok := s.repo.(historyRepo); ok {
+		samples, errHistory := s.fileRepo.JustReadHistory()
+		if errHistory != nil {
+			log.Printf("[WARNING] Failed to restore metrics history from filestore - %s\n", errHistory)
+		} else {
+			hr.RestoreHistory(samples)
+		}
+	}
+
 	log.Printf("[DEBUG] Metrics has been restored from filestore")
 	return nil
 }
+
+// dropBadKeys Возвращает значения метрик без серий, ключи которых не разбираются на имя и метки:
+// такие серии нельзя отдать в формате Prometheus. Если все ключи допустимы, возвращается prm.
+func dropBadKeys(prm *metrics.ProxyMetrics) *metrics.ProxyMetrics {
+	bad := make(map[string]bool)
+	check := func(key string) {
+		if err := metrics.CheckSeriesKey(key); err != nil {
+			log.Println("[WARNING] Dropping metric with bad series key -", err)
+			bad[key] = true
+		}
+	}
+	for key := range prm.Gauges {
+		check(key)
+	}
+	for key := range prm.Counters {
+		check(key)
+	}
+	for key := range prm.Histograms {
+		check(key)
+	}
+	for key := range prm.Summaries {
+		check(key)
+	}
+	for key := range prm.Sets {
+		check(key)
+	}
+	if len(bad) == 0 {
+		return prm
+	}
+
+	clean := metrics.NewProxyMetrics()
+	for key, v := range prm.Gauges {
+		if !bad[key] {
+			clean.Gauges[key] = v
+		}
+	}
+	for key, v := range prm.Counters {
+		if !bad[key] {
+			clean.Counters[key] = v
+		}
+	}
+	for key, v := range prm.Histograms {
+		if !bad[key] {
+			clean.Histograms[key] = v
+		}
+	}
+	for key, v := range prm.Summaries {
+		if !bad[key] {
+			clean.Summaries[key] = v
+		}
+	}
+	for key, v := range prm.Sets {
+		if !bad[key] {
+			clean.Sets[key] = v
+		}
+	}
+
+	return clean
+}
+
+// dropBadSampleKeys Возвращает отсчёты без серий с недопустимыми ключами, см. dropBadKeys.
+func dropBadSampleKeys(samples []metrics.Sample) []metrics.Sample {
+	clean := samples[:0:0]
+	for _, sample := range samples {
+		if err := metrics.CheckSeriesKey(sample.ID); err != nil {
+			log.Println("[WARNING] Dropping sample with bad series key -", err)
+			continue
+		}
+		clean = append(clean, sample)
+	}
+
+	return clean
+}
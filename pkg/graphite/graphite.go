@@ -0,0 +1,46 @@
+// Package graphite Пакет реализует разбор строкового протокола Graphite (plaintext): `path value timestamp`,
+// и преобразование путей в ID метрики и метки по правилам.
+package graphite
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point Отдельное значение метрики Graphite.
+type Point struct {
+	Path  string
+	Value float64
+	Time  time.Time // Нулевое значение, если время не указано или равно -1
+}
+
+// ParseLine Разбирает строку вида `path value [timestamp]`.
+func ParseLine(line string) (Point, error) {
+	p := Point{}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return p, fmt.Errorf("bad graphite line %q: expected `path value timestamp`", line)
+	}
+	p.Path = fields[0]
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return p, fmt.Errorf("bad graphite line %q: invalid value", line)
+	}
+	p.Value = value
+
+	if len(fields) == 3 && fields[2] != "-1" {
+		ts, errTS := strconv.ParseFloat(fields[2], 64)
+		if errTS != nil {
+			return p, fmt.Errorf("bad graphite line %q: invalid timestamp", line)
+		}
+		sec, frac := math.Modf(ts)
+		p.Time = time.Unix(int64(sec), int64(frac*1e9))
+	}
+
+	return p, nil
+}
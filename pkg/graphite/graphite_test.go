@@ -0,0 +1,121 @@
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Point
+		wantErr bool
+	}{
+		{
+			name: "With timestamp",
+			line: "servers.web01.load 0.75 1600000000",
+			want: Point{Path: "servers.web01.load", Value: 0.75, Time: time.Unix(1600000000, 0)},
+		},
+		{
+			name: "Without timestamp",
+			line: "servers.web01.load 1",
+			want: Point{Path: "servers.web01.load", Value: 1},
+		},
+		{
+			name: "Timestamp -1",
+			line: "servers.web01.load 1 -1",
+			want: Point{Path: "servers.web01.load", Value: 1},
+		},
+		{
+			name:    "Bad value",
+			line:    "servers.web01.load abc 1600000000",
+			wantErr: true,
+		},
+		{
+			name:    "Missing value",
+			line:    "servers.web01.load",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseLine(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestMapper(t *testing.T) {
+	rules := make([]Rule, 0)
+	for _, s := range []string{
+		"collectd.* _.host.measurement*",
+		"servers.*.*.load host.dc._.measurement",
+		"apps.* _.app.env.measurement.measurement",
+	} {
+		r, err := ParseRule(s)
+		assert.NoError(t, err)
+		rules = append(rules, r)
+	}
+
+	mp, err := NewMapper(rules)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path   string
+		id     string
+		labels metrics.Labels
+	}{
+		{
+			path:   "collectd.web01.cpu-0.cpu-idle",
+			id:     "cpu-0.cpu-idle",
+			labels: metrics.Labels{"host": "web01"},
+		},
+		{
+			path:   "servers.eu.web02.load",
+			id:     "load",
+			labels: metrics.Labels{"host": "servers", "dc": "eu"},
+		},
+		{
+			path:   "apps.shop.prod.orders.count",
+			id:     "orders.count",
+			labels: metrics.Labels{"app": "shop", "env": "prod"},
+		},
+		{
+			path: "other.metric",
+			id:   "other.metric",
+		},
+		{
+			// узлов больше, чем назначений в шаблоне без `*`: правило не подходит
+			path: "servers.eu.web02.load.shortterm",
+			id:   "servers.eu.web02.load.shortterm",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			id, labels := mp.Map(tt.path)
+			assert.Equal(t, tt.id, id)
+			if tt.labels == nil {
+				assert.Empty(t, labels)
+			} else {
+				assert.Equal(t, tt.labels, labels)
+			}
+		})
+	}
+
+	_, err = ParseRule("only-filter")
+	assert.Error(t, err)
+	_, err = NewMapper([]Rule{{Filter: "a.*", Template: "measurement*.host"}})
+	assert.Error(t, err)
+	_, err = NewMapper([]Rule{{Filter: "a.[", Template: "measurement"}})
+	assert.Error(t, err)
+}
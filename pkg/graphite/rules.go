@@ -0,0 +1,148 @@
+package graphite
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	tokenMeasurement = "measurement"
+	tokenSkip        = "_"
+	greedySuffix     = "*"
+	idSeparator      = "."
+)
+
+// Rule Правило преобразования пути Graphite в ID метрики и метки.
+//
+// Filter — шаблон начала пути, узлы которого сравниваются по правилам path.Match: `collectd.*.cpu-*`.
+// Template — назначения узлов пути через точку: measurement добавляет узел в ID, `_` пропускает узел,
+// любое другое имя сохраняет узел в метку с этим именем. Суффикс `*` у последнего назначения
+// относит к нему все оставшиеся узлы. Например, шаблон `_.host.measurement*` для пути
+// `collectd.web01.cpu-0.idle` даёт ID `cpu-0.idle` и метку host="web01".
+// Шаблон без суффикса `*` подходит только путям, в которых узлов не больше, чем назначений:
+// иначе разные пути слились бы в одну метрику.
+type Rule struct {
+	Filter   string
+	Template string
+}
+
+// ParseRule Разбирает правило вида `filter template`.
+func ParseRule(s string) (Rule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Rule{}, fmt.Errorf("bad graphite rule %q: expected `filter template`", s)
+	}
+
+	return Rule{Filter: fields[0], Template: fields[1]}, nil
+}
+
+type rule struct {
+	filter []string
+	tokens []string
+	greedy bool
+}
+
+// Mapper Преобразует пути Graphite по первому подходящему правилу.
+// Путь, не подходящий ни под одно правило, целиком становится ID метрики.
+type Mapper struct {
+	rules []rule
+}
+
+func NewMapper(rules []Rule) (*Mapper, error) {
+	mp := &Mapper{}
+
+	for _, r := range rules {
+		filter := strings.Split(r.Filter, ".")
+		for _, node := range filter {
+			if _, err := path.Match(node, ""); err != nil {
+				return nil, fmt.Errorf("bad graphite filter %q: %w", r.Filter, err)
+			}
+		}
+
+		tokens := strings.Split(r.Template, ".")
+		greedy := false
+		for k, t := range tokens {
+			if !strings.HasSuffix(t, greedySuffix) {
+				continue
+			}
+			if k != len(tokens)-1 {
+				return nil, fmt.Errorf("bad graphite template %q: only the last node may end with *", r.Template)
+			}
+			tokens[k] = strings.TrimSuffix(t, greedySuffix)
+			greedy = true
+		}
+
+		mp.rules = append(mp.rules, rule{filter: filter, tokens: tokens, greedy: greedy})
+	}
+
+	return mp, nil
+}
+
+// Map Возвращает ID метрики и метки для пути p.
+func (mp *Mapper) Map(p string) (string, metrics.Labels) {
+	nodes := strings.Split(p, ".")
+
+	for _, r := range mp.rules {
+		if !r.match(nodes) {
+			continue
+		}
+		return r.apply(nodes)
+	}
+
+	return p, nil
+}
+
+func (r rule) match(nodes []string) bool {
+	if len(r.filter) > len(nodes) {
+		return false
+	}
+	// лишние узлы пути не должны отбрасываться молча
+	if !r.greedy && len(nodes) > len(r.tokens) {
+		return false
+	}
+
+	for k, f := range r.filter {
+		if ok, _ := path.Match(f, nodes[k]); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r rule) apply(nodes []string) (string, metrics.Labels) {
+	var id []string
+	labels := make(metrics.Labels)
+
+	assign := func(token, value string) {
+		switch token {
+		case tokenSkip, "":
+		case tokenMeasurement:
+			id = append(id, value)
+		default:
+			if labels[token] != "" {
+				value = labels[token] + idSeparator + value
+			}
+			labels[token] = value
+		}
+	}
+
+	for k, node := range nodes {
+		switch {
+		case k < len(r.tokens):
+			assign(r.tokens[k], node)
+		case r.greedy:
+			assign(r.tokens[len(r.tokens)-1], node)
+		}
+	}
+
+	// без узлов ID берётся исходный путь
+	if len(id) == 0 {
+		return strings.Join(nodes, idSeparator), labels
+	}
+
+	return strings.Join(id, idSeparator), labels
+}
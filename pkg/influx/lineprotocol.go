@@ -0,0 +1,198 @@
+// Package influx Пакет реализует разбор строкового протокола InfluxDB (line protocol)
+// и преобразование точек в метрики сервиса.
+package influx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point Точка строкового протокола InfluxDB.
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{} // Значения типов float64, int64, uint64, bool или string
+	Time        time.Time              // Нулевое значение, если время не указано
+}
+
+// Precision Возвращает единицу времени по значению параметра precision: ns, us, ms или s.
+func Precision(s string) (time.Duration, error) {
+	switch s {
+	case "", "ns", "n":
+		return time.Nanosecond, nil
+	case "us", "u":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	}
+
+	return 0, fmt.Errorf("unknown precision %q", s)
+}
+
+// Parse Разбирает строки протокола; время точек задано в единицах precision.
+// Пустые строки и комментарии пропускаются.
+func Parse(data []byte, precision time.Duration) ([]Point, error) {
+	var points []Point
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := ParseLine(line, precision)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		points = append(points, p)
+	}
+
+	return points, scanner.Err()
+}
+
+// ParseLine Разбирает строку вида `measurement,tag=value field=value timestamp`.
+func ParseLine(line string, precision time.Duration) (Point, error) {
+	p := Point{
+		Tags:   make(map[string]string),
+		Fields: make(map[string]interface{}),
+	}
+
+	keyEnd := indexUnescaped(line, ' ', false)
+	if keyEnd < 0 {
+		return p, fmt.Errorf("missing fields")
+	}
+	key := line[:keyEnd]
+	rest := strings.TrimLeft(line[keyEnd+1:], " ")
+
+	fieldsEnd := indexUnescaped(rest, ' ', true)
+	fields := rest
+	ts := ""
+	if fieldsEnd >= 0 {
+		fields = rest[:fieldsEnd]
+		ts = strings.TrimSpace(rest[fieldsEnd+1:])
+	}
+
+	parts := splitUnescaped(key, ',', false)
+	p.Measurement = unescape(parts[0])
+	if p.Measurement == "" {
+		return p, fmt.Errorf("missing measurement")
+	}
+	for _, tag := range parts[1:] {
+		eq := indexUnescaped(tag, '=', false)
+		if eq <= 0 {
+			return p, fmt.Errorf("bad tag %q", tag)
+		}
+		p.Tags[unescape(tag[:eq])] = unescape(tag[eq+1:])
+	}
+
+	for _, field := range splitUnescaped(fields, ',', true) {
+		eq := indexUnescaped(field, '=', false)
+		if eq <= 0 {
+			return p, fmt.Errorf("bad field %q", field)
+		}
+		value, err := parseFieldValue(field[eq+1:])
+		if err != nil {
+			return p, fmt.Errorf("field %q: %w", field[:eq], err)
+		}
+		p.Fields[unescape(field[:eq])] = value
+	}
+	if len(p.Fields) == 0 {
+		return p, fmt.Errorf("missing fields")
+	}
+
+	if ts != "" {
+		n, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return p, fmt.Errorf("bad timestamp %q", ts)
+		}
+		p.Time = time.Unix(0, n*int64(precision))
+	}
+
+	return p, nil
+}
+
+func parseFieldValue(s string) (interface{}, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	switch {
+	case s[0] == '"':
+		if len(s) < 2 || s[len(s)-1] != '"' {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		r := strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+		return r.Replace(s[1 : len(s)-1]), nil
+	case s[len(s)-1] == 'i':
+		return strconv.ParseInt(s[:len(s)-1], 10, 64)
+	case s[len(s)-1] == 'u':
+		return strconv.ParseUint(s[:len(s)-1], 10, 64)
+	}
+
+	switch s {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+// indexUnescaped Возвращает позицию первого неэкранированного символа c;
+// при quoted символы внутри строк в кавычках пропускаются.
+func indexUnescaped(s string, c byte, quoted bool) int {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quoted && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == c && !inQuotes:
+			return i
+		}
+	}
+
+	return -1
+}
+
+func splitUnescaped(s string, c byte, quoted bool) []string {
+	var parts []string
+	for {
+		i := indexUnescaped(s, c, quoted)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// unescape Убирает экранирование запятых, пробелов, знаков равенства и обратной косой черты.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case ',', ' ', '=', '\\', '"':
+				i++
+			}
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
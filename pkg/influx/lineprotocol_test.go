@@ -0,0 +1,86 @@
+package influx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Point
+		wantErr bool
+	}{
+		{
+			name: "Full line",
+			line: `cpu,host=server01,region=us-west usage_idle=64.2,usage_user=3i,online=t 1434055562000000000`,
+			want: Point{
+				Measurement: "cpu",
+				Tags:        map[string]string{"host": "server01", "region": "us-west"},
+				Fields:      map[string]interface{}{"usage_idle": 64.2, "usage_user": int64(3), "online": true},
+				Time:        time.Unix(0, 1434055562000000000),
+			},
+		},
+		{
+			name: "Escapes and quoted string",
+			line: `disk\ io,path=/mnt/my\ disk,dev\=x=a\,b bytes=10u,msg="hello, \"world\" x=1"`,
+			want: Point{
+				Measurement: "disk io",
+				Tags:        map[string]string{"path": "/mnt/my disk", "dev=x": "a,b"},
+				Fields:      map[string]interface{}{"bytes": uint64(10), "msg": `hello, "world" x=1`},
+			},
+		},
+		{
+			name:    "Missing fields",
+			line:    "cpu,host=a",
+			wantErr: true,
+		},
+		{
+			name:    "Bad field value",
+			line:    "cpu value=abc",
+			wantErr: true,
+		},
+		{
+			name:    "Bad timestamp",
+			line:    "cpu value=1 yesterday",
+			wantErr: true,
+		},
+		{
+			name:    "Bad tag",
+			line:    "cpu,host value=1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseLine(tt.line, time.Nanosecond)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	precision, err := Precision("s")
+	assert.NoError(t, err)
+
+	points, err := Parse([]byte("# comment\ncpu value=1 1600000000\n\nmem free=2i\n"), precision)
+	assert.NoError(t, err)
+	if assert.Len(t, points, 2) {
+		assert.Equal(t, time.Unix(1600000000, 0), points[0].Time)
+		assert.True(t, points[1].Time.IsZero())
+	}
+
+	_, err = Parse([]byte("cpu value=1\ncpu\n"), precision)
+	assert.EqualError(t, err, "line 2: missing fields")
+
+	_, err = Precision("h")
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package influx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	// DefaultTemplate Шаблон ID метрики по умолчанию.
+	DefaultTemplate = "{measurement}_{field}"
+
+	placeholderMeasurement = "{measurement}"
+	placeholderField       = "{field}"
+)
+
+// Mapping Правила преобразования точек InfluxDB в метрики сервиса.
+type Mapping struct {
+	// Template Шаблон ID метрики с подстановками {measurement} и {field}.
+	Template string
+	// Counters Регулярные выражения ID метрик, значения которых являются накопительными счётчиками;
+	// остальные поля сохраняются как gauge.
+	Counters []string
+}
+
+// Mapper Преобразует точки InfluxDB в метрики сервиса.
+// Накопительные значения счётчиков преобразуются в приращения.
+type Mapper struct {
+	template string
+	matcher  func(id string) bool
+	tracker  *metrics.CounterTracker
+}
+
+// NewMapper Создаёт преобразователь точек по правилам m.
+func NewMapper(m Mapping) (*Mapper, error) {
+	if m.Template == "" {
+		m.Template = DefaultTemplate
+	}
+	if !strings.Contains(m.Template, placeholderField) && !strings.Contains(m.Template, placeholderMeasurement) {
+		return nil, fmt.Errorf("influx template %q has no placeholders", m.Template)
+	}
+
+	matcher, err := newMatcher(m.Counters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mapper{
+		template: m.Template,
+		matcher:  matcher,
+		tracker:  metrics.NewCounterTracker(),
+	}, nil
+}
+
+// ID Возвращает ID метрики для поля field измерения measurement.
+func (mp *Mapper) ID(measurement, field string) string {
+	r := strings.NewReplacer(placeholderMeasurement, measurement, placeholderField, field)
+	return r.Replace(mp.template)
+}
+
+// Map Преобразует точки в отсчёты метрик с временем точки; точки без времени получают время now.
+// Теги становятся метками, строковые поля пропускаются, логические поля сохраняются как gauge
+// со значениями 0 и 1.
+func (mp *Mapper) Map(points []Point, now time.Time) []metrics.Sample {
+	samples := make([]metrics.Sample, 0, len(points))
+
+	for _, p := range points {
+		ts := p.Time
+		if ts.IsZero() {
+			ts = now
+		}
+
+		labels := make(metrics.Labels, len(p.Tags))
+		for k, v := range p.Tags {
+			labels[metrics.SanitizeLabel(k)] = v
+		}
+
+		for field, raw := range p.Fields {
+			var value float64
+			switch v := raw.(type) {
+			case float64:
+				value = v
+			case int64:
+				value = float64(v)
+			case uint64:
+				value = float64(v)
+			case bool:
+				if v {
+					value = 1
+				}
+			default:
+				continue
+			}
+
+			id := mp.ID(p.Measurement, field)
+			key := metrics.SeriesKey(id, labels)
+			if _, isBool := raw.(bool); !isBool && mp.matcher(id) {
+				samples = append(samples, metrics.Sample{
+					ID:        key,
+					MType:     metrics.TypeCounter,
+					Delta:     int64(mp.tracker.Delta(key, value)),
+					Timestamp: ts,
+				})
+				continue
+			}
+			samples = append(samples, metrics.Sample{ID: key, MType: metrics.TypeGauge, Value: value, Timestamp: ts})
+		}
+	}
+
+	return samples
+}
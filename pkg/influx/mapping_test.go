@@ -0,0 +1,66 @@
+package influx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestMapperMap(t *testing.T) {
+	mp, err := NewMapper(Mapping{
+		Template: "{measurement}.{field}",
+		Counters: []string{`net\.bytes_.*`},
+	})
+	assert.NoError(t, err)
+
+	points := []Point{
+		{
+			Measurement: "net",
+			Tags:        map[string]string{"host": "a", "interface-name": "eth0"},
+			Fields:      map[string]interface{}{"bytes_recv": uint64(1000), "speed": int64(100), "up": true, "name": "eth0"},
+		},
+	}
+	labels := metrics.Labels{"host": "a", "interface_name": "eth0"}
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	byKey := func(samples []metrics.Sample) map[string]metrics.Sample {
+		res := make(map[string]metrics.Sample, len(samples))
+		for _, s := range samples {
+			res[s.ID] = s
+		}
+		return res
+	}
+
+	// первое накопительное значение счётчика только запоминается
+	samples := byKey(mp.Map(points, now))
+	assert.Len(t, samples, 3)
+	recv := samples[metrics.SeriesKey("net.bytes_recv", labels)]
+	assert.Equal(t, metrics.TypeCounter, recv.MType)
+	assert.Equal(t, int64(0), recv.Delta)
+	assert.Equal(t, now, recv.Timestamp)
+	assert.Equal(t, 100.0, samples[metrics.SeriesKey("net.speed", labels)].Value)
+	assert.Equal(t, 1.0, samples[metrics.SeriesKey("net.up", labels)].Value)
+
+	// накопительное значение счётчика передаётся приращением с временем точки
+	points[0].Fields["bytes_recv"] = uint64(1500)
+	points[0].Time = now.Add(-time.Minute)
+	samples = byKey(mp.Map(points, now))
+	recv = samples[metrics.SeriesKey("net.bytes_recv", labels)]
+	assert.Equal(t, int64(500), recv.Delta)
+	assert.Equal(t, now.Add(-time.Minute), recv.Timestamp)
+}
+
+func TestNewMapper(t *testing.T) {
+	mp, err := NewMapper(Mapping{})
+	assert.NoError(t, err)
+	assert.Equal(t, "cpu_usage", mp.ID("cpu", "usage"))
+
+	_, err = NewMapper(Mapping{Template: "static"})
+	assert.Error(t, err)
+
+	_, err = NewMapper(Mapping{Counters: []string{"("}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,30 @@
+package influx
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// newMatcher Возвращает проверку значения на полное совпадение с любым из выражений patterns.
+func newMatcher(patterns []string) (func(string) bool, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+
+	return func(s string) bool {
+		for _, re := range res {
+			if re.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import "time"
+
+const (
+	LabelAgent = "agent" // Метка с идентификатором агента
+	LabelHost  = "host"  // Метка с именем хоста агента
+
+	HeaderAgentID       = "X-Agent-ID"
+	HeaderAgentHostname = "X-Agent-Hostname"
+	HeaderAgentOS       = "X-Agent-OS"
+	HeaderAgentVersion  = "X-Agent-Version"
+	HeaderBatchID       = "X-Batch-ID" // Идентификатор пакета метрик для отбрасывания повторной доставки
+)
+
+// AgentInfo Описывает агента, приславшего отчёт с метриками.
+type AgentInfo struct {
+	ID       string    `json:"id"`
+	Hostname string    `json:"hostname,omitempty"`
+	OS       string    `json:"os,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Address  string    `json:"address,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrBucketsMismatch Границы корзин объединяемых гистограмм не совпадают.
+var ErrBucketsMismatch = errors.New("histogram buckets mismatch")
+
+// DefaultBuckets Границы корзин гистограммы по умолчанию, в секундах.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram Распределение наблюдений по корзинам.
+//
+// Bounds — возрастающие верхние границы корзин. Counts[i] — число наблюдений в корзине
+// (Bounds[i-1], Bounds[i]]; последний элемент Counts соответствует корзине +Inf, поэтому
+// len(Counts) == len(Bounds)+1. Count и Sum — общее число и сумма наблюдений.
+// Как и `counter`, гистограмма передаётся приращениями, которые хранилище объединяет по корзинам.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Count  uint64    `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+// NewHistogram Создаёт пустую гистограмму с заданными границами корзин;
+// без границ используются DefaultBuckets.
+func NewHistogram(bounds []float64) Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+
+	return Histogram{
+		Bounds: append([]float64(nil), bounds...),
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe Добавляет наблюдение v в гистограмму.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += v
+}
+
+// Validate Проверяет согласованность границ и счётчиков корзин.
+func (h Histogram) Validate() error {
+	for i, b := range h.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return fmt.Errorf("bad histogram bound %v", b)
+		}
+		if i > 0 && b <= h.Bounds[i-1] {
+			return fmt.Errorf("histogram bounds must increase")
+		}
+	}
+
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("histogram with %d bounds must have %d bucket counts", len(h.Bounds), len(h.Bounds)+1)
+	}
+
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+	if count != h.Count {
+		return fmt.Errorf("histogram count %d does not match bucket counts %d", h.Count, count)
+	}
+
+	return nil
+}
+
+// Merge Возвращает гистограмму, объединяющую наблюдения h и o по корзинам.
+// Гистограммы с разными границами корзин не объединяются.
+func (h Histogram) Merge(o Histogram) (Histogram, error) {
+	if len(h.Bounds) != len(o.Bounds) || len(h.Counts) != len(o.Counts) {
+		return h, ErrBucketsMismatch
+	}
+	for i := range h.Bounds {
+		if h.Bounds[i] != o.Bounds[i] {
+			return h, ErrBucketsMismatch
+		}
+	}
+
+	merged := h.Clone()
+	for i, c := range o.Counts {
+		merged.Counts[i] += c
+	}
+	merged.Count += o.Count
+	merged.Sum += o.Sum
+
+	return merged, nil
+}
+
+// Clone Возвращает копию гистограммы, не разделяющую с ней память.
+func (h Histogram) Clone() Histogram {
+	return Histogram{
+		Bounds: append([]float64(nil), h.Bounds...),
+		Counts: append([]uint64(nil), h.Counts...),
+		Count:  h.Count,
+		Sum:    h.Sum,
+	}
+}
+
+// Cumulative Возвращает накопительные счётчики корзин: число наблюдений, не превышающих границу корзины.
+func (h Histogram) Cumulative() []uint64 {
+	cumulative := make([]uint64, len(h.Counts))
+	var total uint64
+	for i, c := range h.Counts {
+		total += c
+		cumulative[i] = total
+	}
+
+	return cumulative
+}
+
+// HistogramHash Рассчитывает хэш для метрики типа `histogram`.
+func HistogramHash(key, id string, h Histogram) string {
+	msg := fmt.Sprintf("%s:histogram:%v:%v:%d:%f", id, h.Bounds, h.Counts, h.Count, h.Sum)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(msg))
+	// переводим в 16-тиричный вид, чтобы хэш не пострадал при передаче в строковом представлении
+	return hex.EncodeToString(mac.Sum(nil))
+}
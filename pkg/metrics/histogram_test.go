@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := NewHistogram([]float64{1, 5})
+	for _, v := range []float64{0.5, 1, 3, 7} {
+		h.Observe(v)
+	}
+
+	assert.Equal(t, []uint64{2, 1, 1}, h.Counts)
+	assert.Equal(t, uint64(4), h.Count)
+	assert.Equal(t, 11.5, h.Sum)
+	assert.Equal(t, []uint64{2, 3, 4}, h.Cumulative())
+	assert.NoError(t, h.Validate())
+
+	// без границ используются границы по умолчанию
+	assert.Equal(t, DefaultBuckets, NewHistogram(nil).Bounds)
+}
+
+func TestHistogramValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       Histogram
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			h:    Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 0, 2}, Count: 3, Sum: 10},
+		},
+		{
+			name: "No bounds",
+			h:    Histogram{Counts: []uint64{2}, Count: 2, Sum: 1},
+		},
+		{
+			name:    "Bounds not increasing",
+			h:       Histogram{Bounds: []float64{2, 1}, Counts: []uint64{0, 0, 0}},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong number of counts",
+			h:       Histogram{Bounds: []float64{1, 2}, Counts: []uint64{0, 0}},
+			wantErr: true,
+		},
+		{
+			name:    "Count mismatch",
+			h:       Histogram{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 3},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.h.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 0, 2}, Count: 3, Sum: 10}
+	b := Histogram{Bounds: []float64{1, 2}, Counts: []uint64{0, 4, 1}, Count: 5, Sum: 9}
+
+	merged, err := a.Merge(b)
+	require.NoError(t, err)
+	assert.Equal(t, Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 4, 3}, Count: 8, Sum: 19}, merged)
+	// исходная гистограмма не изменяется
+	assert.Equal(t, []uint64{1, 0, 2}, a.Counts)
+
+	_, err = a.Merge(Histogram{Bounds: []float64{1, 3}, Counts: []uint64{0, 0, 0}})
+	assert.ErrorIs(t, err, ErrBucketsMismatch)
+	_, err = a.Merge(Histogram{Bounds: []float64{1}, Counts: []uint64{0, 0}})
+	assert.ErrorIs(t, err, ErrBucketsMismatch)
+	_, err = a.Merge(Histogram{Bounds: []float64{1, 2}, Counts: []uint64{0, 0}})
+	assert.ErrorIs(t, err, ErrBucketsMismatch)
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels Набор меток, уточняющих серию метрики: хост, агент, окружение и т.п.
+type Labels map[string]string
+
+// SeriesKey Возвращает ключ серии метрики: имя метрики и отсортированный набор меток
+// в виде `name{label1="value1",label2="value2"}`. Для метрики без меток ключ совпадает с её именем.
+func SeriesKey(id string, labels Labels) string {
+	if len(labels) == 0 {
+		return id
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := strings.Builder{}
+	b.WriteString(id)
+	b.WriteByte('{')
+	for k, name := range names {
+		if k > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// ParseSeriesKey Разбирает ключ серии на имя метрики и набор меток.
+func ParseSeriesKey(key string) (string, Labels, error) {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key, nil, nil
+	}
+	if !strings.HasSuffix(key, "}") {
+		return "", nil, fmt.Errorf("bad series key '%s': missing closing brace", key)
+	}
+
+	id := key[:start]
+	body := key[start+1 : len(key)-1]
+	labels := make(Labels)
+
+	for len(body) > 0 {
+		eq := strings.Index(body, `="`)
+		if eq <= 0 {
+			return "", nil, fmt.Errorf("bad series key '%s': label value expected", key)
+		}
+		name := body[:eq]
+		body = body[eq+2:]
+
+		value := strings.Builder{}
+		closed := false
+		i := 0
+		for ; i < len(body); i++ {
+			c := body[i]
+			if c == '\\' && i+1 < len(body) {
+				i++
+				switch body[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(body[i])
+				}
+				continue
+			}
+			if c == '"' {
+				closed = true
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return "", nil, fmt.Errorf("bad series key '%s': unterminated label value", key)
+		}
+		labels[name] = value.String()
+
+		body = body[i+1:]
+		if strings.HasPrefix(body, ",") {
+			body = body[1:]
+		}
+	}
+
+	return id, labels, nil
+}
+
+// CheckLabels Проверяет имена меток на соответствие формату `[a-zA-Z_][a-zA-Z0-9_]*`.
+func CheckLabels(labels Labels) error {
+	for name := range labels {
+		if name == "" {
+			return fmt.Errorf("empty label name")
+		}
+		for i, c := range name {
+			if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= '0' && c <= '9' && i > 0 {
+				continue
+			}
+			return fmt.Errorf("bad label name '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+// CheckSeriesKey Проверяет, что ключ серии разбирается на имя метрики и метки, а имена меток допустимы.
+func CheckSeriesKey(key string) error {
+	_, labels, err := ParseSeriesKey(key)
+	if err != nil {
+		return err
+	}
+
+	return CheckLabels(labels)
+}
+
+// SanitizeLabel Приводит имя метки к формату `[a-zA-Z_][a-zA-Z0-9_]*`, заменяя недопустимые символы на `_`.
+func SanitizeLabel(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	b := []byte(name)
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= '0' && c <= '9' && i > 0 {
+			continue
+		}
+		b[i] = '_'
+	}
+
+	return string(b)
+}
+
+// Key Возвращает ключ серии метрики с учётом меток.
+func (m *Metrics) Key() string {
+	return SeriesKey(m.ID, m.Labels)
+}
+
+func escapeLabelValue(v string) string {
+	if !strings.ContainsAny(v, "\\\"\n") {
+		return v
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return r.Replace(v)
+}
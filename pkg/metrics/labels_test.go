@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		labels Labels
+		want   string
+	}{
+		{
+			name: "Without labels",
+			id:   "Alloc",
+			want: "Alloc",
+		},
+		{
+			name:   "Sorted labels",
+			id:     "Alloc",
+			labels: Labels{"host": "srv1", "agent": "a1"},
+			want:   `Alloc{agent="a1",host="srv1"}`,
+		},
+		{
+			name:   "Escaped value",
+			id:     "Alloc",
+			labels: Labels{"path": `C:\tmp "x"`},
+			want:   `Alloc{path="C:\\tmp \"x\""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := SeriesKey(tt.id, tt.labels)
+			assert.Equal(t, tt.want, key)
+
+			id, labels, err := ParseSeriesKey(key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, id)
+			if len(tt.labels) == 0 {
+				assert.Empty(t, labels)
+			} else {
+				assert.Equal(t, tt.labels, labels)
+			}
+		})
+	}
+}
+
+func TestParseSeriesKeyBad(t *testing.T) {
+	for _, key := range []string{`Alloc{host="a"`, `Alloc{host}`, `Alloc{host="a}`} {
+		_, _, err := ParseSeriesKey(key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestCheckLabels(t *testing.T) {
+	assert.NoError(t, CheckLabels(Labels{"host": "a", "_env2": "b"}))
+	assert.Error(t, CheckLabels(Labels{"2host": "a"}))
+	assert.Error(t, CheckLabels(Labels{"ho-st": "a"}))
+	assert.Error(t, CheckLabels(Labels{"": "a"}))
+}
+
+func TestCheckSeriesKey(t *testing.T) {
+	assert.NoError(t, CheckSeriesKey("Alloc"))
+	assert.NoError(t, CheckSeriesKey(SeriesKey("Alloc", Labels{"host": `a"{b}`})))
+	for _, key := range []string{"foo{", `foo{1a="b"}`, SeriesKey("a{b", Labels{"host": "a"})} {
+		assert.Error(t, CheckSeriesKey(key), key)
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strconv"
+	"time"
 )
 
 const (
 	TypeGauge      = "gauge"
 	TypeCounter    = "counter"
+	TypeHistogram  = "histogram"
+	TypeSummary    = "summary"
+	TypeSet        = "set"
 	TypeGaugeLen   = 31
 	TypeCounterLen = 1
 
@@ -46,7 +50,58 @@ const (
 	PollCount       = "PollCount"
 	TotalMemory     = "TotalMemory"
 	FreeMemory      = "FreeMemory"
+	CPUutilization  = "CPUutilization" // Префикс загрузки ядра процессора, номер ядра начинается с 1
 	CPUutilization1 = "CPUutilization1"
+	CPUUser         = "CPUUser"
+	CPUSystem       = "CPUSystem"
+	CPUIowait       = "CPUIowait"
+	CPUIdle         = "CPUIdle"
+
+	DiskTotal       = "DiskTotal"
+	DiskUsed        = "DiskUsed"
+	DiskFree        = "DiskFree"
+	DiskUsedPercent = "DiskUsedPercent"
+	DiskInodesTotal = "DiskInodesTotal"
+	DiskInodesUsed  = "DiskInodesUsed"
+	DiskInodesFree  = "DiskInodesFree"
+	DiskReadBytes   = "DiskReadBytes"
+	DiskWriteBytes  = "DiskWriteBytes"
+	DiskReads       = "DiskReads"
+	DiskWrites      = "DiskWrites"
+
+	NetBytesRecv   = "NetBytesRecv"
+	NetBytesSent   = "NetBytesSent"
+	NetPacketsRecv = "NetPacketsRecv"
+	NetPacketsSent = "NetPacketsSent"
+	NetErrorsIn    = "NetErrorsIn"
+	NetErrorsOut   = "NetErrorsOut"
+	NetDropsIn     = "NetDropsIn"
+	NetDropsOut    = "NetDropsOut"
+	TCPConnections = "TCPConnections"
+
+	ProcessCount      = "ProcessCount"
+	ProcessCPUPercent = "ProcessCPUPercent"
+	ProcessRSS        = "ProcessRSS"
+	ProcessFDs        = "ProcessFDs"
+	ProcessThreads    = "ProcessThreads"
+	ProcessUptime     = "ProcessUptime"
+
+	CgroupMemoryCurrent    = "CgroupMemoryCurrent"
+	CgroupMemoryMax        = "CgroupMemoryMax"
+	CgroupOOMKills         = "CgroupOOMKills"
+	CgroupPids             = "CgroupPids"
+	CgroupPidsMax          = "CgroupPidsMax"
+	CgroupCPUPercent       = "CgroupCPUPercent"
+	CgroupCPUUsage         = "CgroupCPUUsage"
+	CgroupCPUUser          = "CgroupCPUUser"
+	CgroupCPUSystem        = "CgroupCPUSystem"
+	CgroupCPUPeriods       = "CgroupCPUPeriods"
+	CgroupCPUThrottled     = "CgroupCPUThrottled"
+	CgroupCPUThrottledTime = "CgroupCPUThrottledTime"
+	CgroupIOReadBytes      = "CgroupIOReadBytes"
+	CgroupIOWriteBytes     = "CgroupIOWriteBytes"
+	CgroupIOReads          = "CgroupIOReads"
+	CgroupIOWrites         = "CgroupIOWrites"
 )
 
 var Gauges = map[string]bool{
@@ -81,6 +136,10 @@ var Gauges = map[string]bool{
 	TotalMemory:     true,
 	FreeMemory:      true,
 	CPUutilization1: true,
+	CPUUser:         true,
+	CPUSystem:       true,
+	CPUIowait:       true,
+	CPUIdle:         true,
 }
 
 var Counters = map[string]bool{
@@ -130,29 +189,166 @@ func (c *Counter) FromString(str string) error {
 	return nil
 }
 
-// Metrics Единая структура для хранения значения и метаданных метрики любого из типов: `gauge` или `counter`.
+// Metrics Единая структура для хранения значения и метаданных метрики любого из типов:
+// `gauge`, `counter`, `histogram`, `summary` или `set`.
 type Metrics struct {
-	ID    string   `json:"id"`              // Имя метрики
-	MType string   `json:"type"`            // Параметр, принимающий значение gauge или counter
-	Delta *int64   `json:"delta,omitempty"` // Значение метрики в случае передачи counter
-	Value *float64 `json:"value,omitempty"` // Значение метрики в случае передачи gauge
-	Hash  string   `json:"hash,omitempty"`  // Значение хеш-функции
+	ID          string             `json:"id"`                    // Имя метрики
+	MType       string             `json:"type"`                  // Параметр, принимающий значение gauge, counter, histogram, summary или set
+	Delta       *int64             `json:"delta,omitempty"`       // Значение метрики в случае передачи counter
+	Value       *float64           `json:"value,omitempty"`       // Значение метрики в случае передачи gauge
+	Histogram   *Histogram         `json:"histogram,omitempty"`   // Значение метрики в случае передачи histogram
+	Summary     *Summary           `json:"summary,omitempty"`     // Значение метрики в случае передачи summary
+	Set         *Set               `json:"set,omitempty"`         // Значение метрики в случае передачи set
+	Quantiles   map[string]float64 `json:"quantiles,omitempty"`   // Оценки квантилей summary, возвращаемые сервером
+	Cardinality *uint64            `json:"cardinality,omitempty"` // Оценка числа уникальных значений set, возвращаемая сервером
+	Hash        string             `json:"hash,omitempty"`        // Значение хеш-функции
+	Labels      Labels             `json:"labels,omitempty"`      // Набор меток серии метрики
+}
+
+// Sample Хранит значение метрики, принятое сервисом в определённый момент времени.
+// Для `gauge` сохраняется значение Value, для `counter` — принятое приращение Delta,
+// для `histogram`, `summary` и `set` — принятые приращения Histogram, Summary и Set.
+type Sample struct {
+	ID        string     `json:"id"`
+	MType     string     `json:"type"`
+	Delta     int64      `json:"delta,omitempty"`
+	Value     float64    `json:"value,omitempty"`
+	Histogram *Histogram `json:"histogram,omitempty"`
+	Summary   *Summary   `json:"summary,omitempty"`
+	Set       *Set       `json:"set,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// NewSamples Преобразует значения метрик в список отсчётов с единой меткой времени.
+func NewSamples(prm *ProxyMetrics, ts time.Time) []Sample {
+	samples := make([]Sample, 0, len(prm.Gauges)+len(prm.Counters)+len(prm.Histograms)+len(prm.Summaries)+len(prm.Sets))
+	for id, value := range prm.Gauges {
+		samples = append(samples, Sample{ID: id, MType: TypeGauge, Value: float64(value), Timestamp: ts})
+	}
+	for id, delta := range prm.Counters {
+		samples = append(samples, Sample{ID: id, MType: TypeCounter, Delta: int64(delta), Timestamp: ts})
+	}
+	for id, h := range prm.Histograms {
+		h = h.Clone()
+		samples = append(samples, Sample{ID: id, MType: TypeHistogram, Histogram: &h, Timestamp: ts})
+	}
+	for id, s := range prm.Summaries {
+		s = s.Clone()
+		samples = append(samples, Sample{ID: id, MType: TypeSummary, Summary: &s, Timestamp: ts})
+	}
+	for id, s := range prm.Sets {
+		s = s.Clone()
+		samples = append(samples, Sample{ID: id, MType: TypeSet, Set: &s, Timestamp: ts})
+	}
+
+	return samples
+}
+
+// GaugeTimes Возвращает для каждого `gauge` наибольшую метку времени среди отсчётов: к этому времени
+// относится значение, которое NewProxyMetricsFromSamples берёт из отсчётов.
+func GaugeTimes(samples []Sample) map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, sample := range samples {
+		if sample.MType != TypeGauge {
+			continue
+		}
+		if ts, ok := times[sample.ID]; !ok || ts.Before(sample.Timestamp) {
+			times[sample.ID] = sample.Timestamp
+		}
+	}
+
+	return times
 }
 
-// ProxyMetrics Хранит информацию значений всех метрик.
+// NewProxyMetricsFromSamples Собирает текущие значения метрик из отсчётов: для `gauge` берётся значение
+// с наибольшей меткой времени, приращения `counter` суммируются, приращения `histogram`, `summary` и `set`
+// объединяются; при несовпадении корзин или точности сохраняется более позднее приращение.
+func NewProxyMetricsFromSamples(samples []Sample) *ProxyMetrics {
+	prm := NewProxyMetrics()
+	latest := make(map[string]time.Time)
+	for _, sample := range samples {
+		switch sample.MType {
+		case TypeGauge:
+			if ts, ok := latest[sample.ID]; ok && sample.Timestamp.Before(ts) {
+				continue
+			}
+			latest[sample.ID] = sample.Timestamp
+			prm.Gauges[sample.ID] = Gauge(sample.Value)
+		case TypeCounter:
+			prm.Counters[sample.ID] += Counter(sample.Delta)
+		case TypeHistogram:
+			if sample.Histogram == nil {
+				continue
+			}
+			prev, ok := prm.Histograms[sample.ID]
+			if !ok {
+				prm.Histograms[sample.ID] = sample.Histogram.Clone()
+				continue
+			}
+			merged, err := prev.Merge(*sample.Histogram)
+			if err != nil {
+				merged = sample.Histogram.Clone()
+			}
+			prm.Histograms[sample.ID] = merged
+		case TypeSummary:
+			if sample.Summary == nil {
+				continue
+			}
+			prev, ok := prm.Summaries[sample.ID]
+			if !ok {
+				prm.Summaries[sample.ID] = sample.Summary.Clone()
+				continue
+			}
+			merged, err := prev.Merge(*sample.Summary)
+			if err != nil {
+				merged = sample.Summary.Clone()
+			}
+			prm.Summaries[sample.ID] = merged
+		case TypeSet:
+			if sample.Set == nil {
+				continue
+			}
+			prev, ok := prm.Sets[sample.ID]
+			if !ok {
+				prm.Sets[sample.ID] = sample.Set.Clone()
+				continue
+			}
+			merged, err := prev.Merge(*sample.Set)
+			if err != nil {
+				merged = sample.Set.Clone()
+			}
+			prm.Sets[sample.ID] = merged
+		}
+	}
+
+	return prm
+}
+
+// ProxyMetrics Хранит информацию значений всех метрик; ключами служат ключи серий, см. SeriesKey.
 type ProxyMetrics struct {
-	Gauges   map[string]Gauge
-	Counters map[string]Counter
+	Gauges     map[string]Gauge
+	Counters   map[string]Counter
+	Histograms map[string]Histogram `json:",omitempty"`
+	Summaries  map[string]Summary   `json:",omitempty"`
+	Sets       map[string]Set       `json:",omitempty"`
 }
 
 // NewProxyMetrics Создаёт новый объект типа ProxyMetrics.
 func NewProxyMetrics() *ProxyMetrics {
 	return &ProxyMetrics{
-		Gauges:   make(map[string]Gauge, TypeGaugeLen),
-		Counters: make(map[string]Counter, TypeCounterLen),
+		Gauges:     make(map[string]Gauge, TypeGaugeLen),
+		Counters:   make(map[string]Counter, TypeCounterLen),
+		Histograms: make(map[string]Histogram),
+		Summaries:  make(map[string]Summary),
+		Sets:       make(map[string]Set),
 	}
 }
 
+// Empty Сообщает, что в объекте нет значений ни одной метрики.
+func (prm *ProxyMetrics) Empty() bool {
+	return len(prm.Gauges) == 0 && len(prm.Counters) == 0 && len(prm.Histograms) == 0 && len(prm.Summaries) == 0 && len(prm.Sets) == 0
+}
+
 // GaugeHash Рассчитывает хэш для метрики типа `gauge`.
 func GaugeHash(key, id string, value float64) string {
 	msg := fmt.Sprintf("%s:gauge:%f", id, value)
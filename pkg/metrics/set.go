@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// ErrPrecisionMismatch Точность объединяемых множеств `set` не совпадает.
+var ErrPrecisionMismatch = errors.New("set precision mismatch")
+
+const (
+	// DefaultSetPrecision Точность скетча множества по умолчанию: 2^12 регистров, стандартная ошибка около 1.6%.
+	DefaultSetPrecision = 12
+	// MinSetPrecision и MaxSetPrecision Допустимые значения точности скетча множества.
+	MinSetPrecision = 4
+	MaxSetPrecision = 16
+)
+
+// Способы записи регистров в двоичном представлении множества.
+const (
+	setEncodingDense  byte = 0 // все регистры подряд
+	setEncodingSparse byte = 1 // пары: приращение номера регистра (uvarint) и значение регистра
+)
+
+// Set Скетч HyperLogLog для оценки числа уникальных значений.
+//
+// Значение хэшируется, первые Precision бит хэша выбирают регистр, в котором сохраняется наибольшая
+// позиция первой единицы среди оставшихся бит. Множества с одинаковой точностью объединяются
+// поэлементным максимумом регистров, поэтому отчёты разных агентов можно сводить в одно множество.
+// В JSON и в БД множество передаётся компактно, см. MarshalBinary.
+type Set struct {
+	Precision uint8
+	Registers []byte
+}
+
+// NewSet Создаёт пустое множество с заданной точностью;
+// при точности вне [MinSetPrecision, MaxSetPrecision] используется DefaultSetPrecision.
+func NewSet(precision uint8) Set {
+	if precision < MinSetPrecision || precision > MaxSetPrecision {
+		precision = DefaultSetPrecision
+	}
+
+	return Set{
+		Precision: precision,
+		Registers: make([]byte, 1<<precision),
+	}
+}
+
+// Add Добавляет значение в множество.
+func (s *Set) Add(value string) {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	s.AddHash(mix64(h.Sum64()))
+}
+
+// AddHash Добавляет в множество значение по его 64-битному хэшу.
+func (s *Set) AddHash(hash uint64) {
+	p := s.Precision
+	idx := hash >> (64 - p)
+	// защитный бит ограничивает позицию первой единицы значением 64-p+1
+	rho := byte(bits.LeadingZeros64(hash<<p|1<<(p-1)) + 1)
+	if rho > s.Registers[idx] {
+		s.Registers[idx] = rho
+	}
+}
+
+// mix64 Перемешивает биты хэша FNV, который плохо распределяет короткие строки (финализатор MurmurHash3).
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+
+	return h
+}
+
+// Estimate Возвращает оценку числа уникальных значений множества.
+func (s Set) Estimate() uint64 {
+	m := float64(len(s.Registers))
+	if m == 0 {
+		return 0
+	}
+
+	var (
+		sum   float64
+		zeros int
+	)
+	for _, r := range s.Registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	var alpha float64
+	switch len(s.Registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+
+	estimate := alpha * m * m / sum
+	// на малых мощностях точнее линейный подсчёт по числу пустых регистров
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// Validate Проверяет точность множества и значения регистров.
+func (s Set) Validate() error {
+	if s.Precision < MinSetPrecision || s.Precision > MaxSetPrecision {
+		return fmt.Errorf("bad set precision %d", s.Precision)
+	}
+	if len(s.Registers) != 1<<s.Precision {
+		return fmt.Errorf("set with precision %d must have %d registers", s.Precision, 1<<s.Precision)
+	}
+
+	max := byte(64 - s.Precision + 1)
+	for _, r := range s.Registers {
+		if r > max {
+			return fmt.Errorf("bad set register value %d", r)
+		}
+	}
+
+	return nil
+}
+
+// Merge Возвращает множество, объединяющее значения s и o.
+// Множества с разной точностью не объединяются.
+func (s Set) Merge(o Set) (Set, error) {
+	if s.Precision != o.Precision || len(s.Registers) != len(o.Registers) {
+		return s, ErrPrecisionMismatch
+	}
+
+	merged := s.Clone()
+	for i, r := range o.Registers {
+		if r > merged.Registers[i] {
+			merged.Registers[i] = r
+		}
+	}
+
+	return merged, nil
+}
+
+// Clone Возвращает копию множества, не разделяющую с ним память.
+func (s Set) Clone() Set {
+	return Set{
+		Precision: s.Precision,
+		Registers: append([]byte(nil), s.Registers...),
+	}
+}
+
+// MarshalBinary Представляет множество компактно: байт точности, байт способа записи и регистры.
+// Если заполнена малая часть регистров, записываются только ненулевые регистры.
+func (s Set) MarshalBinary() ([]byte, error) {
+	sparse := make([]byte, 0, 16)
+	sparse = append(sparse, s.Precision, setEncodingSparse)
+	buf := make([]byte, binary.MaxVarintLen64)
+	prev := 0
+	for i, r := range s.Registers {
+		if r == 0 {
+			continue
+		}
+		n := binary.PutUvarint(buf, uint64(i-prev))
+		sparse = append(sparse, buf[:n]...)
+		sparse = append(sparse, r)
+		prev = i
+
+		if len(sparse) >= len(s.Registers)+2 {
+			dense := make([]byte, 0, len(s.Registers)+2)
+			dense = append(dense, s.Precision, setEncodingDense)
+			return append(dense, s.Registers...), nil
+		}
+	}
+
+	return sparse, nil
+}
+
+// UnmarshalBinary Восстанавливает множество из представления MarshalBinary.
+func (s *Set) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("set data too short")
+	}
+
+	precision := data[0]
+	if precision < MinSetPrecision || precision > MaxSetPrecision {
+		return fmt.Errorf("bad set precision %d", precision)
+	}
+	registers := make([]byte, 1<<precision)
+
+	switch data[1] {
+	case setEncodingDense:
+		if len(data)-2 != len(registers) {
+			return fmt.Errorf("bad dense set length %d", len(data)-2)
+		}
+		copy(registers, data[2:])
+	case setEncodingSparse:
+		i := 0
+		for rest := data[2:]; len(rest) > 0; {
+			delta, n := binary.Uvarint(rest)
+			if n <= 0 || len(rest) < n+1 {
+				return fmt.Errorf("bad sparse set data")
+			}
+			i += int(delta)
+			if i >= len(registers) {
+				return fmt.Errorf("set register index %d out of range", i)
+			}
+			registers[i] = rest[n]
+			rest = rest[n+1:]
+		}
+	default:
+		return fmt.Errorf("unknown set encoding %d", data[1])
+	}
+
+	s.Precision = precision
+	s.Registers = registers
+
+	return nil
+}
+
+// MarshalJSON Представляет множество в JSON строкой base64 с данными MarshalBinary.
+func (s Set) MarshalJSON() ([]byte, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON Восстанавливает множество из представления MarshalJSON.
+func (s *Set) UnmarshalJSON(b []byte) error {
+	var data []byte
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	return s.UnmarshalBinary(data)
+}
+
+// SetHash Рассчитывает хэш для метрики типа `set`.
+func SetHash(key, id string, s Set) string {
+	data, _ := s.MarshalBinary()
+	msg := fmt.Sprintf("%s:set:%x", id, data)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(msg))
+	// переводим в 16-тиричный вид, чтобы хэш не пострадал при передаче в строковом представлении
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct int
+	}{
+		{name: "Empty", distinct: 0},
+		{name: "Small", distinct: 10},
+		{name: "Medium", distinct: 1000},
+		{name: "Large", distinct: 100000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSet(DefaultSetPrecision)
+			for i := 0; i < tt.distinct; i++ {
+				// повторы не влияют на оценку
+				s.Add(fmt.Sprintf("user-%d", i))
+				s.Add(fmt.Sprintf("user-%d", i))
+			}
+			require.NoError(t, s.Validate())
+
+			if tt.distinct == 0 {
+				assert.Equal(t, uint64(0), s.Estimate())
+				return
+			}
+			// допускаем три стандартные ошибки скетча
+			assert.InEpsilon(t, tt.distinct, s.Estimate(), 0.05)
+		})
+	}
+}
+
+func TestSetMerge(t *testing.T) {
+	a, b := NewSet(10), NewSet(10)
+	for i := 0; i < 600; i++ {
+		a.Add(fmt.Sprintf("user-%d", i))
+	}
+	for i := 400; i < 1000; i++ {
+		b.Add(fmt.Sprintf("user-%d", i))
+	}
+
+	merged, err := a.Merge(b)
+	require.NoError(t, err)
+	assert.InEpsilon(t, 1000, merged.Estimate(), 0.1)
+	// исходное множество не изменяется
+	assert.InEpsilon(t, 600, a.Estimate(), 0.1)
+
+	_, err = a.Merge(NewSet(12))
+	assert.ErrorIs(t, err, ErrPrecisionMismatch)
+}
+
+func TestSetMarshal(t *testing.T) {
+	sparse := NewSet(DefaultSetPrecision)
+	for i := 0; i < 20; i++ {
+		sparse.Add(fmt.Sprintf("user-%d", i))
+	}
+	dense := NewSet(DefaultSetPrecision)
+	for i := 0; i < 20000; i++ {
+		dense.Add(fmt.Sprintf("user-%d", i))
+	}
+
+	for _, s := range []Set{sparse, dense, NewSet(4)} {
+		data, err := s.MarshalBinary()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(data), len(s.Registers)+2)
+
+		restored := Set{}
+		require.NoError(t, restored.UnmarshalBinary(data))
+		assert.Equal(t, s, restored)
+
+		b, err := json.Marshal(Metrics{ID: "users", MType: TypeSet, Set: &s})
+		require.NoError(t, err)
+		m := Metrics{}
+		require.NoError(t, json.Unmarshal(b, &m))
+		assert.Equal(t, s, *m.Set)
+	}
+
+	// малое множество записывается только ненулевыми регистрами
+	data, err := sparse.MarshalBinary()
+	require.NoError(t, err)
+	assert.Less(t, len(data), 100)
+
+	bad := Set{}
+	assert.Error(t, bad.UnmarshalBinary([]byte{12}))
+	assert.Error(t, bad.UnmarshalBinary([]byte{30, 0}))
+	assert.Error(t, bad.UnmarshalBinary([]byte{4, 1, 20, 1}))
+	assert.Error(t, bad.UnmarshalBinary([]byte{4, 0, 1}))
+}
+
+func TestSetValidate(t *testing.T) {
+	assert.NoError(t, NewSet(4).Validate())
+	assert.Error(t, Set{Precision: 2, Registers: make([]byte, 4)}.Validate())
+	assert.Error(t, Set{Precision: 4, Registers: make([]byte, 8)}.Validate())
+	assert.Error(t, Set{Precision: 4, Registers: append(make([]byte, 15), 62)}.Validate())
+}
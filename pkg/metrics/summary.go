@@ -0,0 +1,281 @@
+package metrics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ErrSketchMismatch Точность объединяемых скетчей `summary` не совпадает.
+var ErrSketchMismatch = errors.New("summary sketch accuracy mismatch")
+
+// DefaultSummaryAlpha Относительная точность оценки квантилей скетча по умолчанию.
+const DefaultSummaryAlpha = 0.01
+
+// MaxSummaryBins Наибольшее число корзин положительных и, отдельно, отрицательных наблюдений скетча.
+// При превышении корзины наименьших по модулю значений объединяются: точность оценки сохраняется
+// для больших по модулю значений, в том числе для верхних квантилей задержек.
+const MaxSummaryBins = 2048
+
+// summaryMinValue Наблюдения, меньшие по модулю, учитываются в нулевой корзине.
+const summaryMinValue = 1e-9
+
+// DefaultQuantiles Квантили, возвращаемые для метрики `summary` по умолчанию: p50, p95 и p99.
+var DefaultQuantiles = []float64{0.5, 0.95, 0.99}
+
+// Summary Скетч распределения наблюдений для оценки квантилей по схеме DDSketch.
+//
+// Положительное наблюдение v попадает в корзину с индексом ceil(log_γ(v)), где γ = (1+Alpha)/(1-Alpha),
+// отрицательное — в такую же корзину для |v| в Negative, близкое к нулю — в Zero. Оценка любого квантиля
+// отличается от точного значения не более чем на Alpha относительно. Скетчи с одинаковой точностью
+// объединяются сложением корзин, поэтому, как и `histogram`, передаются приращениями.
+type Summary struct {
+	Alpha    float64          `json:"alpha"`
+	Positive map[int32]uint64 `json:"positive,omitempty"`
+	Negative map[int32]uint64 `json:"negative,omitempty"`
+	Zero     uint64           `json:"zero,omitempty"`
+	Count    uint64           `json:"count"`
+	Sum      float64          `json:"sum"`
+	Min      float64          `json:"min"`
+	Max      float64          `json:"max"`
+}
+
+// NewSummary Создаёт пустой скетч с заданной относительной точностью;
+// при точности вне интервала (0, 1) используется DefaultSummaryAlpha.
+func NewSummary(alpha float64) Summary {
+	if !(alpha > 0 && alpha < 1) {
+		alpha = DefaultSummaryAlpha
+	}
+
+	return Summary{
+		Alpha:    alpha,
+		Positive: make(map[int32]uint64),
+		Negative: make(map[int32]uint64),
+	}
+}
+
+// gamma Возвращает основание логарифмической шкалы корзин.
+func (s Summary) gamma() float64 {
+	return (1 + s.Alpha) / (1 - s.Alpha)
+}
+
+// Observe Добавляет наблюдение v в скетч; значения NaN и ±Inf не учитываются.
+func (s *Summary) Observe(v float64) {
+	s.ObserveN(v, 1)
+}
+
+// ObserveN Добавляет n одинаковых наблюдений v в скетч, например наблюдение,
+// отправленное с долей отправленных значений 1/n.
+func (s *Summary) ObserveN(v float64, n uint64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) || n == 0 {
+		return
+	}
+
+	switch {
+	case v >= summaryMinValue:
+		if s.Positive == nil {
+			s.Positive = make(map[int32]uint64)
+		}
+		s.Positive[s.index(v)] += n
+		collapse(s.Positive)
+	case v <= -summaryMinValue:
+		if s.Negative == nil {
+			s.Negative = make(map[int32]uint64)
+		}
+		s.Negative[s.index(-v)] += n
+		collapse(s.Negative)
+	default:
+		s.Zero += n
+	}
+
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count += n
+	s.Sum += v * float64(n)
+}
+
+// index Возвращает индекс корзины для положительного значения v.
+func (s Summary) index(v float64) int32 {
+	return int32(math.Ceil(math.Log(v) / math.Log(s.gamma())))
+}
+
+// collapse Объединяет корзины с наименьшими индексами, пока их число превышает MaxSummaryBins.
+func collapse(bins map[int32]uint64) {
+	if len(bins) <= MaxSummaryBins {
+		return
+	}
+
+	indexes := sortedIndexes(bins)
+	excess := len(indexes) - MaxSummaryBins
+	target := indexes[excess]
+	for _, i := range indexes[:excess] {
+		bins[target] += bins[i]
+		delete(bins, i)
+	}
+}
+
+// value Возвращает оценку значений корзины с индексом i.
+func (s Summary) value(i int32) float64 {
+	g := s.gamma()
+	return 2 * math.Pow(g, float64(i)) / (g + 1)
+}
+
+// Validate Проверяет точность скетча, число корзин и согласованность общего числа наблюдений с корзинами.
+func (s Summary) Validate() error {
+	if !(s.Alpha > 0 && s.Alpha < 1) {
+		return fmt.Errorf("bad summary accuracy %v", s.Alpha)
+	}
+
+	count := s.Zero
+	for _, c := range s.Positive {
+		count += c
+	}
+	for _, c := range s.Negative {
+		count += c
+	}
+	if count != s.Count {
+		return fmt.Errorf("summary count %d does not match bucket counts %d", s.Count, count)
+	}
+
+	if len(s.Positive) > MaxSummaryBins || len(s.Negative) > MaxSummaryBins {
+		return fmt.Errorf("summary must have at most %d bins per sign", MaxSummaryBins)
+	}
+
+	if s.Count > 0 && s.Min > s.Max {
+		return fmt.Errorf("summary min %v is greater than max %v", s.Min, s.Max)
+	}
+
+	return nil
+}
+
+// Merge Возвращает скетч, объединяющий наблюдения s и o.
+// Скетчи с разной точностью не объединяются.
+func (s Summary) Merge(o Summary) (Summary, error) {
+	if s.Alpha != o.Alpha {
+		return s, ErrSketchMismatch
+	}
+
+	merged := s.Clone()
+	for i, c := range o.Positive {
+		merged.Positive[i] += c
+	}
+	for i, c := range o.Negative {
+		merged.Negative[i] += c
+	}
+	merged.Zero += o.Zero
+	collapse(merged.Positive)
+	collapse(merged.Negative)
+
+	if o.Count > 0 {
+		if s.Count == 0 || o.Min < merged.Min {
+			merged.Min = o.Min
+		}
+		if s.Count == 0 || o.Max > merged.Max {
+			merged.Max = o.Max
+		}
+	}
+	merged.Count += o.Count
+	merged.Sum += o.Sum
+
+	return merged, nil
+}
+
+// Clone Возвращает копию скетча, не разделяющую с ним память.
+func (s Summary) Clone() Summary {
+	c := s
+	c.Positive = make(map[int32]uint64, len(s.Positive))
+	for i, v := range s.Positive {
+		c.Positive[i] = v
+	}
+	c.Negative = make(map[int32]uint64, len(s.Negative))
+	for i, v := range s.Negative {
+		c.Negative[i] = v
+	}
+
+	return c
+}
+
+// Quantile Возвращает оценку квантиля q из интервала [0, 1]; для пустого скетча возвращает NaN.
+func (s Summary) Quantile(q float64) float64 {
+	if s.Count == 0 || math.IsNaN(q) || q < 0 || q > 1 {
+		return math.NaN()
+	}
+
+	rank := uint64(q * float64(s.Count-1))
+
+	// обходим корзины в порядке возрастания значений: отрицательные от наибольшего модуля,
+	// нулевую и положительные от наименьшего модуля
+	var seen uint64
+	estimate := math.NaN()
+	negative := sortedIndexes(s.Negative)
+	for j := len(negative) - 1; j >= 0; j-- {
+		seen += s.Negative[negative[j]]
+		if seen > rank {
+			estimate = -s.value(negative[j])
+			break
+		}
+	}
+	if math.IsNaN(estimate) {
+		seen += s.Zero
+		if seen > rank {
+			estimate = 0
+		}
+	}
+	if math.IsNaN(estimate) {
+		for _, i := range sortedIndexes(s.Positive) {
+			seen += s.Positive[i]
+			if seen > rank {
+				estimate = s.value(i)
+				break
+			}
+		}
+	}
+
+	// оценка не выходит за пределы наблюдённых значений
+	return math.Max(s.Min, math.Min(s.Max, estimate))
+}
+
+// Quantiles Возвращает оценки квантилей qs с ключами в десятичной записи, например `0.95`;
+// для пустого скетча возвращает nil.
+func (s Summary) Quantiles(qs []float64) map[string]float64 {
+	if s.Count == 0 {
+		return nil
+	}
+
+	quantiles := make(map[string]float64, len(qs))
+	for _, q := range qs {
+		quantiles[strconv.FormatFloat(q, 'f', -1, 64)] = s.Quantile(q)
+	}
+
+	return quantiles
+}
+
+// sortedIndexes Возвращает отсортированные индексы корзин.
+func sortedIndexes(bins map[int32]uint64) []int32 {
+	indexes := make([]int32, 0, len(bins))
+	for i := range bins {
+		indexes = append(indexes, i)
+	}
+	sort.Slice(indexes, func(a, b int) bool { return indexes[a] < indexes[b] })
+
+	return indexes
+}
+
+// SummaryHash Рассчитывает хэш для метрики типа `summary`.
+func SummaryHash(key, id string, s Summary) string {
+	// fmt выводит словари в порядке ключей, поэтому представление корзин однозначно
+	msg := fmt.Sprintf("%s:summary:%f:%v:%v:%d:%d:%f:%f:%f", id, s.Alpha, s.Positive, s.Negative, s.Zero, s.Count, s.Sum, s.Min, s.Max)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(msg))
+	// переводим в 16-тиричный вид, чтобы хэш не пострадал при передаче в строковом представлении
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryQuantile(t *testing.T) {
+	s := NewSummary(0.01)
+	for i := 1; i <= 1000; i++ {
+		s.Observe(float64(i))
+	}
+	require.NoError(t, s.Validate())
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{q: 0, want: 1},
+		{q: 0.5, want: 500},
+		{q: 0.95, want: 950},
+		{q: 0.99, want: 990},
+		{q: 1, want: 1000},
+	}
+	for _, tt := range tests {
+		// оценка отличается от точного значения не более чем на заданную точность
+		assert.InEpsilon(t, tt.want, s.Quantile(tt.q), 0.01, "quantile %v", tt.q)
+	}
+
+	assert.True(t, math.IsNaN(s.Quantile(1.5)))
+	assert.True(t, math.IsNaN(NewSummary(0).Quantile(0.5)))
+	assert.Nil(t, NewSummary(0).Quantiles(DefaultQuantiles))
+	assert.Len(t, s.Quantiles(DefaultQuantiles), 3)
+}
+
+func TestSummaryNegativeAndZero(t *testing.T) {
+	s := NewSummary(0.01)
+	for _, v := range []float64{-10, -1, 0, 1, 10, math.NaN()} {
+		s.Observe(v)
+	}
+
+	assert.Equal(t, uint64(5), s.Count)
+	assert.Equal(t, uint64(1), s.Zero)
+	assert.Equal(t, -10.0, s.Quantile(0))
+	assert.InEpsilon(t, -1, s.Quantile(0.25), 0.01)
+	assert.Equal(t, 0.0, s.Quantile(0.5))
+	assert.InEpsilon(t, 1, s.Quantile(0.75), 0.01)
+	assert.Equal(t, 10.0, s.Quantile(1))
+}
+
+func TestSummaryMerge(t *testing.T) {
+	a, b, all := NewSummary(0.02), NewSummary(0.02), NewSummary(0.02)
+	for i := 1; i <= 100; i++ {
+		a.Observe(float64(i))
+		all.Observe(float64(i))
+	}
+	for i := 101; i <= 300; i++ {
+		b.Observe(float64(i))
+		all.Observe(float64(i))
+	}
+
+	merged, err := a.Merge(b)
+	require.NoError(t, err)
+	assert.Equal(t, all.Count, merged.Count)
+	assert.Equal(t, all.Sum, merged.Sum)
+	assert.Equal(t, 1.0, merged.Min)
+	assert.Equal(t, 300.0, merged.Max)
+	assert.Equal(t, all.Quantiles(DefaultQuantiles), merged.Quantiles(DefaultQuantiles))
+	// исходный скетч не изменяется
+	assert.Equal(t, uint64(100), a.Count)
+
+	// объединение с пустым скетчем не меняет границ наблюдений
+	merged, err = NewSummary(0.02).Merge(a)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, merged.Min)
+	assert.Equal(t, 100.0, merged.Max)
+
+	_, err = a.Merge(NewSummary(0.01))
+	assert.ErrorIs(t, err, ErrSketchMismatch)
+}
+
+func TestSummaryValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       Summary
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			s:    Summary{Alpha: 0.01, Positive: map[int32]uint64{10: 2}, Zero: 1, Count: 3, Min: 0, Max: 1.2},
+		},
+		{
+			name:    "Bad accuracy",
+			s:       Summary{Alpha: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Count mismatch",
+			s:       Summary{Alpha: 0.01, Positive: map[int32]uint64{10: 2}, Count: 3},
+			wantErr: true,
+		},
+		{
+			name: "Too many bins",
+			s: func() Summary {
+				s := Summary{Alpha: 0.01, Positive: make(map[int32]uint64)}
+				for i := int32(0); i <= MaxSummaryBins; i++ {
+					s.Positive[i] = 1
+				}
+				s.Count = MaxSummaryBins + 1
+				return s
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "Min greater than max",
+			s:       Summary{Alpha: 0.01, Zero: 1, Count: 1, Min: 1, Max: 0},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.s.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSummaryObserveN(t *testing.T) {
+	a := NewSummary(DefaultSummaryAlpha)
+	b := NewSummary(DefaultSummaryAlpha)
+	for i := 0; i < 3; i++ {
+		a.Observe(-2)
+		a.Observe(0)
+		a.Observe(5)
+	}
+	b.ObserveN(-2, 3)
+	b.ObserveN(0, 3)
+	b.ObserveN(5, 3)
+	b.ObserveN(7, 0)
+
+	assert.Equal(t, a, b)
+}
+
+func TestSummaryBinsLimit(t *testing.T) {
+	// соседние наблюдения попадают в разные корзины
+	values := make([]float64, 3*MaxSummaryBins)
+	for i := range values {
+		values[i] = math.Pow(1.03, float64(i))
+	}
+
+	s := NewSummary(DefaultSummaryAlpha)
+	for _, v := range values {
+		s.Observe(v)
+	}
+	require.NoError(t, s.Validate())
+	assert.Len(t, s.Positive, MaxSummaryBins)
+	assert.Equal(t, uint64(len(values)), s.Count)
+	// объединяются корзины наименьших значений: верхние квантили сохраняют точность
+	want := values[int(0.99*float64(len(values)-1))]
+	assert.InEpsilon(t, want, s.Quantile(0.99), DefaultSummaryAlpha)
+
+	merged, err := s.Merge(s)
+	require.NoError(t, err)
+	assert.Len(t, merged.Positive, MaxSummaryBins)
+	assert.Equal(t, 2*s.Count, merged.Count)
+}
+
+func TestSummaryHash(t *testing.T) {
+	a := NewSummary(DefaultSummaryAlpha)
+	a.Observe(1)
+	b := a.Clone()
+	b.Min, b.Max = -100, 100
+
+	assert.Equal(t, SummaryHash("key", "Latency", a), SummaryHash("key", "Latency", a.Clone()))
+	assert.NotEqual(t, SummaryHash("key", "Latency", a), SummaryHash("key", "Latency", b))
+}
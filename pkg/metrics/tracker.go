@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultTrackerTTL Время, после которого забываются счётчики, не получавшие новых значений.
+const DefaultTrackerTTL = time.Hour
+
+// trackedCounter Последнее накопительное значение счётчика или гистограммы, время начала накопления
+// и время получения значения.
+type trackedCounter struct {
+	value float64
+	hist  *Histogram
+	start time.Time
+	seen  time.Time
+}
+
+// CounterTracker Преобразует накопительные значения счётчиков и гистограмм внешних источников
+// в приращения, которые принимает хранилище.
+type CounterTracker struct {
+	mu    sync.Mutex
+	last  map[string]trackedCounter
+	ttl   time.Duration
+	swept time.Time
+	now   func() time.Time
+}
+
+type TrackerOption func(t *CounterTracker)
+
+func NewCounterTracker(opts ...TrackerOption) *CounterTracker {
+	t := &CounterTracker{
+		last: make(map[string]trackedCounter),
+		ttl:  DefaultTrackerTTL,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// WithTrackerTTL Определяет время, после которого забываются счётчики, не получавшие новых значений.
+func WithTrackerTTL(ttl time.Duration) TrackerOption {
+	return func(t *CounterTracker) {
+		if ttl > 0 {
+			t.ttl = ttl
+		}
+	}
+}
+
+// Delta Возвращает приращение счётчика key с момента предыдущего значения.
+// Первое значение только запоминается: накопленное до него значение неизвестно, в том числе
+// после перезапуска сервиса, поэтому приращение равно нулю. Значение после сброса счётчика
+// источника возвращается целиком.
+func (t *CounterTracker) Delta(key string, value float64) Counter {
+	return t.DeltaSince(key, value, time.Time{})
+}
+
+// DeltaSince Возвращает приращение счётчика key, накапливаемого источником с момента start.
+// Изменение start означает сброс счётчика источника: значение возвращается целиком.
+// Нулевой start не сравнивается.
+func (t *CounterTracker) DeltaSince(key string, value float64, start time.Time) Counter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+
+	last, ok := t.last[key]
+	t.last[key] = trackedCounter{value: value, start: start, seen: now}
+	if !ok || last.hist != nil {
+		return 0
+	}
+	if value < last.value || restarted(last.start, start) {
+		return Counter(math.Round(value))
+	}
+
+	// округляем накопительные значения, чтобы дробные части приращений не терялись
+	return Counter(math.Round(value) - math.Round(last.value))
+}
+
+// HistogramDelta Возвращает приращение накопительной гистограммы key, накапливаемой источником
+// с момента start. Первое значение только запоминается: возвращается гистограмма без наблюдений.
+// После сброса гистограммы источника, изменения start или границ корзин значение возвращается целиком.
+func (t *CounterTracker) HistogramDelta(key string, h Histogram, start time.Time) Histogram {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+
+	last, ok := t.last[key]
+	cur := h.Clone()
+	t.last[key] = trackedCounter{hist: &cur, start: start, seen: now}
+	if !ok || last.hist == nil {
+		return Histogram{
+			Bounds: append([]float64(nil), h.Bounds...),
+			Counts: make([]uint64, len(h.Counts)),
+		}
+	}
+
+	prev := *last.hist
+	reset := restarted(last.start, start) || h.Count < prev.Count || len(h.Counts) != len(prev.Counts)
+	for i := 0; !reset && i < len(h.Bounds); i++ {
+		reset = i >= len(prev.Bounds) || h.Bounds[i] != prev.Bounds[i]
+	}
+	for i := 0; !reset && i < len(h.Counts); i++ {
+		reset = h.Counts[i] < prev.Counts[i]
+	}
+	if reset {
+		return h.Clone()
+	}
+
+	delta := h.Clone()
+	for i := range delta.Counts {
+		delta.Counts[i] -= prev.Counts[i]
+	}
+	delta.Count -= prev.Count
+	delta.Sum -= prev.Sum
+
+	return delta
+}
+
+// restarted Сообщает, изменилось ли известное время начала накопления значения.
+func restarted(last, start time.Time) bool {
+	return !last.IsZero() && !start.IsZero() && !last.Equal(start)
+}
+
+// Forget Забывает значение счётчика key: следующее значение снова только запоминается.
+func (t *CounterTracker) Forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.last, key)
+}
+
+// sweep Удаляет счётчики, не получавшие значений дольше ttl; выполняется не чаще раза в ttl.
+func (t *CounterTracker) sweep(now time.Time) {
+	if t.swept.IsZero() {
+		t.swept = now
+	}
+	if now.Sub(t.swept) < t.ttl {
+		return
+	}
+	t.swept = now
+
+	for key, c := range t.last {
+		if now.Sub(c.seen) >= t.ttl {
+			delete(t.last, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterTracker(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := NewCounterTracker(WithTrackerTTL(time.Minute))
+	tr.now = func() time.Time { return now }
+
+	// первое значение только запоминается
+	assert.Equal(t, Counter(0), tr.Delta("a", 10))
+	assert.Equal(t, Counter(5), tr.Delta("a", 15))
+	assert.Equal(t, Counter(0), tr.Delta("a", 15))
+	assert.Equal(t, Counter(0), tr.Delta("b", 7))
+	// сброс счётчика источника
+	assert.Equal(t, Counter(3), tr.Delta("a", 3))
+	// дробные приращения не теряются
+	assert.Equal(t, Counter(0), tr.Delta("c", 0.4))
+	assert.Equal(t, Counter(1), tr.Delta("c", 0.8))
+	assert.Equal(t, Counter(0), tr.Delta("c", 1.2))
+
+	// счётчик, не получавший значений дольше ttl, забывается
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, Counter(2), tr.Delta("a", 5))
+	now = now.Add(40 * time.Second)
+	assert.Equal(t, Counter(1), tr.Delta("a", 6))
+	assert.NotContains(t, tr.last, "b")
+	assert.Equal(t, Counter(0), tr.Delta("b", 9))
+
+	tr.Forget("a")
+	assert.Equal(t, Counter(0), tr.Delta("a", 100))
+}
+
+func TestCounterTrackerStart(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := NewCounterTracker()
+
+	assert.Equal(t, Counter(0), tr.DeltaSince("a", 10, start))
+	assert.Equal(t, Counter(5), tr.DeltaSince("a", 15, start))
+	// источник перезапущен и успел накопить больше прежнего значения
+	assert.Equal(t, Counter(20), tr.DeltaSince("a", 20, start.Add(time.Minute)))
+	assert.Equal(t, Counter(1), tr.DeltaSince("a", 21, start.Add(time.Minute)))
+}
+
+func TestCounterTrackerHistogram(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := NewCounterTracker()
+	h := func(counts []uint64, sum float64) Histogram {
+		var count uint64
+		for _, c := range counts {
+			count += c
+		}
+		return Histogram{Bounds: []float64{1}, Counts: counts, Count: count, Sum: sum}
+	}
+
+	// первое значение только запоминается
+	assert.Equal(t, h([]uint64{0, 0}, 0), tr.HistogramDelta("h", h([]uint64{1, 2}, 5), start))
+	assert.Equal(t, h([]uint64{1, 0}, 0.5), tr.HistogramDelta("h", h([]uint64{2, 2}, 5.5), start))
+	// сброс гистограммы источника
+	assert.Equal(t, h([]uint64{1, 0}, 0.5), tr.HistogramDelta("h", h([]uint64{1, 0}, 0.5), start))
+	assert.Equal(t, h([]uint64{3, 3}, 9), tr.HistogramDelta("h", h([]uint64{3, 3}, 9), start.Add(time.Minute)))
+	// изменились границы корзин
+	moved := Histogram{Bounds: []float64{2}, Counts: []uint64{4, 3}, Count: 7, Sum: 10}
+	assert.Equal(t, moved, tr.HistogramDelta("h", moved, start.Add(time.Minute)))
+}
@@ -0,0 +1,204 @@
+// Package otlp Пакет преобразует метрики OpenTelemetry (OTLP) в метрики сервиса.
+package otlp
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	pb "github.com/sergeysynergy/metricser/proto/otlp"
+)
+
+// resourceLabels Атрибуты ресурса, которые добавляются к меткам каждой точки.
+// Остальные атрибуты ресурса отбрасываются, чтобы не увеличивать число серий.
+var resourceLabels = []string{"service.name", "service.namespace", "service.instance.id"}
+
+// Mapper Преобразует метрики OTLP в метрики сервиса.
+//
+// Gauge сохраняется как gauge. Монотонный Sum сохраняется как counter: накопительные значения
+// преобразуются в приращения, приращения сохраняются как есть. Немонотонный накопительный Sum
+// сохраняется как gauge. Гистограмма сохраняется как histogram с приращениями корзин.
+// Изменение времени начала накопления точки означает сброс значения источника.
+// Остальные типы точек отклоняются.
+type Mapper struct {
+	tracker *metrics.CounterTracker
+}
+
+func NewMapper() *Mapper {
+	return &Mapper{
+		tracker: metrics.NewCounterTracker(),
+	}
+}
+
+// Map Преобразует запрос OTLP в метрики. Возвращает также число отклонённых точек.
+func (mp *Mapper) Map(req *pb.ExportMetricsServiceRequest) (*metrics.ProxyMetrics, int64) {
+	prm := metrics.NewProxyMetrics()
+	var rejected int64
+
+	for _, rm := range req.GetResourceMetrics() {
+		resource := make(metrics.Labels)
+		attrs := attributes(rm.GetResource().GetAttributes())
+		for _, name := range resourceLabels {
+			if v, ok := attrs[name]; ok {
+				resource[metrics.SanitizeLabel(name)] = v
+			}
+		}
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				rejected += mp.mapMetric(prm, m, resource)
+			}
+		}
+	}
+
+	return prm, rejected
+}
+
+func (mp *Mapper) mapMetric(prm *metrics.ProxyMetrics, m *pb.Metric, resource metrics.Labels) int64 {
+	var rejected int64
+
+	switch data := m.GetData().(type) {
+	case *pb.Metric_Gauge:
+		for _, p := range data.Gauge.GetDataPoints() {
+			if noValue(p.GetFlags()) {
+				continue
+			}
+			key := metrics.SeriesKey(m.GetName(), pointLabels(resource, p.GetAttributes()))
+			prm.Gauges[key] = metrics.Gauge(numberValue(p))
+		}
+
+	case *pb.Metric_Sum:
+		cumulative := data.Sum.GetAggregationTemporality() == pb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		for _, p := range data.Sum.GetDataPoints() {
+			if noValue(p.GetFlags()) {
+				continue
+			}
+			key := metrics.SeriesKey(m.GetName(), pointLabels(resource, p.GetAttributes()))
+			value := numberValue(p)
+
+			switch {
+			case data.Sum.GetIsMonotonic():
+				prm.Counters[key] += mp.counter(key, value, startTime(p.GetStartTimeUnixNano()), cumulative)
+			case cumulative:
+				prm.Gauges[key] = metrics.Gauge(value)
+			default:
+				rejected++
+			}
+		}
+
+	case *pb.Metric_Histogram:
+		cumulative := data.Histogram.GetAggregationTemporality() == pb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		for _, p := range data.Histogram.GetDataPoints() {
+			if noValue(p.GetFlags()) {
+				continue
+			}
+			if !mp.mapHistogram(prm, m.GetName(), pointLabels(resource, p.GetAttributes()), p, cumulative) {
+				rejected++
+			}
+		}
+
+	case *pb.Metric_ExponentialHistogram:
+		rejected += int64(len(data.ExponentialHistogram.GetDataPoints()))
+	case *pb.Metric_Summary:
+		rejected += int64(len(data.Summary.GetDataPoints()))
+	}
+
+	return rejected
+}
+
+// mapHistogram Сохраняет точку гистограммы как histogram. Накопительные значения корзин
+// преобразуются в приращения. Возвращает false, если корзины точки не согласованы.
+func (mp *Mapper) mapHistogram(prm *metrics.ProxyMetrics, name string, labels metrics.Labels, p *pb.HistogramDataPoint, cumulative bool) bool {
+	h := metrics.Histogram{
+		Bounds: append([]float64(nil), p.GetExplicitBounds()...),
+		Counts: append([]uint64(nil), p.GetBucketCounts()...),
+		Count:  p.GetCount(),
+		Sum:    p.GetSum(),
+	}
+	// точка без корзин передаёт только число и сумму значений
+	if len(h.Bounds) == 0 && len(h.Counts) == 0 {
+		h.Counts = []uint64{h.Count}
+	}
+	if err := h.Validate(); err != nil {
+		return false
+	}
+
+	key := metrics.SeriesKey(name, labels)
+	if cumulative {
+		h = mp.tracker.HistogramDelta(key, h, startTime(p.GetStartTimeUnixNano()))
+	}
+
+	prev, ok := prm.Histograms[key]
+	if !ok {
+		prm.Histograms[key] = h
+		return true
+	}
+	merged, err := prev.Merge(h)
+	if err != nil {
+		log.Printf("[WARNING] Replacing OTLP histogram %s with different buckets\n", key)
+		merged = h
+	}
+	prm.Histograms[key] = merged
+
+	return true
+}
+
+func (mp *Mapper) counter(key string, value float64, start time.Time, cumulative bool) metrics.Counter {
+	if cumulative {
+		return mp.tracker.DeltaSince(key, value, start)
+	}
+	return metrics.Counter(math.Round(value))
+}
+
+// startTime Возвращает время начала накопления точки; нулевое значение означает, что время неизвестно.
+func startTime(ns uint64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns))
+}
+
+func numberValue(p *pb.NumberDataPoint) float64 {
+	if v, ok := p.GetValue().(*pb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return p.GetAsDouble()
+}
+
+func noValue(flags uint32) bool {
+	return flags&uint32(pb.DataPointFlags_FLAG_NO_RECORDED_VALUE) != 0
+}
+
+// pointLabels Объединяет метки ресурса с атрибутами точки; атрибуты точки имеют приоритет.
+func pointLabels(resource metrics.Labels, attrs []*pb.KeyValue) metrics.Labels {
+	labels := make(metrics.Labels, len(resource)+len(attrs))
+	for k, v := range resource {
+		labels[k] = v
+	}
+	for k, v := range attributes(attrs) {
+		labels[metrics.SanitizeLabel(k)] = v
+	}
+
+	return labels
+}
+
+// attributes Возвращает строковые представления скалярных атрибутов; составные значения пропускаются.
+func attributes(kvs []*pb.KeyValue) map[string]string {
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		switch v := kv.GetValue().GetValue().(type) {
+		case *pb.AnyValue_StringValue:
+			attrs[kv.GetKey()] = v.StringValue
+		case *pb.AnyValue_BoolValue:
+			attrs[kv.GetKey()] = strconv.FormatBool(v.BoolValue)
+		case *pb.AnyValue_IntValue:
+			attrs[kv.GetKey()] = strconv.FormatInt(v.IntValue, 10)
+		case *pb.AnyValue_DoubleValue:
+			attrs[kv.GetKey()] = strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		}
+	}
+
+	return attrs
+}
@@ -0,0 +1,124 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	pb "github.com/sergeysynergy/metricser/proto/otlp"
+)
+
+func stringAttr(k, v string) *pb.KeyValue {
+	return &pb.KeyValue{Key: k, Value: &pb.AnyValue{Value: &pb.AnyValue_StringValue{StringValue: v}}}
+}
+
+func request(ms ...*pb.Metric) *pb.ExportMetricsServiceRequest {
+	return &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*pb.ResourceMetrics{{
+			Resource: &pb.Resource{Attributes: []*pb.KeyValue{
+				stringAttr("service.name", "shop"),
+				stringAttr("host.arch", "amd64"),
+			}},
+			ScopeMetrics: []*pb.ScopeMetrics{{Metrics: ms}},
+		}},
+	}
+}
+
+func sum(name string, value int64, temporality pb.AggregationTemporality, monotonic bool) *pb.Metric {
+	return &pb.Metric{Name: name, Data: &pb.Metric_Sum{Sum: &pb.Sum{
+		AggregationTemporality: temporality,
+		IsMonotonic:            monotonic,
+		DataPoints: []*pb.NumberDataPoint{{
+			Attributes: []*pb.KeyValue{stringAttr("http.method", "GET")},
+			Value:      &pb.NumberDataPoint_AsInt{AsInt: value},
+		}},
+	}}}
+}
+
+func histogram(count uint64, buckets []uint64, sum float64, temporality pb.AggregationTemporality) *pb.Metric {
+	return &pb.Metric{Name: "latency", Data: &pb.Metric_Histogram{Histogram: &pb.Histogram{
+		AggregationTemporality: temporality,
+		DataPoints: []*pb.HistogramDataPoint{{
+			StartTimeUnixNano: 1,
+			Count:             count,
+			Sum:               &sum,
+			BucketCounts:      buckets,
+			ExplicitBounds:    []float64{0.1, 1},
+		}},
+	}}}
+}
+
+func TestMapper(t *testing.T) {
+	const (
+		cumulative = pb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		delta      = pb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	)
+	service := metrics.Labels{"service_name": "shop"}
+	method := metrics.Labels{"service_name": "shop", "http_method": "GET"}
+	latency := metrics.SeriesKey("latency", service)
+	hist := func(counts []uint64, sum float64) metrics.Histogram {
+		var count uint64
+		for _, c := range counts {
+			count += c
+		}
+		return metrics.Histogram{Bounds: []float64{0.1, 1}, Counts: counts, Count: count, Sum: sum}
+	}
+
+	mp := NewMapper()
+
+	gauge := &pb.Metric{Name: "queue", Data: &pb.Metric_Gauge{Gauge: &pb.Gauge{
+		DataPoints: []*pb.NumberDataPoint{
+			{Value: &pb.NumberDataPoint_AsDouble{AsDouble: 1.5}},
+		},
+	}}}
+	summary := &pb.Metric{Name: "rpc", Data: &pb.Metric_Summary{Summary: &pb.Summary{
+		DataPoints: []*pb.SummaryDataPoint{{Count: 1}},
+	}}}
+
+	prm, rejected := mp.Map(request(
+		gauge,
+		sum("requests", 10, cumulative, true),
+		sum("sent", 3, delta, true),
+		sum("active", 4, cumulative, false),
+		sum("active.delta", 4, delta, false),
+		histogram(3, []uint64{1, 1, 1}, 2.5, cumulative),
+		summary,
+		histogram(3, []uint64{1, 1}, 2.5, delta),
+	))
+	assert.Equal(t, int64(3), rejected)
+	assert.Equal(t, metrics.Gauge(1.5), prm.Gauges[metrics.SeriesKey("queue", service)])
+	// первые накопительные значения только запоминаются
+	assert.Equal(t, metrics.Counter(0), prm.Counters[metrics.SeriesKey("requests", method)])
+	assert.Equal(t, metrics.Counter(3), prm.Counters[metrics.SeriesKey("sent", method)])
+	assert.Equal(t, metrics.Gauge(4), prm.Gauges[metrics.SeriesKey("active", method)])
+	assert.Equal(t, hist([]uint64{0, 0, 0}, 0), prm.Histograms[latency])
+
+	// накопительные значения преобразуются в приращения, приращения сохраняются как есть
+	prm, _ = mp.Map(request(
+		sum("requests", 15, cumulative, true),
+		sum("sent", 3, delta, true),
+		histogram(5, []uint64{1, 2, 2}, 4, cumulative),
+	))
+	assert.Equal(t, metrics.Counter(5), prm.Counters[metrics.SeriesKey("requests", method)])
+	assert.Equal(t, metrics.Counter(3), prm.Counters[metrics.SeriesKey("sent", method)])
+	assert.Equal(t, hist([]uint64{0, 1, 1}, 1.5), prm.Histograms[latency])
+
+	// изменение времени начала накопления означает перезапуск источника
+	started := func(value int64, start uint64) *pb.Metric {
+		m := sum("requests", value, cumulative, true)
+		m.GetSum().DataPoints[0].StartTimeUnixNano = start
+		return m
+	}
+	mp.Map(request(started(16, 1)))
+	prm, _ = mp.Map(request(started(20, 2)))
+	assert.Equal(t, metrics.Counter(20), prm.Counters[metrics.SeriesKey("requests", method)])
+
+	// точки гистограммы с передачей приращений сохраняются как есть и объединяются
+	mp = NewMapper()
+	prm, _ = mp.Map(request(
+		histogram(1, []uint64{1, 0, 0}, 0.05, delta),
+		histogram(2, []uint64{0, 2, 0}, 1, delta),
+	))
+	assert.Equal(t, hist([]uint64{1, 2, 0}, 1.05), prm.Histograms[latency])
+}
@@ -0,0 +1,306 @@
+// Package prometheus Пакет реализует текстовый формат представления метрик Prometheus.
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// ContentType Тип содержимого текстового формата Prometheus.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const counterSuffix = "_total"
+
+var helpPrefix = map[string]string{
+	metrics.TypeGauge:     "Gauge metric",
+	metrics.TypeCounter:   "Counter metric",
+	metrics.TypeHistogram: "Histogram metric",
+	metrics.TypeSummary:   "Summary metric",
+	metrics.TypeSet:       "Set metric",
+}
+
+// expositionType Типы метрик, которым нет соответствия в формате Prometheus.
+var expositionType = map[string]string{
+	metrics.TypeSet: metrics.TypeGauge,
+}
+
+// sample Строки одной серии метрики в текстовом формате; серии упорядочиваются по key,
+// строки внутри серии (корзины гистограммы) выводятся в исходном порядке.
+type sample struct {
+	key   string
+	lines []string
+}
+
+// family Семейство серий метрики с общим именем и типом.
+// id и kind — ID и тип метрики сервиса, из которой построено семейство.
+type family struct {
+	name    string
+	mType   string
+	help    string
+	id      string
+	kind    string
+	samples []sample
+}
+
+// series Серия метрики сервиса, ожидающая записи в семейство.
+type series struct {
+	key   string
+	mType string
+	lines func(name string, labels metrics.Labels) []string
+}
+
+// seriesSuffixes Суффиксы имён серий, которые выводятся в семействе метрики помимо имени семейства.
+var seriesSuffixes = map[string][]string{
+	metrics.TypeHistogram: {"_bucket", "_sum", "_count"},
+	metrics.TypeSummary:   {"_sum", "_count"},
+}
+
+// reservedLabel Метки, которые формат Prometheus добавляет к сериям метрик этого типа.
+var reservedLabel = map[string]string{
+	metrics.TypeHistogram: "le",
+	metrics.TypeSummary:   "quantile",
+}
+
+// seriesNames Возвращает имена всех серий семейства метрики заданного типа.
+func seriesNames(name, mType string) []string {
+	names := []string{name}
+	for _, suffix := range seriesSuffixes[mType] {
+		names = append(names, name+suffix)
+	}
+
+	return names
+}
+
+// typeOrder Порядок типов метрик при разрешении совпадений имён семейств.
+var typeOrder = map[string]int{
+	metrics.TypeGauge:     0,
+	metrics.TypeCounter:   1,
+	metrics.TypeHistogram: 2,
+	metrics.TypeSummary:   3,
+	metrics.TypeSet:       4,
+}
+
+// Encode Записывает значения всех метрик в текстовом формате Prometheus:
+// gauge-метрики как `gauge`, counter-метрики как `counter` с суффиксом `_total`,
+// histogram-метрики как `histogram` с сериями `_bucket`, `_sum` и `_count`,
+// summary-метрики как `summary` с квантилями DefaultQuantiles и сериями `_sum` и `_count`,
+// set-метрики как `gauge` с оценкой числа уникальных значений.
+//
+// Метрики разных типов или с разными ID, имена серий которых после приведения к формату Prometheus
+// совпадают (в том числе с учётом суффиксов `_bucket`, `_sum` и `_count`), в одно семейство
+// не объединяются: выводится метрика с меньшим ключом серии, остальные пропускаются с предупреждением.
+// Гистограммы с меткой `le` и скетчи с меткой `quantile` пропускаются: эти метки добавляет формат.
+func Encode(w io.Writer, prm *metrics.ProxyMetrics) error {
+	families := make(map[string]*family)
+	owners := make(map[string]*family) // семейства по именам всех их серий
+
+	add := func(key, mType string, lines func(name string, labels metrics.Labels) []string) {
+		id, labels, err := metrics.ParseSeriesKey(key)
+		if err == nil {
+			err = metrics.CheckLabels(labels)
+		}
+		if err != nil {
+			// одна испорченная серия не должна ломать выдачу остальных
+			log.Println("[WARNING] Skipping series in Prometheus output -", err)
+			return
+		}
+
+		if label, ok := reservedLabel[mType]; ok {
+			if _, ok = labels[label]; ok {
+				log.Printf("[WARNING] Skipping series in Prometheus output - %s %s uses reserved label '%s'\n",
+					mType, key, label)
+				return
+			}
+		}
+
+		name := SanitizeName(id)
+		if mType == metrics.TypeCounter && !strings.HasSuffix(name, counterSuffix) {
+			name += counterSuffix
+		}
+
+		// имена серий семейства, включая серии с суффиксами, не должны совпадать с именами других семейств
+		names := seriesNames(name, mType)
+		for _, n := range names {
+			if f, ok := owners[n]; ok && (f.id != id || f.kind != mType) {
+				log.Printf("[WARNING] Skipping series in Prometheus output - %s %s conflicts with %s %s as '%s'\n",
+					mType, key, f.kind, f.id, n)
+				return
+			}
+		}
+
+		f, ok := families[name]
+		if !ok {
+			exposed := mType
+			if t, ok := expositionType[mType]; ok {
+				exposed = t
+			}
+			f = &family{
+				name:  name,
+				mType: exposed,
+				help:  fmt.Sprintf("%s %s.", helpPrefix[mType], id),
+				id:    id,
+				kind:  mType,
+			}
+			families[name] = f
+			for _, n := range names {
+				owners[n] = f
+			}
+		}
+		// ключ серии с пустым именем содержит отсортированные и экранированные метки
+		f.samples = append(f.samples, sample{
+			key:   metrics.SeriesKey("", labels),
+			lines: lines(name, labels),
+		})
+	}
+	single := func(value string) func(string, metrics.Labels) []string {
+		return func(name string, labels metrics.Labels) []string {
+			return []string{name + metrics.SeriesKey("", labels) + " " + value}
+		}
+	}
+
+	all := make([]series, 0, len(prm.Gauges)+len(prm.Counters)+len(prm.Histograms)+len(prm.Sets)+len(prm.Summaries))
+	for key, value := range prm.Gauges {
+		all = append(all, series{key, metrics.TypeGauge, single(FormatFloat(float64(value)))})
+	}
+	for key, delta := range prm.Counters {
+		all = append(all, series{key, metrics.TypeCounter, single(strconv.FormatInt(int64(delta), 10))})
+	}
+	for key, h := range prm.Histograms {
+		h := h
+		lines := func(name string, labels metrics.Labels) []string {
+			return histogramLines(name, labels, h)
+		}
+		all = append(all, series{key, metrics.TypeHistogram, lines})
+	}
+	for key, s := range prm.Sets {
+		value := strconv.FormatUint(s.Estimate(), 10)
+		all = append(all, series{key, metrics.TypeSet, single(value)})
+	}
+	for key, s := range prm.Summaries {
+		s := s
+		lines := func(name string, labels metrics.Labels) []string {
+			return summaryLines(name, labels, s)
+		}
+		all = append(all, series{key, metrics.TypeSummary, lines})
+	}
+
+	// при совпадении имён семейств выбор выводимой метрики не зависит от порядка обхода map
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].key != all[j].key {
+			return all[i].key < all[j].key
+		}
+		return typeOrder[all[i].mType] < typeOrder[all[j].mType]
+	})
+	for _, s := range all {
+		add(s.key, s.mType, s.lines)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		f := families[name]
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.mType)
+
+		sort.Slice(f.samples, func(i, j int) bool { return f.samples[i].key < f.samples[j].key })
+		for _, s := range f.samples {
+			for _, line := range s.lines {
+				fmt.Fprintln(bw, line)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// histogramLines Представляет гистограмму сериями `_bucket` с накопительными счётчиками
+// в порядке возрастания границ, а также сериями `_sum` и `_count`.
+func histogramLines(name string, labels metrics.Labels, h metrics.Histogram) []string {
+	cumulative := h.Cumulative()
+	lines := make([]string, 0, len(cumulative)+2)
+
+	bucket := make(metrics.Labels, len(labels)+1)
+	for k, v := range labels {
+		bucket[k] = v
+	}
+	for i, c := range cumulative {
+		le := math.Inf(1)
+		if i < len(h.Bounds) {
+			le = h.Bounds[i]
+		}
+		bucket["le"] = FormatFloat(le)
+		lines = append(lines, name+"_bucket"+metrics.SeriesKey("", bucket)+" "+strconv.FormatUint(c, 10))
+	}
+
+	lines = append(lines,
+		name+"_sum"+metrics.SeriesKey("", labels)+" "+FormatFloat(h.Sum),
+		name+"_count"+metrics.SeriesKey("", labels)+" "+strconv.FormatUint(h.Count, 10),
+	)
+
+	return lines
+}
+
+// summaryLines Представляет скетч сериями квантилей в порядке возрастания, а также сериями `_sum` и `_count`.
+func summaryLines(name string, labels metrics.Labels, s metrics.Summary) []string {
+	lines := make([]string, 0, len(metrics.DefaultQuantiles)+2)
+
+	quantile := make(metrics.Labels, len(labels)+1)
+	for k, v := range labels {
+		quantile[k] = v
+	}
+	for _, q := range metrics.DefaultQuantiles {
+		quantile["quantile"] = FormatFloat(q)
+		lines = append(lines, name+metrics.SeriesKey("", quantile)+" "+FormatFloat(s.Quantile(q)))
+	}
+
+	lines = append(lines,
+		name+"_sum"+metrics.SeriesKey("", labels)+" "+FormatFloat(s.Sum),
+		name+"_count"+metrics.SeriesKey("", labels)+" "+strconv.FormatUint(s.Count, 10),
+	)
+
+	return lines
+}
+
+// SanitizeName Заменяет недопустимые в имени метрики Prometheus символы на `_`.
+func SanitizeName(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	b := []byte(name)
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':' || c >= '0' && c <= '9' && i > 0 {
+			continue
+		}
+		b[i] = '_'
+	}
+
+	return string(b)
+}
+
+// FormatFloat Представляет число в формате Prometheus, включая специальные значения.
+func FormatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,215 @@
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		prm  *metrics.ProxyMetrics
+		want string
+	}{
+		{
+			name: "Empty",
+			prm:  metrics.NewProxyMetrics(),
+			want: "",
+		},
+		{
+			name: "Gauges and counters",
+			prm: &metrics.ProxyMetrics{
+				Gauges: map[string]metrics.Gauge{
+					"HeapAlloc": 42.5,
+					"Alloc":     1024,
+				},
+				Counters: map[string]metrics.Counter{
+					"PollCount": 7,
+				},
+			},
+			want: "# HELP Alloc Gauge metric Alloc.\n" +
+				"# TYPE Alloc gauge\n" +
+				"Alloc 1024\n" +
+				"# HELP HeapAlloc Gauge metric HeapAlloc.\n" +
+				"# TYPE HeapAlloc gauge\n" +
+				"HeapAlloc 42.5\n" +
+				"# HELP PollCount_total Counter metric PollCount.\n" +
+				"# TYPE PollCount_total counter\n" +
+				"PollCount_total 7\n",
+		},
+		{
+			name: "Sanitized names and special values",
+			prm: &metrics.ProxyMetrics{
+				Gauges: map[string]metrics.Gauge{
+					"1cpu.load-avg": metrics.Gauge(math.Inf(1)),
+				},
+				Counters: map[string]metrics.Counter{
+					"requests_total": 3,
+				},
+			},
+			want: "# HELP _cpu_load_avg Gauge metric 1cpu.load-avg.\n" +
+				"# TYPE _cpu_load_avg gauge\n" +
+				"_cpu_load_avg +Inf\n" +
+				"# HELP requests_total Counter metric requests_total.\n" +
+				"# TYPE requests_total counter\n" +
+				"requests_total 3\n",
+		},
+		{
+			name: "Bad series key skipped",
+			prm: &metrics.ProxyMetrics{
+				Gauges: map[string]metrics.Gauge{
+					"Alloc":          1,
+					"foo{":           2,
+					`bar{1host="a"}`: 3,
+				},
+			},
+			want: "# HELP Alloc Gauge metric Alloc.\n" +
+				"# TYPE Alloc gauge\n" +
+				"Alloc 1\n",
+		},
+		{
+			name: "Histogram",
+			prm: &metrics.ProxyMetrics{
+				Histograms: map[string]metrics.Histogram{
+					metrics.SeriesKey("latency", metrics.Labels{"host": "a"}): {
+						Bounds: []float64{0.5, 1, 10},
+						Counts: []uint64{2, 1, 0, 1},
+						Count:  4,
+						Sum:    13.2,
+					},
+				},
+			},
+			want: "# HELP latency Histogram metric latency.\n" +
+				"# TYPE latency histogram\n" +
+				"latency_bucket{host=\"a\",le=\"0.5\"} 2\n" +
+				"latency_bucket{host=\"a\",le=\"1\"} 3\n" +
+				"latency_bucket{host=\"a\",le=\"10\"} 3\n" +
+				"latency_bucket{host=\"a\",le=\"+Inf\"} 4\n" +
+				"latency_sum{host=\"a\"} 13.2\n" +
+				"latency_count{host=\"a\"} 4\n",
+		},
+		{
+			name: "Summary",
+			prm: &metrics.ProxyMetrics{
+				Summaries: map[string]metrics.Summary{
+					"duration": func() metrics.Summary {
+						s := metrics.NewSummary(0)
+						s.Observe(2)
+						return s
+					}(),
+				},
+			},
+			want: "# HELP duration Summary metric duration.\n" +
+				"# TYPE duration summary\n" +
+				"duration{quantile=\"0.5\"} 2\n" +
+				"duration{quantile=\"0.95\"} 2\n" +
+				"duration{quantile=\"0.99\"} 2\n" +
+				"duration_sum 2\n" +
+				"duration_count 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := Encode(buf, tt.prm)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestEncodeLabels(t *testing.T) {
+	prm := &metrics.ProxyMetrics{
+		Gauges: map[string]metrics.Gauge{
+			metrics.SeriesKey("Alloc", metrics.Labels{"host": "b"}):                2,
+			metrics.SeriesKey("Alloc", metrics.Labels{"host": "a", "env": "prod"}): 1,
+		},
+		Counters: map[string]metrics.Counter{},
+	}
+	want := "# HELP Alloc Gauge metric Alloc.\n" +
+		"# TYPE Alloc gauge\n" +
+		"Alloc{env=\"prod\",host=\"a\"} 1\n" +
+		"Alloc{host=\"b\"} 2\n"
+
+	buf := &bytes.Buffer{}
+	err := Encode(buf, prm)
+	require.NoError(t, err)
+	assert.Equal(t, want, buf.String())
+}
+
+func TestEncodeCollisions(t *testing.T) {
+	prm := &metrics.ProxyMetrics{
+		Gauges: map[string]metrics.Gauge{
+			"a.b":       1,
+			"a_b":       2,
+			"x_total":   3,
+			"Heap-Size": 4,
+		},
+		Counters: map[string]metrics.Counter{
+			"x": 5,
+		},
+		Sets: map[string]metrics.Set{
+			"Heap-Size": metrics.NewSet(metrics.DefaultSetPrecision),
+		},
+	}
+	want := "# HELP Heap_Size Gauge metric Heap-Size.\n" +
+		"# TYPE Heap_Size gauge\n" +
+		"Heap_Size 4\n" +
+		"# HELP a_b Gauge metric a.b.\n" +
+		"# TYPE a_b gauge\n" +
+		"a_b 1\n" +
+		"# HELP x_total Counter metric x.\n" +
+		"# TYPE x_total counter\n" +
+		"x_total 5\n"
+
+	for i := 0; i < 10; i++ {
+		buf := &bytes.Buffer{}
+		err := Encode(buf, prm)
+		require.NoError(t, err)
+		assert.Equal(t, want, buf.String())
+	}
+}
+
+func TestEncodeSuffixCollisions(t *testing.T) {
+	h := metrics.NewHistogram([]float64{1})
+	h.Observe(0.5)
+	prm := &metrics.ProxyMetrics{
+		Gauges: map[string]metrics.Gauge{
+			"lat_count":  7,
+			"lat_bucket": 8,
+			"dur_sum":    9,
+		},
+		Histograms: map[string]metrics.Histogram{
+			"lat": h,
+			metrics.SeriesKey("h", metrics.Labels{"le": "1"}): h,
+		},
+		Summaries: map[string]metrics.Summary{
+			"dur": metrics.NewSummary(0),
+			metrics.SeriesKey("q", metrics.Labels{"quantile": "0.5"}): metrics.NewSummary(0),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := Encode(buf, prm)
+	require.NoError(t, err)
+	out := buf.String()
+
+	// серии с суффиксами заняты гистограммой и скетчем, gauge-метрики с такими именами пропускаются
+	assert.Contains(t, out, "# TYPE lat histogram\n")
+	assert.Contains(t, out, "# TYPE dur summary\n")
+	assert.NotContains(t, out, "lat_count 7")
+	assert.NotContains(t, out, "lat_bucket 8")
+	assert.NotContains(t, out, "dur_sum 9")
+	assert.NotContains(t, out, "# TYPE lat_count")
+	assert.NotContains(t, out, "# TYPE dur_sum")
+
+	// метки `le` и `quantile` добавляет формат, такие серии пропускаются
+	assert.NotContains(t, out, "# TYPE h ")
+	assert.NotContains(t, out, "# TYPE q ")
+}
@@ -0,0 +1,165 @@
+package prometheus
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// labelLe Метка верхней границы корзины гистограммы.
+const labelLe = "le"
+
+// Mapper Преобразует серии, полученные от одного источника, в метрики сервиса.
+//
+// Счётчики и число наблюдений сводок сохраняются как counter: накопительные значения преобразуются
+// в приращения. Гистограммы сохраняются как histogram с приращениями корзин. Остальные серии,
+// включая суммы наблюдений и квантили сводок, сохраняются как gauge. Нечисловые значения пропускаются.
+type Mapper struct {
+	tracker *metrics.CounterTracker
+}
+
+func NewMapper() *Mapper {
+	return &Mapper{
+		tracker: metrics.NewCounterTracker(),
+	}
+}
+
+// histogramSeries Накопительные значения серий одной гистограммы.
+type histogramSeries struct {
+	buckets map[float64]float64 // накопительное число наблюдений по верхней границе корзины
+	sum     float64
+}
+
+// Map Преобразует серии в метрики, добавляя к каждой серии метки labels; они имеют приоритет
+// над метками серии.
+func (mp *Mapper) Map(series []Series, labels metrics.Labels) *metrics.ProxyMetrics {
+	prm := metrics.NewProxyMetrics()
+	hists := make(map[string]*histogramSeries)
+
+	for _, s := range series {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+
+		seriesLabels := make(metrics.Labels, len(s.Labels)+len(labels))
+		for k, v := range s.Labels {
+			seriesLabels[k] = v
+		}
+		for k, v := range labels {
+			seriesLabels[k] = v
+		}
+
+		if s.Type == TypeHistogram && collectHistogram(hists, s, seriesLabels) {
+			continue
+		}
+
+		key := metrics.SeriesKey(s.Name, seriesLabels)
+		if isCounter(s) {
+			prm.Counters[key] += mp.tracker.Delta(key, s.Value)
+			continue
+		}
+		prm.Gauges[key] = metrics.Gauge(s.Value)
+	}
+
+	for key, hs := range hists {
+		h, err := hs.histogram()
+		if err != nil {
+			log.Printf("[WARNING] Skipping histogram %s in Prometheus output - %s\n", key, err)
+			continue
+		}
+		prm.Histograms[key] = mp.tracker.HistogramDelta(key, h, time.Time{})
+	}
+
+	return prm
+}
+
+// collectHistogram Запоминает значение корзины или суммы гистограммы. Число наблюдений совпадает
+// с корзиной +Inf и не запоминается. Возвращает false для серии, не относящейся к гистограмме.
+func collectHistogram(hists map[string]*histogramSeries, s Series, labels metrics.Labels) bool {
+	suffix := strings.TrimPrefix(s.Name, s.Family)
+	if suffix != suffixBucket && suffix != suffixSum && suffix != suffixCount {
+		return false
+	}
+
+	le, isBucket := labels[labelLe]
+	if isBucket && suffix == suffixBucket {
+		delete(labels, labelLe)
+	}
+	key := metrics.SeriesKey(s.Family, labels)
+	hs, ok := hists[key]
+	if !ok {
+		hs = &histogramSeries{buckets: make(map[float64]float64)}
+		hists[key] = hs
+	}
+
+	switch suffix {
+	case suffixBucket:
+		bound, err := strconv.ParseFloat(le, 64)
+		if err != nil || math.IsNaN(bound) {
+			// корзина без границы делает гистограмму несогласованной
+			bound = math.NaN()
+		}
+		hs.buckets[bound] = s.Value
+	case suffixSum:
+		hs.sum = s.Value
+	}
+
+	return true
+}
+
+// histogram Возвращает накопительную гистограмму по значениям корзин.
+func (hs *histogramSeries) histogram() (metrics.Histogram, error) {
+	total, ok := hs.buckets[math.Inf(1)]
+	if !ok {
+		return metrics.Histogram{}, fmt.Errorf("no +Inf bucket")
+	}
+
+	bounds := make([]float64, 0, len(hs.buckets)-1)
+	for b := range hs.buckets {
+		if math.IsNaN(b) {
+			return metrics.Histogram{}, fmt.Errorf("bad bucket bound")
+		}
+		if !math.IsInf(b, 1) {
+			bounds = append(bounds, b)
+		}
+	}
+	sort.Float64s(bounds)
+
+	h := metrics.Histogram{
+		Bounds: bounds,
+		Counts: make([]uint64, len(bounds)+1),
+		Count:  uint64(math.Round(total)),
+		Sum:    hs.sum,
+	}
+	var prev float64
+	for i := range h.Counts {
+		cumulative := total
+		if i < len(bounds) {
+			cumulative = hs.buckets[bounds[i]]
+		}
+		if cumulative < prev {
+			return metrics.Histogram{}, fmt.Errorf("bucket counts must not decrease")
+		}
+		h.Counts[i] = uint64(math.Round(cumulative) - math.Round(prev))
+		prev = cumulative
+	}
+
+	return h, h.Validate()
+}
+
+func isCounter(s Series) bool {
+	switch s.Type {
+	case TypeCounter:
+		return true
+	case TypeSummary:
+		return s.Name == s.Family+suffixCount
+	}
+
+	return false
+}
@@ -0,0 +1,180 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+// Типы семейств метрик текстового формата Prometheus.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+	TypeSummary   = "summary"
+	TypeUntyped   = "untyped"
+)
+
+// Суффиксы серий гистограмм и сводок.
+const (
+	suffixBucket = "_bucket"
+	suffixSum    = "_sum"
+	suffixCount  = "_count"
+)
+
+// Series Значение одной серии, разобранное из текстового формата.
+// Family и Type описывают семейство, к которому относится серия по строке `# TYPE`.
+type Series struct {
+	Name   string
+	Labels metrics.Labels
+	Value  float64
+	Family string
+	Type   string
+}
+
+// Parse Разбирает метрики в текстовом формате Prometheus. Метки времени серий не используются.
+// Недопустимые имена метрик и меток, как и нарушения формата, приводят к ошибке разбора.
+func Parse(r io.Reader) ([]Series, error) {
+	types := make(map[string]string)
+	series := make([]Series, 0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) == 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		s, err := parseSeries(line)
+		if err == nil {
+			err = checkName(s.Name)
+		}
+		if err == nil {
+			err = metrics.CheckLabels(s.Labels)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		s.Family, s.Type = familyOf(types, s.Name)
+		series = append(series, s)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// familyOf Находит семейство серии: для гистограмм и сводок серии отличаются от имени семейства суффиксом.
+func familyOf(types map[string]string, name string) (string, string) {
+	if t, ok := types[name]; ok {
+		return name, t
+	}
+
+	for _, suffix := range []string{suffixBucket, suffixSum, suffixCount, counterSuffix} {
+		base := strings.TrimSuffix(name, suffix)
+		if base == name {
+			continue
+		}
+		if t, ok := types[base]; ok {
+			return base, t
+		}
+	}
+
+	return name, TypeUntyped
+}
+
+// checkName Проверяет имя метрики на соответствие формату `[a-zA-Z_:][a-zA-Z0-9_:]*`.
+func checkName(name string) error {
+	for i, c := range name {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':' || c >= '0' && c <= '9' && i > 0 {
+			continue
+		}
+		return fmt.Errorf("bad metric name '%s'", name)
+	}
+
+	return nil
+}
+
+// parseSeries Разбирает строку вида `name{label="value",...} value [timestamp]`.
+func parseSeries(line string) (Series, error) {
+	s := Series{Labels: make(metrics.Labels)}
+
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return s, fmt.Errorf("bad series %q: value expected", line)
+	}
+	s.Name = line[:end]
+	rest := line[end:]
+
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		rest, err = parseLabels(rest[1:], s.Labels)
+		if err != nil {
+			return s, fmt.Errorf("bad series %q: %w", line, err)
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return s, fmt.Errorf("bad series %q: expected value and optional timestamp", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return s, fmt.Errorf("bad series %q: invalid value", line)
+	}
+	s.Value = value
+
+	return s, nil
+}
+
+// parseLabels Разбирает метки до закрывающей скобки и возвращает остаток строки.
+func parseLabels(body string, labels metrics.Labels) (string, error) {
+	for {
+		body = strings.TrimLeft(body, " \t")
+		if strings.HasPrefix(body, "}") {
+			return body[1:], nil
+		}
+
+		eq := strings.IndexByte(body, '=')
+		if eq <= 0 || len(body) < eq+2 || body[eq+1] != '"' {
+			return "", fmt.Errorf("label value expected")
+		}
+		name := strings.TrimSpace(body[:eq])
+		body = body[eq+2:]
+
+		value := strings.Builder{}
+		i := 0
+		for ; i < len(body) && body[i] != '"'; i++ {
+			if body[i] == '\\' && i+1 < len(body) {
+				i++
+				if body[i] == 'n' {
+					value.WriteByte('\n')
+					continue
+				}
+			}
+			value.WriteByte(body[i])
+		}
+		if i == len(body) {
+			return "", fmt.Errorf("unterminated label value")
+		}
+		labels[name] = value.String()
+
+		body = strings.TrimLeft(body[i+1:], " \t")
+		body = strings.TrimPrefix(body, ",")
+	}
+}
@@ -0,0 +1,110 @@
+package prometheus
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const exposition = `# HELP http_requests_total Total requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",path="/a\"b"} 10 1600000000000
+http_requests_total{method="POST"} 2
+# TYPE temperature gauge
+temperature 21.5
+# TYPE latency histogram
+latency_bucket{le="0.1"} 1
+latency_bucket{le="+Inf"} 3
+latency_sum 2.5
+latency_count 3
+# TYPE rpc summary
+rpc{quantile="0.5"} 0.2
+rpc_sum 7
+rpc_count 12
+go_goroutines 8
+broken NaN
+`
+
+func TestParse(t *testing.T) {
+	series, err := Parse(strings.NewReader(exposition))
+	require.NoError(t, err)
+	require.Len(t, series, 12)
+
+	assert.Equal(t, Series{
+		Name:   "http_requests_total",
+		Labels: metrics.Labels{"method": "GET", "path": `/a"b`},
+		Value:  10,
+		Family: "http_requests_total",
+		Type:   TypeCounter,
+	}, series[0])
+	assert.Equal(t, TypeGauge, series[2].Type)
+	assert.Equal(t, "latency", series[3].Family)
+	assert.Equal(t, TypeHistogram, series[6].Type)
+	assert.Equal(t, TypeSummary, series[9].Type)
+	assert.Equal(t, TypeUntyped, series[10].Type)
+	assert.True(t, math.IsNaN(series[11].Value))
+
+	for _, bad := range []string{"metric", `metric{a="b} 1`, "metric abc", `metric{a=b} 1`, `1metric 1`, `me"tric 1`, `metric{1a="b"} 1`} {
+		_, err = Parse(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestMapper(t *testing.T) {
+	series, err := Parse(strings.NewReader(exposition))
+	require.NoError(t, err)
+
+	target := metrics.Labels{"job": "app", "method": "override"}
+	mp := NewMapper()
+	prm := mp.Map(series, target)
+
+	key := func(name string, labels metrics.Labels) string {
+		l := metrics.Labels{"job": "app", "method": "override"}
+		for k, v := range labels {
+			l[k] = v
+		}
+		return metrics.SeriesKey(name, l)
+	}
+
+	// метки цели имеют приоритет над метками серии, первые значения счётчиков только запоминаются
+	assert.Equal(t, metrics.Counter(0), prm.Counters[key("http_requests_total", metrics.Labels{"path": `/a"b`})])
+	assert.Equal(t, metrics.Gauge(21.5), prm.Gauges[key("temperature", nil)])
+	assert.Equal(t, metrics.Histogram{Bounds: []float64{0.1}, Counts: []uint64{0, 0}}, prm.Histograms[key("latency", nil)])
+	assert.NotContains(t, prm.Counters, key("latency_count", nil))
+	assert.Equal(t, metrics.Gauge(0.2), prm.Gauges[key("rpc", metrics.Labels{"quantile": "0.5"})])
+	assert.Equal(t, metrics.Counter(0), prm.Counters[key("rpc_count", nil)])
+	assert.Equal(t, metrics.Gauge(7), prm.Gauges[key("rpc_sum", nil)])
+	assert.Equal(t, metrics.Gauge(8), prm.Gauges[key("go_goroutines", nil)])
+	assert.NotContains(t, prm.Gauges, key("broken", nil))
+
+	// при повторном опросе счётчики и корзины гистограмм преобразуются в приращения
+	series, err = Parse(strings.NewReader(`# TYPE latency histogram
+latency_bucket{le="0.1"} 2
+latency_bucket{le="+Inf"} 5
+latency_sum 4
+latency_count 5
+# TYPE rpc summary
+rpc_count 15
+`))
+	require.NoError(t, err)
+	prm = mp.Map(series, target)
+	assert.Equal(t, metrics.Histogram{Bounds: []float64{0.1}, Counts: []uint64{1, 1}, Count: 2, Sum: 1.5}, prm.Histograms[key("latency", nil)])
+	assert.Equal(t, metrics.Counter(3), prm.Counters[key("rpc_count", nil)])
+
+	// гистограмма без корзины +Inf или с убывающими корзинами пропускается
+	series, err = Parse(strings.NewReader(`# TYPE a histogram
+a_bucket{le="1"} 2
+a_count 2
+# TYPE b histogram
+b_bucket{le="1"} 3
+b_bucket{le="+Inf"} 2
+`))
+	require.NoError(t, err)
+	prm = mp.Map(series, target)
+	assert.Empty(t, prm.Histograms)
+}
@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	pb "github.com/sergeysynergy/metricser/proto"
+)
+
+// labelName Метка Prometheus, содержащая имя метрики.
+const labelName = "__name__"
+
+// DecodeWriteRequest Разбирает сжатый snappy запрос remote_write и возвращает отсчёты серий
+// с метками времени источника. Значения сохраняются как `gauge`: накопительные счётчики Prometheus
+// хранятся как есть, без преобразования в приращения. Нечисловые значения, в том числе
+// маркеры устаревания серий, пропускаются. Серии без имени или с недопустимыми метками пропускаются
+// целиком и не мешают записи остальных: возвращается также число пропущенных серий и первая ошибка серии.
+func DecodeWriteRequest(data []byte) ([]metrics.Sample, int, error) {
+	raw, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode snappy body - %w", err)
+	}
+
+	req := &pb.WriteRequest{}
+	err = proto.Unmarshal(raw, req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal write request - %w", err)
+	}
+
+	samples := make([]metrics.Sample, 0)
+	skipped := 0
+	var errSeries error
+	skip := func(err error) {
+		if errSeries == nil {
+			errSeries = err
+		}
+		skipped++
+	}
+	for _, ts := range req.GetTimeseries() {
+		name := ""
+		labels := make(metrics.Labels, len(ts.GetLabels()))
+		for _, l := range ts.GetLabels() {
+			if l.GetName() == labelName {
+				name = l.GetValue()
+				continue
+			}
+			labels[l.GetName()] = l.GetValue()
+		}
+		if name == "" {
+			skip(fmt.Errorf("time series without metric name"))
+			continue
+		}
+		if err = metrics.CheckLabels(labels); err != nil {
+			skip(fmt.Errorf("bad time series '%s' - %w", name, err))
+			continue
+		}
+
+		key := metrics.SeriesKey(name, labels)
+		for _, s := range ts.GetSamples() {
+			if math.IsNaN(s.GetValue()) || math.IsInf(s.GetValue(), 0) {
+				continue
+			}
+			samples = append(samples, metrics.Sample{
+				ID:        key,
+				MType:     metrics.TypeGauge,
+				Value:     s.GetValue(),
+				Timestamp: time.UnixMilli(s.GetTimestamp()),
+			})
+		}
+	}
+
+	return samples, skipped, errSeries
+}
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+	pb "github.com/sergeysynergy/metricser/proto"
+)
+
+// encodeWriteRequest Сжимает запрос remote_write так же, как это делает Prometheus.
+func encodeWriteRequest(t *testing.T, req *pb.WriteRequest) []byte {
+	raw, err := proto.Marshal(req)
+	require.NoError(t, err)
+	return snappy.Encode(nil, raw)
+}
+
+func TestDecodeWriteRequest(t *testing.T) {
+	const staleNaN = 0x7ff0000000000002
+
+	data := encodeWriteRequest(t, &pb.WriteRequest{Timeseries: []*pb.TimeSeries{
+		{
+			Labels: []*pb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}},
+			Samples: []*pb.Sample{
+				{Value: 1, Timestamp: 1600000000000},
+				{Value: 0, Timestamp: 1600000015000},
+				{Value: math.Float64frombits(staleNaN), Timestamp: 1600000030000},
+			},
+		},
+		{
+			Labels:  []*pb.Label{{Name: "__name__", Value: "http_requests_total"}},
+			Samples: []*pb.Sample{{Value: 42, Timestamp: 1600000000500}},
+		},
+	}})
+
+	samples, skipped, err := DecodeWriteRequest(data)
+	require.NoError(t, err)
+	assert.Zero(t, skipped)
+	up := metrics.SeriesKey("up", metrics.Labels{"job": "node"})
+	assert.Equal(t, []metrics.Sample{
+		{ID: up, MType: metrics.TypeGauge, Value: 1, Timestamp: time.UnixMilli(1600000000000)},
+		{ID: up, MType: metrics.TypeGauge, Value: 0, Timestamp: time.UnixMilli(1600000015000)},
+		{ID: "http_requests_total", MType: metrics.TypeGauge, Value: 42, Timestamp: time.UnixMilli(1600000000500)},
+	}, samples)
+
+	_, _, err = DecodeWriteRequest([]byte("not snappy"))
+	assert.Error(t, err)
+
+	// недопустимые серии пропускаются, остальные разбираются
+	samples, skipped, err = DecodeWriteRequest(encodeWriteRequest(t, &pb.WriteRequest{Timeseries: []*pb.TimeSeries{
+		{Labels: []*pb.Label{{Name: "job", Value: "node"}}, Samples: []*pb.Sample{{Value: 1}}},
+		{Labels: []*pb.Label{{Name: "__name__", Value: "up"}, {Name: "1job", Value: "node"}}, Samples: []*pb.Sample{{Value: 1}}},
+		{Labels: []*pb.Label{{Name: "__name__", Value: "up"}}, Samples: []*pb.Sample{{Value: 1, Timestamp: 1600000000000}}},
+	}}))
+	assert.Error(t, err)
+	assert.Equal(t, 2, skipped)
+	assert.Equal(t, []metrics.Sample{
+		{ID: "up", MType: metrics.TypeGauge, Value: 1, Timestamp: time.UnixMilli(1600000000000)},
+	}, samples)
+}
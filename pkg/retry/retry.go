@@ -0,0 +1,119 @@
+// Package retry Пакет реализует повтор операций с экспоненциальной задержкой и случайным разбросом.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Policy Описывает правила повтора операции.
+type Policy struct {
+	MaxRetries      int           // Максимальное количество повторов, 0 — операция выполняется один раз
+	InitialInterval time.Duration // Задержка перед первым повтором
+	MaxInterval     time.Duration // Максимальная задержка между повторами
+	Multiplier      float64       // Множитель задержки для каждого следующего повтора
+	Jitter          float64       // Доля случайного разброса задержки в диапазоне [0, 1]
+	MaxElapsedTime  time.Duration // Максимальное общее время выполнения, 0 — без ограничения
+}
+
+// Error Ошибка, после которой операцию можно повторить.
+type Error struct {
+	Err        error
+	RetryAfter time.Duration // Задержка, запрошенная сервером, не более MaxInterval; 0 — задержка определяется политикой
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable Помечает ошибку как допускающую повтор операции.
+func Retryable(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Err: err, RetryAfter: retryAfter}
+}
+
+// IsRetryable Проверяет, допускает ли ошибка повтор операции.
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e)
+}
+
+// Do Выполняет операцию fn, повторяя её согласно политике p, пока операция возвращает
+// ошибку, допускающую повтор. Возвращает последнюю ошибку операции.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	interval := p.InitialInterval
+
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var re *Error
+		if !errors.As(err, &re) || attempt >= p.MaxRetries {
+			return err
+		}
+
+		delay := p.delay(interval)
+		if re.RetryAfter > 0 {
+			// сервер не может задержать повтор дольше максимальной задержки политики
+			delay = re.RetryAfter
+			if p.MaxInterval > 0 && delay > p.MaxInterval {
+				delay = p.MaxInterval
+			}
+		}
+		if p.MaxElapsedTime > 0 && time.Since(start)+delay > p.MaxElapsedTime {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		interval = p.next(interval)
+	}
+}
+
+// delay Возвращает задержку со случайным разбросом в пределах ±Jitter.
+func (p Policy) delay(interval time.Duration) time.Duration {
+	if p.Jitter <= 0 || interval <= 0 {
+		return interval
+	}
+
+	jitter := p.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	delta := jitter * float64(interval)
+
+	return time.Duration(float64(interval) - delta + rand.Float64()*2*delta)
+}
+
+// next Возвращает задержку для следующего повтора.
+func (p Policy) next(interval time.Duration) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	next := time.Duration(float64(interval) * multiplier)
+	if p.MaxInterval > 0 && next > p.MaxInterval {
+		next = p.MaxInterval
+	}
+
+	return next
+}
@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+
+	policy := Policy{
+		MaxRetries:      3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     2 * time.Millisecond,
+		Multiplier:      2,
+		Jitter:          0.5,
+	}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		errs     []error
+		wantErr  error
+		attempts int
+	}{
+		{
+			name:     "Success",
+			policy:   policy,
+			errs:     []error{nil},
+			attempts: 1,
+		},
+		{
+			name:     "Success after retries",
+			policy:   policy,
+			errs:     []error{Retryable(errTemporary, 0), Retryable(errTemporary, 0), nil},
+			attempts: 3,
+		},
+		{
+			name:     "Permanent error is not retried",
+			policy:   policy,
+			errs:     []error{errPermanent, nil},
+			wantErr:  errPermanent,
+			attempts: 1,
+		},
+		{
+			name:     "Retries exhausted",
+			policy:   policy,
+			errs:     []error{Retryable(errTemporary, 0), Retryable(errTemporary, 0), Retryable(errTemporary, 0), Retryable(errTemporary, 0), nil},
+			wantErr:  errTemporary,
+			attempts: 4,
+		},
+		{
+			name:     "Retry-After exceeds max elapsed time",
+			policy:   Policy{MaxRetries: 3, MaxElapsedTime: 10 * time.Millisecond},
+			errs:     []error{Retryable(errTemporary, time.Second), nil},
+			wantErr:  errTemporary,
+			attempts: 1,
+		},
+		{
+			name:     "Retry-After is capped by max interval",
+			policy:   Policy{MaxRetries: 1, MaxInterval: time.Millisecond, MaxElapsedTime: 10 * time.Millisecond},
+			errs:     []error{Retryable(errTemporary, time.Hour), nil},
+			attempts: 2,
+		},
+		{
+			name:     "No retries by default",
+			policy:   Policy{},
+			errs:     []error{Retryable(errTemporary, 0), nil},
+			wantErr:  errTemporary,
+			attempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := Do(context.Background(), tt.policy, func(ctx context.Context) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.attempts, attempts)
+		})
+	}
+}
+
+func TestPolicyDelay(t *testing.T) {
+	p := Policy{InitialInterval: 100 * time.Millisecond, MaxInterval: 300 * time.Millisecond, Multiplier: 2, Jitter: 0.1}
+
+	for i := 0; i < 100; i++ {
+		d := p.delay(p.InitialInterval)
+		assert.GreaterOrEqual(t, d, 90*time.Millisecond)
+		assert.LessOrEqual(t, d, 110*time.Millisecond)
+	}
+
+	assert.Equal(t, 200*time.Millisecond, p.next(100*time.Millisecond))
+	assert.Equal(t, 300*time.Millisecond, p.next(200*time.Millisecond))
+}
@@ -0,0 +1,138 @@
+// Package statsd Пакет реализует разбор строкового протокола StatsD с тегами в стиле DogStatsD:
+// `name:value|type|@rate|#tag1:value1,tag2:value2`.
+package statsd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+const (
+	TypeCounter   = "c"
+	TypeGauge     = "g"
+	TypeTimer     = "ms"
+	TypeHistogram = "h"
+	TypeSet       = "s"
+)
+
+// Metric Отдельное значение метрики StatsD.
+type Metric struct {
+	Name       string
+	Type       string
+	Value      float64
+	Raw        string  // Исходное значение; для множеств значением является строка
+	SampleRate float64 // Доля отправленных значений, по умолчанию 1
+	Relative   bool    // Значение gauge со знаком + или - изменяет текущее значение
+	Labels     metrics.Labels
+}
+
+// Key Возвращает ключ серии метрики.
+func (m Metric) Key() string {
+	return metrics.SeriesKey(m.Name, m.Labels)
+}
+
+// Parse Разбирает пакет из нескольких строк. Некорректные строки пропускаются,
+// ошибки их разбора возвращаются отдельным списком.
+func Parse(data []byte) ([]Metric, []error) {
+	var (
+		res  []Metric
+		errs []error
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		m, err := ParseLine(line)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		res = append(res, m)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return res, errs
+}
+
+// ParseLine Разбирает строку вида `name:value|type|@rate|#tags`.
+func ParseLine(line string) (Metric, error) {
+	m := Metric{SampleRate: 1}
+
+	colon := strings.LastIndexByte(strings.SplitN(line, "|", 2)[0], ':')
+	if colon <= 0 {
+		return m, fmt.Errorf("bad statsd line %q: name expected", line)
+	}
+	m.Name = line[:colon]
+
+	parts := strings.Split(line[colon+1:], "|")
+	if len(parts) < 2 {
+		return m, fmt.Errorf("bad statsd line %q: type expected", line)
+	}
+	m.Raw = parts[0]
+	m.Type = parts[1]
+
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			rate, err := strconv.ParseFloat(p[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return m, fmt.Errorf("bad statsd line %q: invalid sample rate", line)
+			}
+			m.SampleRate = rate
+		case strings.HasPrefix(p, "#"):
+			labels, err := parseTags(p[1:])
+			if err != nil {
+				return m, fmt.Errorf("bad statsd line %q: %w", line, err)
+			}
+			m.Labels = labels
+		}
+	}
+
+	switch m.Type {
+	case TypeCounter, TypeGauge, TypeTimer, TypeHistogram:
+		value, err := strconv.ParseFloat(m.Raw, 64)
+		if err != nil {
+			return m, fmt.Errorf("bad statsd line %q: invalid value", line)
+		}
+		m.Value = value
+		m.Relative = m.Type == TypeGauge && (m.Raw[0] == '+' || m.Raw[0] == '-')
+	case TypeSet:
+	default:
+		return m, fmt.Errorf("bad statsd line %q: unknown type %q", line, m.Type)
+	}
+
+	return m, nil
+}
+
+// parseTags Разбирает теги вида `tag1:value1,tag2:value2`; тег без значения получает пустое значение.
+func parseTags(s string) (metrics.Labels, error) {
+	labels := make(metrics.Labels)
+	for _, tag := range strings.Split(s, ",") {
+		if tag == "" {
+			continue
+		}
+		kv := strings.SplitN(tag, ":", 2)
+		value := ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+		labels[kv[0]] = value
+	}
+
+	if err := metrics.CheckLabels(labels); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,88 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergeysynergy/metricser/pkg/metrics"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Metric
+		wantErr bool
+	}{
+		{
+			name: "Counter",
+			line: "app.requests:1|c",
+			want: Metric{Name: "app.requests", Type: TypeCounter, Value: 1, Raw: "1", SampleRate: 1},
+		},
+		{
+			name: "Counter with sample rate and tags",
+			line: "app.requests:2|c|@0.5|#env:prod,region:eu",
+			want: Metric{Name: "app.requests", Type: TypeCounter, Value: 2, Raw: "2", SampleRate: 0.5,
+				Labels: metrics.Labels{"env": "prod", "region": "eu"}},
+		},
+		{
+			name: "Relative gauge",
+			line: "queue.size:-3|g",
+			want: Metric{Name: "queue.size", Type: TypeGauge, Value: -3, Raw: "-3", SampleRate: 1, Relative: true},
+		},
+		{
+			name: "Timer",
+			line: "db.query:12.5|ms",
+			want: Metric{Name: "db.query", Type: TypeTimer, Value: 12.5, Raw: "12.5", SampleRate: 1},
+		},
+		{
+			name: "Set",
+			line: "users:alice|s",
+			want: Metric{Name: "users", Type: TypeSet, Raw: "alice", SampleRate: 1},
+		},
+		{
+			name:    "No type",
+			line:    "app.requests:1",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown type",
+			line:    "app.requests:1|x",
+			wantErr: true,
+		},
+		{
+			name:    "Bad value",
+			line:    "app.requests:abc|c",
+			wantErr: true,
+		},
+		{
+			name:    "Bad sample rate",
+			line:    "app.requests:1|c|@2",
+			wantErr: true,
+		},
+		{
+			name:    "Bad tag",
+			line:    "app.requests:1|c|#1env:prod",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseLine(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	ms, errs := Parse([]byte("a:1|c\nbad\n\nb:2|g\n"))
+	assert.Len(t, ms, 2)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "b", ms[1].Key())
+}
@@ -2,4 +2,4 @@
 
 protoc --go_out=. --go_opt=paths=source_relative \
   --go-grpc_out=. --go-grpc_opt=paths=source_relative \
-  proto/demo.proto proto/metrics.proto
+  proto/demo.proto proto/metrics.proto proto/otlp/otlp.proto proto/remote.proto